@@ -0,0 +1,127 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package iris
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/islisp-dev/iris/runtime"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// condition returns the condition signalled for a Go error. An *Error
+// gives its own condition; any other error gives a <simple-error> whose
+// report is the error message.
+func condition(e env.Environment, err error) ilos.Instance {
+	var lispErr *Error
+	if errors.As(err, &lispErr) {
+		return lispErr.Condition
+	}
+	message := strings.Replace(err.Error(), "~", "~~", -1)
+	return instance.NewSimpleError(e, instance.NewString([]rune(message)), instance.Nil)
+}
+
+// Func wraps fn, an ordinary Go function, as an ISLisp function named name.
+// The arguments are converted to the parameter types of fn and the result
+// is converted back:
+//
+//	<integer>           int, int8 ... uint64, *big.Int
+//	<float>             float32, float64 (integers are accepted too)
+//	<string>            string
+//	<character>         rune (int32), as an argument only
+//	<list>, <general-vector>  slices (the result is a list)
+//	association lists   maps
+//	<standard-object>   structs
+//	T and NIL           bool
+//	any object          ilos.Instance or interface{}
+//
+// Values are converted as Marshal and Unmarshal do.
+// An argument of another class signals <domain-error>. fn may take an
+// env.Environment first and be variadic. It may return nothing, a value,
+// an error, or a value and an error; a non-nil error is signalled as a
+// condition.
+func Func(name string, fn interface{}) (ilos.Instance, error) {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func {
+		return nil, fmt.Errorf("iris: %v is not a function", ft)
+	}
+	withEnv := ft.NumIn() > 0 && ft.In(0) == envType
+	params := []reflect.Type{}
+	for i := 0; i < ft.NumIn(); i++ {
+		if i == 0 && withEnv {
+			continue
+		}
+		t := ft.In(i)
+		if i == ft.NumIn()-1 && ft.IsVariadic() {
+			t = t.Elem()
+		}
+		if !convertible(t) {
+			return nil, fmt.Errorf("iris: cannot convert the parameter of type %v", t)
+		}
+		params = append(params, t)
+	}
+	returnsError := ft.NumOut() > 0 && ft.Out(ft.NumOut()-1) == errorType
+	values := ft.NumOut()
+	if returnsError {
+		values--
+	}
+	if values > 1 || (values == 1 && !convertible(ft.Out(0))) {
+		return nil, fmt.Errorf("iris: cannot convert the results of %v", ft)
+	}
+	symbol := instance.NewSymbol(strings.ToUpper(name))
+	fixed := len(params)
+	if ft.IsVariadic() {
+		fixed--
+	}
+	arity := instance.Arity{Required: fixed, Rest: ft.IsVariadic()}
+	return instance.NewVariadicFunction(symbol, arity, func(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+		if len(arguments) < fixed || (!ft.IsVariadic() && len(arguments) > fixed) {
+			return runtime.SignalCondition(e, instance.NewArityError(e), runtime.Nil)
+		}
+		in := []reflect.Value{}
+		if withEnv {
+			in = append(in, reflect.ValueOf(e))
+		}
+		for i, argument := range arguments {
+			t := params[len(params)-1]
+			if i < len(params) {
+				t = params[i]
+			}
+			v, err := toGo(e, argument, t)
+			if err != nil {
+				return runtime.SignalCondition(e, err, runtime.Nil)
+			}
+			in = append(in, v)
+		}
+		out := fv.Call(in)
+		if returnsError && !out[len(out)-1].IsNil() {
+			return runtime.SignalCondition(e, condition(e, out[len(out)-1].Interface().(error)), runtime.Nil)
+		}
+		if values == 0 {
+			return runtime.Nil, nil
+		}
+		ret, ok := fromGo(e, out[0], false)
+		if !ok {
+			return runtime.SignalCondition(e, condition(e, fmt.Errorf("%v cannot be converted", out[0].Type())), runtime.Nil)
+		}
+		return ret, nil
+	}), nil
+}
+
+// Defun binds name to the Go function fn as Func does.
+func (i *Interpreter) Defun(name string, fn interface{}) error {
+	f, err := Func(name, fn)
+	if err != nil {
+		return err
+	}
+	i.env.Function.Define(instance.NewSymbol(strings.ToUpper(name)), f)
+	return nil
+}
@@ -0,0 +1,130 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package iris
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+func TestInterpreter_Defun(t *testing.T) {
+	i := New()
+	functions := map[string]interface{}{
+		"go-add": func(a int, b float64) float64 { return float64(a) + b },
+		"go-repeat": func(s string, n uint8) (string, error) {
+			if n == 0 {
+				return "", errors.New("zero ~a times")
+			}
+			return strings.Repeat(s, int(n)), nil
+		},
+		"go-sum": func(xs ...int64) int64 {
+			sum := int64(0)
+			for _, x := range xs {
+				sum += x
+			}
+			return sum
+		},
+		"go-lengths": func(s []string) []int {
+			r := []int{}
+			for _, x := range s {
+				r = append(r, len(x))
+			}
+			return r
+		},
+		"go-upcase":   func(r rune) string { return strings.ToUpper(string(r)) },
+		"go-not":      func(b bool) bool { return !b },
+		"go-square":   func(n *big.Int) *big.Int { return new(big.Int).Mul(n, n) },
+		"go-describe": func(x interface{}) string { return fmt.Sprintf("%T", x) },
+		"go-identity": func(x ilos.Instance) ilos.Instance { return x },
+		"go-noop":     func() {},
+		"go-eval": func(e env.Environment, src string) (ilos.Instance, error) {
+			return i.EvalString(src)
+		},
+	}
+	for name, fn := range functions {
+		if err := i.Defun(name, fn); err != nil {
+			t.Fatalf("Defun(%v) err = %v", name, err)
+		}
+	}
+	tests := []struct {
+		src       string
+		want      string
+		wantClass ilos.Class
+	}{
+		{"(go-add 1 2.5)", "3.5", nil},
+		{"(go-add 1 2)", "3.0", nil},
+		{"(go-add 1.5 2)", "", class.DomainError},
+		{`(go-add "1" 2)`, "", class.DomainError},
+		{"(go-add 1)", "", class.ProgramError},
+		{`(go-repeat "ab" 3)`, `"ababab"`, nil},
+		{`(go-repeat "ab" 256)`, "", class.DomainError},
+		{`(go-repeat "ab" -1)`, "", class.DomainError},
+		{`(go-repeat "ab" 0)`, "", class.SimpleError},
+		{"(go-sum)", "0", nil},
+		{"(go-sum 1 2 3)", "6", nil},
+		{"(go-sum 1 'a)", "", class.DomainError},
+		{`(go-lengths '("a" "bc"))`, "(1 2)", nil},
+		{`(go-lengths #("a" "bc"))`, "(1 2)", nil},
+		{`(go-lengths nil)`, "NIL", nil},
+		{`(go-lengths '("a" . "b"))`, "", class.DomainError},
+		{`(go-lengths '("a" 1))`, "", class.DomainError},
+		{`(go-upcase #\a)`, `"A"`, nil},
+		{"(go-not nil)", "T", nil},
+		{"(go-not 0)", "NIL", nil},
+		{"(go-square 100000000000)", "10000000000000000000000", nil},
+		{"(go-describe 1)", `"int"`, nil},
+		{`(go-describe '(1 "a" #\b 1.0))`, `"[]interface {}"`, nil},
+		{"(go-describe 'a)", `"instance.Symbol"`, nil},
+		{"(go-identity 'a)", "A", nil},
+		{"(go-noop)", "NIL", nil},
+		{"(funcall #'go-sum 1 2)", "3", nil},
+		{"(function-arity #'go-add)", "(2 NIL)", nil},
+		{"(function-arity #'go-sum)", "(0 T)", nil},
+		{`(go-eval "(car 1)")`, "", class.DomainError},
+	}
+	for _, tt := range tests {
+		got, err := i.EvalString(tt.src)
+		if tt.wantClass != nil {
+			e, ok := err.(*Error)
+			if !ok || !ilos.InstanceOf(tt.wantClass, e.Condition) {
+				t.Errorf("%v err = %v, want %v", tt.src, err, tt.wantClass)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%v = %v, %v, want %v", tt.src, got, err, tt.want)
+		}
+	}
+	_, err := i.EvalString(`(go-repeat "ab" 0)`)
+	message, _ := err.(*Error).Condition.(instance.Instance).GetSlotValue(instance.NewSymbol("FORMAT-STRING"), class.SimpleError)
+	if message.String() != `"zero ~~a times"` {
+		t.Errorf("FORMAT-STRING = %v, want %v", message, `"zero ~~a times"`)
+	}
+}
+
+func TestFunc(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   interface{}
+	}{
+		{"not a function", 1},
+		{"channel parameter", func(chan int) {}},
+		{"function result", func() func() { return nil }},
+		{"two results", func() (int, int) { return 0, 0 }},
+	}
+	for _, tt := range tests {
+		if _, err := Func("f", tt.fn); err == nil {
+			t.Errorf("%v: Func() err = nil", tt.name)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/islisp-dev/iris/runtime"
+	"github.com/islisp-dev/iris/runtime/env"
+)
+
+var coverprofile = flag.String("coverprofile", "", "count the forms evaluated and write them to `file` in the format of go test -coverprofile on exit")
+
+var lcov = flag.String("lcov", "", "count the forms evaluated and write them to `file` in the LCOV format on exit")
+
+// startCoverage starts counting the forms evaluated in e if a flag asks for
+// it and returns a function which stops it and writes the counts out.
+func startCoverage(e env.Environment) func() {
+	if *coverprofile == "" && *lcov == "" {
+		return func() {}
+	}
+	runtime.StartCoverage(e)
+	return func() {
+		cov := runtime.StopCoverage(e)
+		for _, out := range []struct {
+			path  string
+			write func(io.Writer, *env.Coverage) error
+		}{
+			{*coverprofile, runtime.WriteCoverprofile},
+			{*lcov, runtime.WriteLCOV},
+		} {
+			if out.path == "" {
+				continue
+			}
+			if err := writeCoverage(out.path, out.write, cov); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
+
+func writeCoverage(path string, write func(io.Writer, *env.Coverage) error, cov *env.Coverage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f, cov); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,188 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/islisp-dev/iris/console"
+	"github.com/islisp-dev/iris/runtime"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+var debug = flag.Bool("debug", false, "open a debugger when a condition is not handled")
+
+const debuggerHelp = `:frames           list the calls in progress, innermost first
+:frame N          select the Nth call
+:locals           print the variables of the selected call
+:continue FORM    continue the condition with the value of FORM
+:abort            return to the top level
+Any other form is evaluated in the selected call.`
+
+// debugger is the handler of the conditions which no handler of the program
+// handles when the REPL is run with -debug. It is entered where the
+// condition was signalled, so the calls in progress can still be looked
+// into before the condition unwinds them.
+type debugger struct {
+	in *console.Reader
+	// handler is the top level handler which the debugger replaced.
+	handler ilos.Instance
+	// top is the top level environment of the REPL, whose standard input
+	// the commands are read from.
+	top env.Environment
+	// aborted is whether the debugger returned a condition to the top level
+	// in the last evaluation, which it has reported already.
+	aborted bool
+}
+
+func newDebugger(in *console.Reader) *debugger {
+	return &debugger{in: in, handler: instance.NewFunction(instance.NewSymbol("TOP-LEVEL-HANDLER"), runtime.TopLevelHander)}
+}
+
+func (d *debugger) handle(e env.Environment, condition ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if location, ok := runtime.ConditionLocation(condition); ok {
+		fmt.Printf("%v: %v\n", location, condition)
+	} else {
+		fmt.Println(condition)
+	}
+	continuable, _ := runtime.ConditionContinuable(e, condition)
+	if continuable != runtime.Nil {
+		fmt.Printf("The condition is continuable: %v\n", continuable)
+	}
+	fmt.Println("Type :help for the commands of the debugger.")
+	frames := runtime.Frames(e)
+	selected := 0
+	prompt := d.in.Prompt
+	defer func() { d.in.Prompt = prompt }()
+	for {
+		d.in.Prompt = fmt.Sprintf("debug[%v]> ", selected)
+		form, err := read(d.top, d.in)
+		if err != nil {
+			if err != errEndOfInput {
+				fmt.Println(err)
+				continue
+			}
+			return d.abort(condition)
+		}
+		switch form {
+		case instance.NewSymbol(":HELP"):
+			fmt.Println(debuggerHelp)
+		case instance.NewSymbol(":FRAMES"):
+			for i, frame := range frames {
+				fmt.Printf("%3v: %v\n", i, frame)
+			}
+		case instance.NewSymbol(":FRAME"):
+			n, err := read(d.top, d.in)
+			if err == errEndOfInput {
+				return d.abort(condition)
+			}
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if i, ok := n.(instance.Integer); !ok || int(i) < 0 || int(i) >= len(frames) {
+				fmt.Printf("There is no call %v; :frames lists them.\n", n)
+				continue
+			}
+			selected = int(n.(instance.Integer))
+			fmt.Println(frames[selected])
+		case instance.NewSymbol(":LOCALS"):
+			if len(frames) == 0 {
+				continue
+			}
+			names, values := frames[selected].Locals()
+			if len(names) == 0 {
+				fmt.Println("No variables are known in this call.")
+			}
+			for i, name := range names {
+				fmt.Printf("%v = %v\n", name, values[i])
+			}
+		case instance.NewSymbol(":CONTINUE"):
+			form, err := read(d.top, d.in)
+			if err == errEndOfInput {
+				return d.abort(condition)
+			}
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			if continuable == runtime.Nil {
+				fmt.Println("The condition is not continuable.")
+				continue
+			}
+			value, err := d.eval(e, frames, selected, form)
+			if err != nil {
+				if ilos.InstanceOf(class.Interrupted, err) {
+					return nil, err
+				}
+				report(err)
+				continue
+			}
+			return runtime.ContinueCondition(e, condition, value)
+		case instance.NewSymbol(":ABORT"):
+			return d.abort(condition)
+		default:
+			value, err := d.eval(e, frames, selected, form)
+			if err != nil {
+				if ilos.InstanceOf(class.Interrupted, err) {
+					return nil, err
+				}
+				report(err)
+				continue
+			}
+			fmt.Println(value)
+		}
+	}
+}
+
+// errEndOfInput is returned by read when the input ends or Ctrl-C is typed.
+var errEndOfInput = instance.NewSymbol("END-OF-INPUT")
+
+// read reads a form of a command from the standard input of top, which in
+// is the input of, as the REPL does.
+func read(top env.Environment, in *console.Reader) (ilos.Instance, ilos.Instance) {
+	form, err := runtime.ReadForm(top)
+	if in.Interrupted() {
+		return nil, errEndOfInput
+	}
+	if err != nil && ilos.InstanceOf(class.EndOfStream, err) {
+		return nil, errEndOfInput
+	}
+	return form, err
+}
+
+// eval evaluates form in the selected frame, or where the condition was
+// signalled if no call is in progress. Conditions signalled by form go to
+// the handler the debugger replaced, so they do not enter it again.
+func (d *debugger) eval(e env.Environment, frames []runtime.Frame, selected int, form ilos.Instance) (ilos.Instance, ilos.Instance) {
+	fe := e.NewDynamic()
+	if len(frames) > 0 {
+		fe = frames[selected].Env
+	}
+	fe.Handler = d.handler
+	value, err := runtime.Eval(fe, form)
+	runtime.FinishOutput(fe, fe.StandardOutput)
+	return value, err
+}
+
+func (d *debugger) abort(condition ilos.Instance) (ilos.Instance, ilos.Instance) {
+	d.aborted = true
+	return nil, condition
+}
+
+// reported reports whether the last evaluation was aborted in the debugger,
+// which reported its condition already, and clears the report.
+func (d *debugger) reported() bool {
+	if d == nil {
+		return false
+	}
+	aborted := d.aborted
+	d.aborted = false
+	return aborted
+}
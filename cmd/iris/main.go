@@ -0,0 +1,198 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	golang "runtime"
+	"strings"
+
+	"github.com/islisp-dev/iris"
+	"github.com/islisp-dev/iris/console"
+	"github.com/islisp-dev/iris/runtime"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+var commit string
+
+var maxDepth = flag.Int("max-depth", env.DefaultDepthLimit, "how deeply function calls may nest before <storage-exhausted> is signalled (0 for no limit)")
+
+var vm = flag.Bool("vm", false, "compile forms to bytecode and run them on the virtual machine")
+
+// backtraceLength is how many calls of a backtrace are printed.
+const backtraceLength = 20
+
+func report(err ilos.Instance) {
+	if location, ok := runtime.ConditionLocation(err); ok {
+		fmt.Printf("%v: %v\n", location, err)
+	} else {
+		fmt.Println(err)
+	}
+	printBacktrace(err)
+}
+
+// printBacktrace prints the calls which were in progress when condition
+// was signalled, innermost first.
+func printBacktrace(condition ilos.Instance) {
+	calls := runtime.Backtrace(condition)
+	for i, call := range calls {
+		if i == backtraceLength {
+			fmt.Printf("  ... and %v more\n", len(calls)-i)
+			break
+		}
+		fmt.Printf("  %v\n", call)
+	}
+}
+
+// options returns the options of the interpreter which the flags select.
+func options() []iris.Option {
+	opts := []iris.Option{iris.WithMaxDepth(*maxDepth)}
+	if *vm {
+		opts = append(opts, iris.WithBytecode())
+	}
+	return opts
+}
+
+// complete returns the names bound in e which start with prefix.
+func complete(e env.Environment, prefix string) []string {
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, s := range [][]ilos.Instance{e.Function.Keys(), e.Macro.Keys(), e.Special.Keys(), e.Variable.Keys(), e.Constant.Keys(), e.DynamicVariable.Keys(), e.Class.Keys()} {
+		for _, k := range s {
+			name := strings.ToLower(fmt.Sprint(k))
+			if strings.HasPrefix(name, strings.ToLower(prefix)) && !seen[name] {
+				seen[name] = true
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	return candidates
+}
+
+// interrupts receives Ctrl-C while a form is evaluated, as SIGINT or from
+// the line editor when the form reads from the terminal.
+var interrupts = make(chan os.Signal, 1)
+
+// evalInterruptibly evaluates exp in it until Ctrl-C is typed, which stops
+// the evaluation with an <interrupted> condition.
+func evalInterruptibly(it *iris.Interpreter, exp ilos.Instance) (ilos.Instance, ilos.Instance) {
+	select {
+	case <-interrupts: // typed while nothing was evaluated
+	default:
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-done:
+		}
+	}()
+	ret, err := it.EvalContext(ctx, exp)
+	if err != nil {
+		return nil, err.(*iris.Error).Condition
+	}
+	return ret, nil
+}
+
+func repl() {
+	terminal := console.IsTerminal(os.Stdin.Fd())
+	if terminal {
+		if commit == "" {
+			commit = "HEAD"
+		}
+		fmt.Printf("Iris ISLisp Interpreter Commit %v on %v\n", commit, golang.Version())
+		fmt.Printf("Copyright 2017 islisp-dev All Rights Reserved.\n")
+	}
+	in := console.NewReader(os.Stdin, os.Stdout, terminal)
+	in.Interrupt = func() {
+		select {
+		case interrupts <- os.Interrupt:
+		default:
+		}
+	}
+	opts := append(options(), iris.WithStdin(in))
+	var d *debugger
+	if *debug {
+		d = newDebugger(in)
+		opts = append(opts, iris.WithHandler(instance.NewFunction(instance.NewSymbol("DEBUGGER"), d.handle)))
+	}
+	it := iris.New(opts...)
+	e := it.Env()
+	if terminal {
+		if home, err := os.UserHomeDir(); err == nil {
+			in.Editor.History = console.NewHistory(filepath.Join(home, ".iris_history"))
+		}
+		in.Editor.Complete = func(prefix string) []string { return complete(e, prefix) }
+	}
+	if d != nil {
+		d.top = e
+	}
+	e.Stepper.Pause = pauseIn(in, e)
+	defer startProfiling(e)()
+	defer startCoverage(e)()
+	for {
+		// A form interrupted by Ctrl-C has been discarded by in and by
+		// the parser, so the stream of the interpreter reads on.
+		exp, err := runtime.ReadForm(e)
+		if in.Interrupted() {
+			continue
+		}
+		if err != nil {
+			if ilos.InstanceOf(class.EndOfStream, err) {
+				return
+			}
+			report(err)
+			continue
+		}
+		ret, err := evalInterruptibly(it, exp)
+		in.Interrupted() // clears a Ctrl-C typed while the form read
+		if err != nil {
+			if !d.reported() {
+				report(err)
+			}
+		} else {
+			fmt.Println(ret)
+		}
+	}
+}
+
+func script(path string) {
+	it := iris.New(options()...)
+	defer startProfiling(it.Env())()
+	defer startCoverage(it.Env())()
+	if err := it.LoadFile(path); err != nil {
+		if err, ok := err.(*iris.Error); ok {
+			fmt.Println(err)
+			printBacktrace(err.Condition)
+		}
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *vm && (*coverprofile != "" || *lcov != "") {
+		fmt.Fprintln(os.Stderr, "forms run with -vm are not counted for -coverprofile or -lcov")
+		os.Exit(2)
+	}
+	if flag.NArg() > 0 {
+		script(flag.Arg(0))
+		return
+	}
+	repl()
+}
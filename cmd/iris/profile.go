@@ -0,0 +1,50 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/islisp-dev/iris/runtime"
+	"github.com/islisp-dev/iris/runtime/env"
+)
+
+var profile = flag.Bool("profile", false, "count the calls of Lisp functions and print a report of them on exit")
+
+var pprof = flag.String("pprof", "", "count the calls of Lisp functions and write them to `file` in the pprof format on exit")
+
+// startProfiling starts profiling the calls made in e if a flag asks for it
+// and returns a function which stops it and writes the profile out.
+func startProfiling(e env.Environment) func() {
+	if !*profile && *pprof == "" {
+		return func() {}
+	}
+	runtime.StartProfiling(e)
+	return func() {
+		p := runtime.StopProfiling(e)
+		if *profile {
+			runtime.WriteProfile(os.Stderr, p)
+		}
+		if *pprof != "" {
+			if err := writePprof(*pprof, p); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
+
+func writePprof(path string, p *env.Profile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := runtime.WritePprof(f, p); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
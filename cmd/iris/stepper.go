@@ -0,0 +1,75 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/islisp-dev/iris/console"
+	"github.com/islisp-dev/iris/reader/parser"
+	"github.com/islisp-dev/iris/runtime"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+const stepperHelp = `:into             evaluate the form, pausing before the forms in it
+:over             evaluate the form without pausing in it
+:out              finish the form around this one without pausing
+:continue         stop stepping until the next breakpoint
+:abort            return to the top level
+Any other form is evaluated where the evaluation is paused.`
+
+// pauseIn returns the Pause function of the stepper of the REPL, which
+// prints the form paused at and reads what to do from in, the input of the
+// top level environment top.
+func pauseIn(in *console.Reader, top env.Environment) func(env.Environment, ilos.Instance) (env.StepAction, ilos.Instance) {
+	return func(e env.Environment, form ilos.Instance) (env.StepAction, ilos.Instance) {
+		if span, ok := parser.Location(e, form); ok {
+			fmt.Printf("%v at %v\n", form, span)
+		} else {
+			fmt.Println(form)
+		}
+		prompt := in.Prompt
+		in.Prompt = "step> "
+		defer func() { in.Prompt = prompt }()
+		for {
+			command, err := read(top, in)
+			if err == errEndOfInput {
+				return env.Continue, instance.Create(e, class.Interrupted)
+			}
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			switch command {
+			case instance.NewSymbol(":HELP"):
+				fmt.Println(stepperHelp)
+			case instance.NewSymbol(":INTO"):
+				return env.StepInto, nil
+			case instance.NewSymbol(":OVER"):
+				return env.StepOver, nil
+			case instance.NewSymbol(":OUT"):
+				return env.StepOut, nil
+			case instance.NewSymbol(":CONTINUE"):
+				return env.Continue, nil
+			case instance.NewSymbol(":ABORT"):
+				return env.Continue, instance.Create(e, class.Interrupted)
+			default:
+				value, err := runtime.Eval(e.NewLexical(), command)
+				runtime.FinishOutput(e, e.StandardOutput)
+				if err != nil {
+					if ilos.InstanceOf(class.Interrupted, err) {
+						return env.Continue, err
+					}
+					report(err)
+					continue
+				}
+				fmt.Println(value)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package console
+
+import (
+	"io"
+	"strings"
+
+	"github.com/islisp-dev/iris/reader/tokenizer"
+)
+
+// Incomplete reports whether src ends in the middle of a form: inside a
+// list, a string, a |symbol| or a block comment, or right after a quote.
+// Parentheses in strings, comments and character literals are not counted.
+func Incomplete(src string) bool {
+	t := tokenizer.NewReader(strings.NewReader(src))
+	depth, last := 0, ""
+	for {
+		tok, err := t.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return true
+		}
+		switch tok.Str {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if !strings.HasPrefix(tok.Str, ";") && !strings.HasPrefix(tok.Str, "#|") {
+			last = tok.Str
+		}
+	}
+	switch last {
+	case "'", "`", ",", ",@", "#'", "#":
+		return true
+	}
+	return depth > 0
+}
@@ -0,0 +1,174 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package console
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func complete(prefix string) []string {
+	candidates := []string{}
+	for _, s := range []string{"symbolp", "symbol-value", "symbol-function"} {
+		if strings.HasPrefix(s, prefix) {
+			candidates = append(candidates, s)
+		}
+	}
+	return candidates
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"", false},
+		{"(+ 1 2)", false},
+		{"(+ 1", true},
+		{"(a (b)\n c)", false},
+		{`"a (b"`, false},
+		{`"a (b`, true},
+		{`#\( `, false},
+		{`(list #\)`, true},
+		{"; (\n", false},
+		{"(a ; )\n", true},
+		{"#| ( |#", false},
+		{"#| (", true},
+		{"|a(b|", false},
+		{"'", true},
+		{"#'", true},
+		{"`(a ,", true},
+		{"#(1 2", true},
+		{")", false},
+	}
+	for _, tt := range tests {
+		if got := Incomplete(tt.src); got != tt.want {
+			t.Errorf("Incomplete(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEditor_ReadLine(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"plain", "(car x)\r", "(car x)"},
+		{"backspace", "(cat\x7fr x)\r", "(car x)"},
+		{"cursor", "(car)\x1b[D x\r", "(car x)"},
+		{"home and end", "car x\x01(\x05)\r", "(car x)"},
+		{"kill", "(car x) junk\x1b[D\x1b[D\x1b[D\x1b[D\x1b[D\x0b\r", "(car x)"},
+		{"kill word", "(car junk\x17x)\r", "(car x)"},
+		{"delete", "(cxar x)\x01\x06\x06\x04\r", "(car x)"},
+		{"completion", "(cdr (symbol-v\t'x))\r", "(cdr (symbol-value 'x))"},
+		{"end of input", "(car x)", "(car x)"},
+	}
+	for _, tt := range tests {
+		ed := NewEditor(strings.NewReader(tt.keys), ioutil.Discard)
+		ed.Complete = complete
+		got, err := ed.ReadLine(">>> ")
+		if err != nil || got != tt.want {
+			t.Errorf("%v: ReadLine() = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestEditor_Complete(t *testing.T) {
+	out := new(bytes.Buffer)
+	ed := NewEditor(strings.NewReader("(sym\t\t\r"), out)
+	ed.Complete = complete
+	got, err := ed.ReadLine(">>> ")
+	if err != nil || got != "(symbol" {
+		t.Errorf("ReadLine() = %q, %v, want %q", got, err, "(symbol")
+	}
+	if !strings.Contains(out.String(), "symbol-function symbol-value symbolp") {
+		t.Errorf("candidates are not listed: %q", out.String())
+	}
+}
+
+func TestEditor_Interrupt(t *testing.T) {
+	ed := NewEditor(strings.NewReader("(car\x03\x04"), ioutil.Discard)
+	if _, err := ed.ReadLine(">>> "); err != ErrInterrupt {
+		t.Errorf("ReadLine() error = %v, want %v", err, ErrInterrupt)
+	}
+	if _, err := ed.ReadLine(">>> "); err != io.EOF {
+		t.Errorf("ReadLine() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history")
+	h := NewHistory(path)
+	h.Add("(car x)")
+	h.Add("(cdr x)")
+	h.Add("(cdr x)")
+	h.Add("  ")
+	ed := NewEditor(strings.NewReader("\x1b[A\x1b[A\r(co\x10\x10\x0e\x0e\r"), ioutil.Discard)
+	ed.History = NewHistory(path)
+	if want := []string{"(car x)", "(cdr x)"}; !reflect.DeepEqual(ed.History.Lines(), want) {
+		t.Errorf("Lines() = %q, want %q", ed.History.Lines(), want)
+	}
+	if got, _ := ed.ReadLine(">>> "); got != "(car x)" {
+		t.Errorf("ReadLine() = %q, want %q", got, "(car x)")
+	}
+	if got, _ := ed.ReadLine(">>> "); got != "(co" {
+		t.Errorf("ReadLine() = %q, want %q", got, "(co")
+	}
+}
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		terminal bool
+		input    string
+		want     string
+		history  []string
+	}{
+		{
+			name:  "pipe",
+			input: "(+ 1\n2)\n3\n",
+			want:  "",
+		},
+		{
+			name:     "terminal",
+			terminal: true,
+			input:    "(+ 1\r2)\r3\r",
+			want:     ">>> ... >>> >>> ",
+			history:  []string{"(+ 1 2)", "3"},
+		},
+	}
+	for _, tt := range tests {
+		out := new(bytes.Buffer)
+		r := NewReader(strings.NewReader(tt.input), out, tt.terminal)
+		src, err := ioutil.ReadAll(r)
+		if err != nil || string(src) != "(+ 1\n2)\n3\n" {
+			t.Errorf("%v: ReadAll() = %q, %v", tt.name, src, err)
+		}
+		prompts := regexp.MustCompile("\r(>>> |\\.\\.\\. )\x1b\\[K").FindAllStringSubmatch(out.String(), -1)
+		got := ""
+		for _, p := range prompts {
+			got += p[1]
+		}
+		if got != tt.want {
+			t.Errorf("%v: prompts = %q, want %q", tt.name, got, tt.want)
+		}
+		if tt.terminal && !reflect.DeepEqual(r.Editor.History.Lines(), tt.history) {
+			t.Errorf("%v: history = %q, want %q", tt.name, r.Editor.History.Lines(), tt.history)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package console
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupt is returned by ReadLine when the user types Ctrl-C.
+var ErrInterrupt = errors.New("interrupt")
+
+// Editor reads lines with readline-style editing from a terminal in raw
+// mode. It only interprets the bytes it reads, so it also works on scripted
+// input.
+type Editor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	History  *History
+	Complete func(prefix string) []string
+
+	line   []rune
+	cursor int
+	prompt string
+	tabbed bool
+}
+
+// NewEditor returns an editor reading keys from in and echoing to out.
+func NewEditor(in io.Reader, out io.Writer) *Editor {
+	return &Editor{in: bufio.NewReader(in), out: out, History: NewHistory("")}
+}
+
+func (ed *Editor) refresh() {
+	fmt.Fprintf(ed.out, "\r%v%v\x1b[K", ed.prompt, string(ed.line))
+	if n := len(ed.line) - ed.cursor; n > 0 {
+		fmt.Fprintf(ed.out, "\x1b[%vD", n)
+	}
+}
+
+func (ed *Editor) set(line string) {
+	ed.line = []rune(line)
+	ed.cursor = len(ed.line)
+}
+
+func (ed *Editor) insert(r rune) {
+	ed.line = append(ed.line[:ed.cursor], append([]rune{r}, ed.line[ed.cursor:]...)...)
+	ed.cursor++
+}
+
+func (ed *Editor) delete(from, to int) {
+	ed.line = append(ed.line[:from], ed.line[to:]...)
+	ed.cursor = from
+}
+
+func isWordDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`()'"`+"`,;", r)
+}
+
+// word returns the start of the word before the cursor.
+func (ed *Editor) word() int {
+	i := ed.cursor
+	for i > 0 && !isWordDelimiter(ed.line[i-1]) {
+		i--
+	}
+	return i
+}
+
+func (ed *Editor) complete() {
+	if ed.Complete == nil {
+		return
+	}
+	start := ed.word()
+	prefix := string(ed.line[start:ed.cursor])
+	candidates := ed.Complete(prefix)
+	if len(candidates) == 0 {
+		return
+	}
+	sort.Strings(candidates)
+	common := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(candidates) == 1 {
+		common += " "
+	}
+	if len([]rune(common)) > len([]rune(prefix)) {
+		rest := append([]rune(common), ed.line[ed.cursor:]...)
+		ed.line = append(ed.line[:start], rest...)
+		ed.cursor = start + len([]rune(common))
+		return
+	}
+	if ed.tabbed {
+		fmt.Fprintf(ed.out, "\r\n%v\r\n", strings.Join(candidates, " "))
+	}
+}
+
+func (ed *Editor) escape() error {
+	r, _, err := ed.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return err
+	}
+	seq := ""
+	for {
+		r, _, err := ed.in.ReadRune()
+		if err != nil {
+			return err
+		}
+		seq += string(r)
+		if r >= '@' && r <= '~' {
+			break
+		}
+	}
+	switch seq {
+	case "A":
+		ed.set(ed.History.Prev(string(ed.line)))
+	case "B":
+		ed.set(ed.History.Next(string(ed.line)))
+	case "C":
+		if ed.cursor < len(ed.line) {
+			ed.cursor++
+		}
+	case "D":
+		if ed.cursor > 0 {
+			ed.cursor--
+		}
+	case "H", "1~", "7~":
+		ed.cursor = 0
+	case "F", "4~", "8~":
+		ed.cursor = len(ed.line)
+	case "3~":
+		if ed.cursor < len(ed.line) {
+			ed.delete(ed.cursor, ed.cursor+1)
+		}
+	}
+	return nil
+}
+
+// ReadLine shows prompt and returns the line the user entered without the
+// newline. It returns io.EOF for Ctrl-D on an empty line and ErrInterrupt
+// for Ctrl-C.
+func (ed *Editor) ReadLine(prompt string) (string, error) {
+	ed.prompt, ed.line, ed.cursor = prompt, nil, 0
+	ed.History.Reset()
+	ed.refresh()
+	for {
+		r, _, err := ed.in.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(ed.line) > 0 {
+				break
+			}
+			return "", err
+		}
+		tabbed := r == '\t'
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(ed.out, "\r\n")
+			return string(ed.line), nil
+		case 0x03: // Ctrl-C
+			fmt.Fprint(ed.out, "^C\r\n")
+			return "", ErrInterrupt
+		case 0x04: // Ctrl-D
+			if len(ed.line) == 0 {
+				fmt.Fprint(ed.out, "\r\n")
+				return "", io.EOF
+			}
+			if ed.cursor < len(ed.line) {
+				ed.delete(ed.cursor, ed.cursor+1)
+			}
+		case 0x01: // Ctrl-A
+			ed.cursor = 0
+		case 0x05: // Ctrl-E
+			ed.cursor = len(ed.line)
+		case 0x02: // Ctrl-B
+			if ed.cursor > 0 {
+				ed.cursor--
+			}
+		case 0x06: // Ctrl-F
+			if ed.cursor < len(ed.line) {
+				ed.cursor++
+			}
+		case 0x08, 0x7f: // Backspace
+			if ed.cursor > 0 {
+				ed.delete(ed.cursor-1, ed.cursor)
+			}
+		case 0x0b: // Ctrl-K
+			ed.line = ed.line[:ed.cursor]
+		case 0x15: // Ctrl-U
+			ed.delete(0, ed.cursor)
+		case 0x17: // Ctrl-W
+			i := ed.cursor
+			for i > 0 && unicode.IsSpace(ed.line[i-1]) {
+				i--
+			}
+			for i > 0 && !unicode.IsSpace(ed.line[i-1]) {
+				i--
+			}
+			ed.delete(i, ed.cursor)
+		case 0x10: // Ctrl-P
+			ed.set(ed.History.Prev(string(ed.line)))
+		case 0x0e: // Ctrl-N
+			ed.set(ed.History.Next(string(ed.line)))
+		case 0x0c: // Ctrl-L
+			fmt.Fprint(ed.out, "\x1b[H\x1b[2J")
+		case '\t':
+			ed.complete()
+		case 0x1b:
+			if err := ed.escape(); err != nil {
+				return "", err
+			}
+		default:
+			if unicode.IsPrint(r) {
+				ed.insert(r)
+			}
+		}
+		ed.tabbed = tabbed
+		ed.refresh()
+	}
+	return string(ed.line), nil
+}
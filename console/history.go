@@ -0,0 +1,115 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package console
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// HistorySize is the number of entries kept in a history file.
+const HistorySize = 1000
+
+// History is the list of lines entered so far. If it has a path, the lines
+// are loaded from and appended to that file.
+type History struct {
+	path  string
+	lines []string
+	pos   int
+	draft string
+}
+
+// NewHistory returns a history backed by the file at path. An empty path
+// gives a history which is kept only in memory.
+func NewHistory(path string) *History {
+	h := &History{path: path}
+	if path == "" {
+		return h
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.lines = append(h.lines, line)
+		}
+	}
+	if len(h.lines) > HistorySize {
+		h.lines = h.lines[len(h.lines)-HistorySize:]
+	}
+	return h
+}
+
+// Lines returns the entries from the oldest to the newest.
+func (h *History) Lines() []string {
+	return h.lines
+}
+
+// Add appends line unless it is blank or the same as the last entry.
+func (h *History) Add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(h.lines) > 0 && h.lines[len(h.lines)-1] == line) {
+		return
+	}
+	h.lines = append(h.lines, line)
+	if h.path == "" {
+		return
+	}
+	if len(h.lines) > HistorySize {
+		h.lines = h.lines[len(h.lines)-HistorySize:]
+		h.save()
+		return
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.WriteString(line + "\n")
+}
+
+func (h *History) save() {
+	file, err := os.OpenFile(h.path, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.WriteString(strings.Join(h.lines, "\n") + "\n")
+}
+
+// Reset moves back to the end of the history before a new line is edited.
+func (h *History) Reset() {
+	h.pos = len(h.lines)
+	h.draft = ""
+}
+
+// Prev returns the entry before the current one. current is the line being
+// edited, which is restored by Next when moving past the newest entry.
+func (h *History) Prev(current string) string {
+	if h.pos == len(h.lines) {
+		h.draft = current
+	}
+	if h.pos == 0 {
+		return current
+	}
+	h.pos--
+	return h.lines[h.pos]
+}
+
+// Next returns the entry after the current one.
+func (h *History) Next(current string) string {
+	if h.pos >= len(h.lines) {
+		return current
+	}
+	h.pos++
+	if h.pos == len(h.lines) {
+		return h.draft
+	}
+	return h.lines[h.pos]
+}
@@ -0,0 +1,97 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package console
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Reader is the input of a REPL. Each time the runtime needs more input it
+// reads a line, prompting with Prompt at the beginning of a form and with
+// Continuation while the form entered so far is incomplete. On a terminal
+// the line is read by Editor and each completed form is added to its
+// history; otherwise lines are read as they are and nothing is echoed.
+type Reader struct {
+	Prompt       string
+	Continuation string
+	Editor       *Editor
+	// Interrupt, if set, is called when a read is interrupted by Ctrl-C,
+	// before the read returns ErrInterrupt.
+	Interrupt func()
+
+	in          io.Reader
+	plain       *bufio.Reader
+	pending     string
+	form        string
+	interrupted bool
+}
+
+// NewReader returns a REPL input reading from in. If terminal is true, lines
+// are edited with an Editor echoing to out, and in is put into raw mode
+// while a line is edited if it is a terminal file.
+func NewReader(in io.Reader, out io.Writer, terminal bool) *Reader {
+	r := &Reader{Prompt: ">>> ", Continuation: "... ", in: in}
+	if terminal {
+		r.Editor = NewEditor(in, out)
+	} else {
+		r.plain = bufio.NewReader(in)
+	}
+	return r
+}
+
+// Interrupted reports whether the last read was interrupted by Ctrl-C and
+// clears the report. The partial form has been discarded, so the caller
+// should also discard what it has read.
+func (r *Reader) Interrupted() bool {
+	interrupted := r.interrupted
+	r.interrupted = false
+	return interrupted
+}
+
+func (r *Reader) readLine() (string, error) {
+	prompt := r.Continuation
+	if !Incomplete(r.form) {
+		prompt, r.form = r.Prompt, ""
+	}
+	if r.Editor == nil {
+		line, err := r.plain.ReadString('\n')
+		if err == io.EOF && line != "" {
+			return strings.TrimSuffix(line, "\n"), nil
+		}
+		return strings.TrimSuffix(line, "\n"), err
+	}
+	if f, ok := r.in.(interface{ Fd() uintptr }); ok && IsTerminal(f.Fd()) {
+		restore, err := MakeRaw(f.Fd())
+		if err == nil {
+			defer restore()
+		}
+	}
+	return r.Editor.ReadLine(prompt)
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.pending == "" {
+		line, err := r.readLine()
+		if err == ErrInterrupt {
+			r.form, r.interrupted = "", true
+			if r.Interrupt != nil {
+				r.Interrupt()
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.form += line + "\n"
+		if r.Editor != nil && !Incomplete(r.form) {
+			r.Editor.History.Add(strings.Replace(strings.TrimSpace(r.form), "\n", " ", -1))
+		}
+		r.pending = line + "\n"
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,21 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package console
+
+import "errors"
+
+// IsTerminal reports whether fd refers to a terminal. Line editing is not
+// supported on this platform, so it always returns false.
+func IsTerminal(fd uintptr) bool {
+	return false
+}
+
+// MakeRaw is not supported on this platform.
+func MakeRaw(fd uintptr) (func(), error) {
+	return nil, errors.New("raw mode is not supported")
+}
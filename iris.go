@@ -0,0 +1,261 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package iris embeds the ISLisp interpreter in Go programs. Each
+// Interpreter has its own environment and standard streams, so several of
+// them can run side by side without seeing each other's definitions.
+package iris
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/islisp-dev/iris/runtime"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// Error is a condition which was signalled and not handled.
+type Error struct {
+	Condition ilos.Instance
+
+	cause error
+}
+
+func (err *Error) Error() string {
+	if location, ok := runtime.ConditionLocation(err.Condition); ok {
+		return fmt.Sprintf("%v: %v", location, err.Condition)
+	}
+	return fmt.Sprint(err.Condition)
+}
+
+// Unwrap returns the error of the context which interrupted the evaluation,
+// if the condition is <interrupted> or <time-limit-exceeded>, so that
+// errors.Is(err, context.DeadlineExceeded) tells a timeout from other errors.
+func (err *Error) Unwrap() error {
+	return err.cause
+}
+
+// Interpreter is an independent ISLisp world.
+type Interpreter struct {
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	maxDepth int
+	bytecode bool
+	sandbox  *Sandbox
+	handler  ilos.Instance
+	env      env.Environment
+}
+
+// Option configures an Interpreter.
+type Option func(*Interpreter)
+
+// WithStdin sets the standard input of the interpreter. It defaults to os.Stdin.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) { i.stdin = r }
+}
+
+// WithStdout sets the standard output of the interpreter. It defaults to os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) { i.stdout = w }
+}
+
+// WithStderr sets the error output of the interpreter. It defaults to os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) { i.stderr = w }
+}
+
+// WithMaxDepth sets how deeply function calls may be nested before
+// <storage-exhausted> is signalled. Zero means no limit. It defaults to
+// env.DefaultDepthLimit.
+func WithMaxDepth(n int) Option {
+	return func(i *Interpreter) { i.maxDepth = n }
+}
+
+// WithBytecode makes the interpreter compile each form to bytecode and run it
+// on a virtual machine instead of evaluating the form directly. Programs
+// which call many small functions run faster this way.
+func WithBytecode() Option {
+	return func(i *Interpreter) { i.bytecode = true }
+}
+
+// WithHandler sets the function which is called with a condition that no
+// handler of the program handles, as a handler established by with-handler
+// is. By default the evaluation ends with the condition as its error.
+func WithHandler(handler ilos.Instance) Option {
+	return func(i *Interpreter) { i.handler = handler }
+}
+
+// Sandbox limits what the code run by an interpreter may do, for code which
+// is not trusted. The limits apply to each call of Eval, EvalString and the
+// like, and a zero limit means no limit. Breaking one stops the evaluation
+// with a <step-limit-exceeded>, <allocation-limit-exceeded> or
+// <time-limit-exceeded> condition, each a <storage-exhausted>, which no
+// handler can resume.
+type Sandbox struct {
+	// Steps is how many function calls and iterations of loops may be
+	// made.
+	Steps int
+	// Conses and VectorElements are how many conses and elements of
+	// vectors and arrays may be allocated.
+	Conses         int
+	VectorElements int
+	// StringLength is the length of the longest string which may be made.
+	StringLength int
+	// IntegerBits is how many bits an integer which is made may have. The
+	// arithmetic of a large integer is not stopped by Timeout once it has
+	// begun, so a zero IntegerBits is DefaultIntegerBits rather than no
+	// limit.
+	IntegerBits int
+	// Timeout is how long an evaluation may take.
+	Timeout time.Duration
+	// Privileged keeps the builtins which reach outside the interpreter,
+	// such as open-input-file and probe-file. Without it they are removed.
+	Privileged bool
+}
+
+// DefaultIntegerBits is the IntegerBits of a sandbox which does not set it,
+// integers of about 300000 digits, which take milliseconds to multiply.
+const DefaultIntegerBits = 1 << 20
+
+// WithSandbox runs the code of the interpreter in the sandbox s.
+func WithSandbox(s Sandbox) Option {
+	return func(i *Interpreter) { i.sandbox = &s }
+}
+
+// New returns an interpreter with the builtins installed in its own
+// environment.
+func New(opts ...Option) *Interpreter {
+	i := &Interpreter{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, maxDepth: env.DefaultDepthLimit}
+	for _, opt := range opts {
+		opt(i)
+	}
+	i.env = runtime.NewEnvironment(
+		instance.NewStream(i.stdin, nil, class.Character),
+		instance.NewStream(nil, i.stdout, class.Character),
+		instance.NewStream(nil, i.stderr, class.Character),
+	)
+	i.env.Depth.Limit = i.maxDepth
+	if i.handler != nil {
+		i.env.Handler = i.handler
+	}
+	if i.sandbox != nil && !i.sandbox.Privileged {
+		runtime.Confine(i.env)
+	}
+	return i
+}
+
+// Env returns the top level environment of the interpreter.
+func (i *Interpreter) Env() env.Environment {
+	return i.env
+}
+
+func (i *Interpreter) flush() {
+	runtime.FinishOutput(i.env, i.env.StandardOutput)
+	runtime.FinishOutput(i.env, i.env.ErrorOutput)
+}
+
+// Eval evaluates form and returns its value. An unhandled condition is
+// returned as an *Error.
+func (i *Interpreter) Eval(form ilos.Instance) (ilos.Instance, error) {
+	return i.EvalContext(context.Background(), form)
+}
+
+// EvalContext evaluates form as Eval does until ctx is done. Then the
+// evaluation is stopped, after running the cleanup forms of the
+// unwind-protect forms it is in, and an *Error with an <interrupted>
+// condition is returned which unwraps to ctx.Err().
+func (i *Interpreter) EvalContext(ctx context.Context, form ilos.Instance) (ilos.Instance, error) {
+	e, cancel := i.begin(ctx)
+	defer cancel()
+	return i.eval(e, form)
+}
+
+// begin returns the environment for an evaluation until ctx is done, with a
+// new budget if the interpreter has a sandbox. The evaluation has to call
+// cancel when it ends.
+func (i *Interpreter) begin(ctx context.Context) (e env.Environment, cancel context.CancelFunc) {
+	e, cancel = i.env, func() {}
+	if s := i.sandbox; s != nil {
+		e.Budget = &env.Budget{StepLimit: s.Steps, ConsLimit: s.Conses, ElementLimit: s.VectorElements, StringLimit: s.StringLength, IntegerLimit: s.IntegerBits}
+		if s.IntegerBits == 0 {
+			e.Budget.IntegerLimit = DefaultIntegerBits
+		}
+		if s.Timeout > 0 {
+			e.Budget.Deadline = time.Now().Add(s.Timeout)
+			ctx, cancel = context.WithDeadline(ctx, e.Budget.Deadline)
+		}
+	}
+	e.Context = ctx
+	return e, cancel
+}
+
+func (i *Interpreter) eval(e env.Environment, form ilos.Instance) (ilos.Instance, error) {
+	eval := runtime.Eval
+	if i.bytecode {
+		eval = runtime.Execute
+	}
+	ret, err := eval(e, form)
+	i.flush()
+	if err != nil {
+		if ilos.InstanceOf(class.Interrupted, err) || ilos.InstanceOf(class.TimeLimitExceeded, err) {
+			return nil, &Error{err, e.Context.Err()}
+		}
+		return nil, &Error{Condition: err}
+	}
+	return ret, nil
+}
+
+// load evaluates the forms read from r in order and returns the value of
+// the last one.
+func (i *Interpreter) load(ctx context.Context, r io.Reader) (ilos.Instance, error) {
+	e, cancel := i.begin(ctx)
+	defer cancel()
+	stream := instance.NewStream(r, nil, class.Character)
+	var ret ilos.Instance = runtime.Nil
+	for {
+		form, condition := runtime.ReadForm(i.env, stream)
+		if condition != nil {
+			if ilos.InstanceOf(class.EndOfStream, condition) {
+				return ret, nil
+			}
+			return nil, &Error{Condition: condition}
+		}
+		var err error
+		if ret, err = i.eval(e, form); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// EvalString evaluates the forms in src and returns the value of the last
+// one, or NIL if there is none. The limits of a sandbox apply to all of the
+// forms together.
+func (i *Interpreter) EvalString(src string) (ilos.Instance, error) {
+	return i.EvalStringContext(context.Background(), src)
+}
+
+// EvalStringContext evaluates the forms in src as EvalString does, stopping
+// as EvalContext does once ctx is done.
+func (i *Interpreter) EvalStringContext(ctx context.Context, src string) (ilos.Instance, error) {
+	return i.load(ctx, strings.NewReader(src))
+}
+
+// LoadFile evaluates the forms in the file at path.
+func (i *Interpreter) LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = i.load(context.Background(), file)
+	return err
+}
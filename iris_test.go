@@ -0,0 +1,353 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package iris
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/islisp-dev/iris/runtime"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+func TestInterpreter_EvalString(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    ilos.Instance
+		wantErr bool
+	}{
+		{"", instance.Nil, false},
+		{"(+ 1 2)", instance.NewInteger(3), false},
+		{"(defglobal x 10) (* x x)", instance.NewInteger(100), false},
+		{`(string-append "a" "b")`, instance.NewString([]rune("ab")), false},
+		{"(car 1)", nil, true},
+		{"(car", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := New().EvalString(tt.src)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("EvalString(%q) err = %v, wantErr %v", tt.src, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want.String() {
+			t.Errorf("EvalString(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestInterpreter_Isolation(t *testing.T) {
+	i1, i2 := New(), New()
+	if _, err := i1.EvalString("(defun f () 1) (defglobal g 2) (defclass <c> () ())"); err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []string{"(f)", "g", "(class <c>)"} {
+		if _, err := i2.EvalString(src); err == nil {
+			t.Errorf("%v is defined in another interpreter", src)
+		}
+	}
+	if _, err := i2.EvalString("(defun car (x) x)"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := i1.EvalString("(car '(1 2))"); err != nil || got.String() != "1" {
+		t.Errorf("(car '(1 2)) = %v, %v, want 1", got, err)
+	}
+}
+
+func TestInterpreter_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			it := New()
+			for k := 0; k < 100; k++ {
+				if _, err := it.EvalString("(list (gensym) (block b (return-from b 1)) (catch 'c (throw 'c 2)))"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestInterpreter_Streams(t *testing.T) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	i := New(WithStdin(strings.NewReader("(a b) c")), WithStdout(stdout), WithStderr(stderr))
+	got, err := i.EvalString(`
+	  (format (standard-output) "out~%")
+	  (format (error-output) "err~%")
+	  (list (read) (read))`)
+	if err != nil || got.String() != "((A B) C)" {
+		t.Errorf("EvalString() = %v, %v, want ((A B) C)", got, err)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout, stderr)
+	}
+}
+
+func TestInterpreter_LoadFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "iris*.lsp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("(defglobal x 1)\n(car x)\n")
+	file.Close()
+	i := New()
+	err = i.LoadFile(file.Name())
+	e, ok := err.(*Error)
+	if !ok || !ilos.InstanceOf(class.DomainError, e.Condition) {
+		t.Fatalf("LoadFile() err = %v, want a <domain-error>", err)
+	}
+	if want := file.Name() + ":2:1: "; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("Error() = %q, want prefix %q", err.Error(), want)
+	}
+	if got, err := i.EvalString("x"); err != nil || got.String() != "1" {
+		t.Errorf("x = %v, %v, want 1", got, err)
+	}
+	if err := i.LoadFile(file.Name() + ".missing"); !os.IsNotExist(err) {
+		t.Errorf("LoadFile() err = %v, want not exist", err)
+	}
+}
+
+func TestInterpreter_MaxDepth(t *testing.T) {
+	i := New(WithMaxDepth(100))
+	if _, err := i.EvalString("(defun nest (n) (if (= n 0) 0 (+ 1 (nest (- n 1)))))"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := i.EvalString("(nest 10)"); err != nil || got.String() != "10" {
+		t.Errorf("(nest 10) = %v, %v, want 10", got, err)
+	}
+	_, err := i.EvalString("(nest 1000)")
+	if e, ok := err.(*Error); !ok || !ilos.InstanceOf(class.StorageExhausted, e.Condition) {
+		t.Errorf("(nest 1000) err = %v, want a <storage-exhausted>", err)
+	}
+	if got, err := i.EvalString("(nest 10)"); err != nil || got.String() != "10" {
+		t.Errorf("(nest 10) after exhaustion = %v, %v, want 10", got, err)
+	}
+	if got, err := New(WithMaxDepth(0)).EvalString("(defun nest (n) (if (= n 0) 0 (+ 1 (nest (- n 1))))) (nest 1000)"); err != nil || got.String() != "1000" {
+		t.Errorf("(nest 1000) without a limit = %v, %v, want 1000", got, err)
+	}
+	for _, opts := range [][]Option{nil, {WithBytecode()}} {
+		if got, err := New(opts...).EvalString("(defun nest (n) (if (= n 0) 0 (+ 1 (nest (- n 1))))) (nest 1000)"); err != nil || got.String() != "1000" {
+			t.Errorf("(nest 1000) at the default limit = %v, %v, want 1000", got, err)
+		}
+	}
+	for _, opts := range [][]Option{nil, {WithBytecode()}} {
+		for _, src := range []string{"(defmacro runaway () '(progn (runaway))) (runaway)", "(defmacro lp () '(lp)) (lp)"} {
+			_, err = New(opts...).EvalString(src)
+			if e, ok := err.(*Error); !ok || !ilos.InstanceOf(class.StorageExhausted, e.Condition) {
+				t.Errorf("%v err = %v, want a <storage-exhausted>", src, err)
+			}
+		}
+	}
+}
+
+func TestInterpreter_DefaultDepth(t *testing.T) {
+	nest := "(defun nest (n) (if (= n 0) 0 (+ 1 (nest (- n 1)))))"
+	heavy := `
+		(defun heavy (n)
+			(let ((m n))
+				(cond ((= m 0) 0)
+					  (t (let* ((k (- m 1)))
+							(block b (catch 'c (unwind-protect (+ 1 (heavy k)) nil))))))))`
+	if got, err := New().EvalString(nest + "(nest 5000)"); err != nil || got.String() != "5000" {
+		t.Errorf("(nest 5000) = %v, %v, want 5000", got, err)
+	}
+	// Recursion as deep as the limit for a small Go stack fits in it, and
+	// runaway recursion is stopped before it overflows it.
+	defer debug.SetMaxStack(debug.SetMaxStack(64 << 20))
+	limit := env.DepthLimit(64 << 20)
+	for _, opts := range [][]Option{{WithMaxDepth(limit)}, {WithMaxDepth(limit), WithBytecode()}} {
+		i := New(opts...)
+		src := fmt.Sprintf("%v (nest %v)", nest, limit-10)
+		if got, err := i.EvalString(src); err != nil || got.String() != fmt.Sprint(limit-10) {
+			t.Errorf("(nest %v) = %v, %v", limit-10, got, err)
+		}
+		_, err := i.EvalString(heavy + "(with-handler (lambda (c) (heavy 1000000)) (heavy 1000000))")
+		if e, ok := err.(*Error); !ok || !ilos.InstanceOf(class.StorageExhausted, e.Condition) {
+			t.Errorf("(heavy 1000000) err = %v, want a <storage-exhausted>", err)
+		}
+	}
+}
+
+func TestInterpreter_RedefineMacro(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"(defmacro m () 1) (defun f () (m)) (f)", "1"},
+		{"(defmacro m () 2) (f)", "2"},
+		{"(defmacro scale (x) (list '* 2 x)) (defun g (n) (let ((k n)) (scale k))) (g 3)", "6"},
+		{"(defmacro scale (x) (list '* 3 x)) (g 3)", "9"},
+	}
+	for _, opts := range [][]Option{nil, {WithBytecode()}} {
+		i := New(opts...)
+		for _, tt := range tests {
+			if got, err := i.EvalString(tt.src); err != nil || got.String() != tt.want {
+				t.Errorf("%v with %v options = %v, %v, want %v", tt.src, len(opts), got, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestInterpreter_BytecodeParity(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"(defmacro shadowed () ''macro) (flet ((shadowed () 'local)) (shadowed))", "LOCAL"},
+		{"(defmacro shadowed () 1) (labels ((shadowed () 2)) (shadowed))", "2"},
+		{"(defmacro shadowed () 1) (flet ((shadowed () 2)) (list (shadowed) (funcall #'shadowed)))", "(2 2)"},
+	}
+	for _, tt := range tests {
+		for _, opts := range [][]Option{nil, {WithBytecode()}} {
+			if got, err := New(opts...).EvalString(tt.src); err != nil || got.String() != tt.want {
+				t.Errorf("%v with %v options = %v, %v, want %v", tt.src, len(opts), got, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestInterpreter_Bytecode(t *testing.T) {
+	var out bytes.Buffer
+	i := New(WithBytecode(), WithStdout(&out), WithMaxDepth(100))
+	got, err := i.EvalString(`
+		(defun fact (n) (if (= n 0) 1 (* n (fact (- n 1)))))
+		(defun loop (n) (if (= n 0) 'done (loop (- n 1))))
+		(format (standard-output) "~A" (fact 10))
+		(loop 1000)`)
+	if err != nil || got.String() != "DONE" || out.String() != "3628800" {
+		t.Errorf("EvalString() = %v, %v, output %q, want DONE, 3628800", got, err, out.String())
+	}
+	_, err = i.EvalString("(fact 1000)")
+	if e, ok := err.(*Error); !ok || !ilos.InstanceOf(class.StorageExhausted, e.Condition) {
+		t.Errorf("(fact 1000) err = %v, want a <storage-exhausted>", err)
+	}
+}
+
+func TestInterpreter_Handler(t *testing.T) {
+	handler := instance.NewFunction(instance.NewSymbol("HANDLER"), func(e env.Environment, condition ilos.Instance) (ilos.Instance, ilos.Instance) {
+		return runtime.ContinueCondition(e, condition, instance.NewInteger(42))
+	})
+	for _, opts := range [][]Option{nil, {WithBytecode()}} {
+		i := New(append(opts, WithHandler(handler))...)
+		if got, err := i.EvalString(`(+ 1 (cerror "use 42" "no value"))`); err != nil || got.String() != "43" {
+			t.Errorf("EvalString() = %v, %v, want 43", got, err)
+		}
+		if got, err := i.EvalString(`(with-handler (lambda (c) (continue-condition c 1)) (+ 1 (cerror "use 1" "no value")))`); err != nil || got.String() != "2" {
+			t.Errorf("EvalString() with a handler = %v, %v, want 2", got, err)
+		}
+	}
+}
+
+func TestInterpreter_EvalContext(t *testing.T) {
+	stdin, w := io.Pipe()
+	defer w.Close()
+	for _, opts := range [][]Option{{WithStdin(stdin)}, {WithStdin(stdin), WithBytecode()}} {
+		i := New(opts...)
+		if _, err := i.EvalString(`
+			(defglobal cleaned nil)
+			(defun spin () (spin))`); err != nil {
+			t.Fatal(err)
+		}
+		for _, src := range []string{
+			"(while t)",
+			"(for ((i 0 (+ i 1))) (nil))",
+			"(tagbody a (go a))",
+			"(spin)",
+			"(read-line)",
+			"(with-handler (lambda (c) (continue-condition c 1)) (while t))",
+			"(unwind-protect (while t) (for ((i 0 (+ i 1))) ((= i 10))) (setq cleaned t))",
+		} {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			_, err := i.EvalStringContext(ctx, src)
+			cancel()
+			if e, ok := err.(*Error); !ok || !ilos.InstanceOf(class.Interrupted, e.Condition) {
+				t.Errorf("%v err = %v, want an <interrupted>", src, err)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("%v err = %v, want it to wrap %v", src, err, context.DeadlineExceeded)
+			}
+		}
+		if got, err := i.EvalString("cleaned"); err != nil || got != instance.T {
+			t.Errorf("cleaned = %v, %v, want T", got, err)
+		}
+	}
+}
+
+func TestInterpreter_Sandbox(t *testing.T) {
+	sandbox := Sandbox{Steps: 10000, Conses: 100, VectorElements: 100, StringLength: 100, Timeout: time.Second}
+	tests := []struct {
+		src  string
+		want ilos.Class
+	}{
+		{"(create-list 60)", nil},
+		{"(create-list 60)", nil},
+		{"(while t)", class.StepLimitExceeded},
+		{"(defun down (n) (if (= n 0) 0 (down (- n 1)))) (down 100000)", class.StepLimitExceeded},
+		{"(with-handler (lambda (c) (continue-condition c 1)) (while t))", class.StepLimitExceeded},
+		{"(create-list 1000)", class.AllocationLimitExceeded},
+		{"(let ((l nil)) (for ((i 0 (+ i 1))) ((= i 1000) l) (setq l (cons i l))))", class.AllocationLimitExceeded},
+		{"(create-vector 1000)", class.AllocationLimitExceeded},
+		{"(create-array '(10 10 10))", class.AllocationLimitExceeded},
+		{"(create-array '(-1 -1) 0)", class.DomainError},
+		{"(create-array '(4294967296 4294967296))", class.AllocationLimitExceeded},
+		{"(create-array '(1000000 0))", class.AllocationLimitExceeded},
+		{`(create-string 1000 #\a)`, class.AllocationLimitExceeded},
+		{`(let ((s "a")) (while t (setq s (string-append s s))))`, class.AllocationLimitExceeded},
+		{"(expt 2 100)", nil},
+		{"(expt 3 300000000)", class.AllocationLimitExceeded},
+		{"(expt 2 30000000)", class.AllocationLimitExceeded},
+		{"(let ((x (expt 2 1000000))) (* x x))", class.AllocationLimitExceeded},
+		{"(lcm (expt 2 1000000) (- (expt 2 1000000) 1))", class.AllocationLimitExceeded},
+		{`(open-input-file "iris.go")`, class.UndefinedFunction},
+		{`(probe-file "iris.go")`, class.UndefinedFunction},
+	}
+	for _, opts := range [][]Option{{WithSandbox(sandbox)}, {WithSandbox(sandbox), WithBytecode()}} {
+		i := New(opts...)
+		for _, tt := range tests {
+			_, err := i.EvalString(tt.src)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("%v err = %v, want nil", tt.src, err)
+				}
+				continue
+			}
+			if e, ok := err.(*Error); !ok || !ilos.InstanceOf(tt.want, e.Condition) {
+				t.Errorf("%v err = %v, want a %v", tt.src, err, tt.want)
+			}
+		}
+	}
+	i := New(WithSandbox(Sandbox{Timeout: 20 * time.Millisecond}))
+	_, err := i.EvalString("(while t)")
+	if e, ok := err.(*Error); !ok || !ilos.InstanceOf(class.TimeLimitExceeded, e.Condition) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("(while t) err = %v, want a <time-limit-exceeded>", err)
+	}
+	_, err = New(WithSandbox(Sandbox{Timeout: time.Second})).EvalString("(expt 3 300000000)")
+	if e, ok := err.(*Error); !ok || !ilos.InstanceOf(class.AllocationLimitExceeded, e.Condition) {
+		t.Errorf("(expt 3 300000000) err = %v, want an <allocation-limit-exceeded>", err)
+	}
+	if got, err := New(WithSandbox(Sandbox{IntegerBits: 100})).EvalString("(let ((x (expt 2 60))) (* x x x))"); err == nil {
+		t.Errorf("(* x x x) = %v, want an <allocation-limit-exceeded>", got)
+	}
+	if got, err := New(WithSandbox(Sandbox{Privileged: true})).EvalString(`(probe-file "iris.go")`); err != nil || got != instance.T {
+		t.Errorf(`(probe-file "iris.go") = %v, %v, want T`, got, err)
+	}
+}
@@ -0,0 +1,537 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package iris
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/islisp-dev/iris/runtime"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+var (
+	instanceType = reflect.TypeOf((*ilos.Instance)(nil)).Elem()
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	envType      = reflect.TypeOf(env.Environment{})
+	bigIntType   = reflect.TypeOf((*big.Int)(nil))
+)
+
+// field is an exported struct field and the slot it is stored in.
+type field struct {
+	index     []int
+	slot      ilos.Instance
+	omitempty bool
+	vector    bool
+}
+
+// structClass is the class of the instances a struct type is marshaled to.
+type structClass struct {
+	class  ilos.Class
+	name   string
+	fields []field
+}
+
+var structClasses = struct {
+	sync.Mutex
+	m map[reflect.Type]*structClass
+}{m: map[reflect.Type]*structClass{}}
+
+// lispName converts a Go identifier to an ISLisp name: HTTPServer becomes
+// HTTP-SERVER.
+func lispName(name string) string {
+	r := []rune(name)
+	s := []rune{}
+	for i, c := range r {
+		if i > 0 && unicode.IsUpper(c) && (!unicode.IsUpper(r[i-1]) || (i+1 < len(r) && unicode.IsLower(r[i+1]))) {
+			s = append(s, '-')
+		}
+		s = append(s, unicode.ToUpper(c))
+	}
+	return string(s)
+}
+
+// classOf returns the class of struct type t. The class is named after the
+// type, as <POINT> for Point, and has a slot for each exported field. A
+// field is named after the field or by its iris tag:
+//
+//	Name string `iris:"name"`            // the slot NAME
+//	Tags []string `iris:"tags,vector"`   // a general vector instead of a list
+//	Note string `iris:",omitempty"`      // left unbound if empty
+//	Skip int `iris:"-"`                  // not marshaled
+//
+// The slot name is also the initarg of the slot.
+func classOf(t reflect.Type) *structClass {
+	structClasses.Lock()
+	defer structClasses.Unlock()
+	if c, ok := structClasses.m[t]; ok {
+		return c
+	}
+	name := "STRUCT"
+	if t.Name() != "" {
+		name = lispName(t.Name())
+	}
+	c := &structClass{name: name}
+	slots := []ilos.Instance{}
+	initargs := map[ilos.Instance]ilos.Instance{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("iris")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		options := strings.Split(tag, ",")
+		slot := lispName(f.Name)
+		if options[0] != "" {
+			slot = strings.ToUpper(options[0])
+		}
+		fd := field{index: f.Index, slot: instance.NewSymbol(slot)}
+		for _, option := range options[1:] {
+			switch option {
+			case "omitempty":
+				fd.omitempty = true
+			case "vector":
+				fd.vector = true
+			}
+		}
+		c.fields = append(c.fields, fd)
+		slots = append(slots, fd.slot)
+		initargs[fd.slot] = fd.slot
+	}
+	c.class = instance.NewStandardClass(instance.NewSymbol("<"+name+">"), []ilos.Class{class.StandardObject}, slots, map[ilos.Instance]ilos.Instance{}, initargs, class.StandardClass, instance.Nil)
+	structClasses.m[t] = c
+	return c
+}
+
+// convertible reports whether values of t can be passed between Go and
+// ISLisp.
+func convertible(t reflect.Type) bool {
+	return convertibleType(t, map[reflect.Type]bool{})
+}
+
+// convertibleType is convertible for a type which may refer to the struct
+// types in seen, which are assumed to be convertible.
+func convertibleType(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == instanceType || t == bigIntType || t.Implements(instanceType) || seen[t] {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice, reflect.Array, reflect.Ptr:
+		return convertibleType(t.Elem(), seen)
+	case reflect.Map:
+		return convertibleType(t.Key(), seen) && convertibleType(t.Elem(), seen)
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	case reflect.Struct:
+		seen[t] = true
+		for _, f := range classOf(t).fields {
+			if !convertibleType(t.FieldByIndex(f.index).Type, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// elements returns the elements of a proper list or a general vector.
+func elements(obj ilos.Instance) ([]ilos.Instance, bool) {
+	switch v := obj.(type) {
+	case instance.GeneralVector:
+		return v, true
+	case *instance.Null:
+		return nil, true
+	case *instance.Cons:
+		s := []ilos.Instance{}
+		for ; ilos.InstanceOf(class.Cons, obj); obj = obj.(*instance.Cons).Cdr {
+			s = append(s, obj.(*instance.Cons).Car)
+		}
+		return s, obj == instance.Nil
+	}
+	return nil, false
+}
+
+// slotValue returns the value of the slot named slot of obj, which may be
+// defined by obj's class or any of its superclasses.
+func slotValue(obj instance.Instance, slot ilos.Instance) (ilos.Instance, bool) {
+	for _, c := range obj.Class().ClassPrecedenceList() {
+		if v, ok := obj.GetSlotValue(slot, c); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// natural returns the Go value which obj converts to when the Go side
+// does not say which type it wants.
+func natural(obj ilos.Instance) interface{} {
+	switch v := obj.(type) {
+	case instance.Integer:
+		return int(v)
+	case instance.BigInteger:
+		return new(big.Int).Set(v.Int)
+	case instance.Float:
+		return float64(v)
+	case instance.String:
+		return string(v)
+	case instance.Character:
+		return rune(v)
+	case *instance.Null:
+		return nil
+	case *instance.Cons, instance.GeneralVector:
+		if s, ok := elements(v); ok {
+			r := make([]interface{}, len(s))
+			for i, o := range s {
+				r[i] = natural(o)
+			}
+			return r
+		}
+	}
+	return obj
+}
+
+// toGo converts obj to a value of t. It returns a domain error if obj is
+// not of the class which corresponds to t.
+func toGo(e env.Environment, obj ilos.Instance, t reflect.Type) (reflect.Value, ilos.Instance) {
+	v := reflect.New(t).Elem()
+	switch {
+	case t == instanceType:
+		v.Set(reflect.ValueOf(&obj).Elem())
+		return v, nil
+	case t.Implements(instanceType):
+		if reflect.TypeOf(obj) != t {
+			return v, instance.NewDomainError(e, obj, class.Object)
+		}
+		v.Set(reflect.ValueOf(obj))
+		return v, nil
+	case t == bigIntType:
+		switch n := obj.(type) {
+		case instance.Integer:
+			v.Set(reflect.ValueOf(big.NewInt(int64(n))))
+		case instance.BigInteger:
+			v.Set(reflect.ValueOf(new(big.Int).Set(n.Int)))
+		default:
+			return v, instance.NewDomainError(e, obj, class.Integer)
+		}
+		return v, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		v.SetBool(obj != instance.Nil)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch n := obj.(type) {
+		case instance.Integer:
+			if v.OverflowInt(int64(n)) {
+				return v, instance.NewDomainError(e, obj, class.Integer)
+			}
+			v.SetInt(int64(n))
+		case instance.BigInteger:
+			if !n.Int.IsInt64() || v.OverflowInt(n.Int.Int64()) {
+				return v, instance.NewDomainError(e, obj, class.Integer)
+			}
+			v.SetInt(n.Int.Int64())
+		case instance.Character:
+			if t.Kind() != reflect.Int32 {
+				return v, instance.NewDomainError(e, obj, class.Integer)
+			}
+			v.SetInt(int64(n))
+		default:
+			return v, instance.NewDomainError(e, obj, class.Integer)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch n := obj.(type) {
+		case instance.Integer:
+			if n < 0 || v.OverflowUint(uint64(n)) {
+				return v, instance.NewDomainError(e, obj, class.Integer)
+			}
+			v.SetUint(uint64(n))
+		case instance.BigInteger:
+			if !n.Int.IsUint64() || v.OverflowUint(n.Int.Uint64()) {
+				return v, instance.NewDomainError(e, obj, class.Integer)
+			}
+			v.SetUint(n.Int.Uint64())
+		default:
+			return v, instance.NewDomainError(e, obj, class.Integer)
+		}
+	case reflect.Float32, reflect.Float64:
+		switch n := obj.(type) {
+		case instance.Float:
+			v.SetFloat(float64(n))
+		case instance.Integer:
+			v.SetFloat(float64(n))
+		case instance.BigInteger:
+			f, _ := new(big.Float).SetInt(n.Int).Float64()
+			v.SetFloat(f)
+		default:
+			return v, instance.NewDomainError(e, obj, class.Number)
+		}
+	case reflect.String:
+		s, ok := obj.(instance.String)
+		if !ok {
+			return v, instance.NewDomainError(e, obj, class.String)
+		}
+		v.SetString(string(s))
+	case reflect.Slice, reflect.Array:
+		s, ok := elements(obj)
+		if !ok || (t.Kind() == reflect.Array && len(s) != t.Len()) {
+			return v, instance.NewDomainError(e, obj, class.List)
+		}
+		if t.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(t, len(s), len(s)))
+		}
+		for i, o := range s {
+			w, err := toGo(e, o, t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.Index(i).Set(w)
+		}
+	case reflect.Map:
+		s, ok := elements(obj)
+		if !ok {
+			return v, instance.NewDomainError(e, obj, class.List)
+		}
+		v.Set(reflect.MakeMapWithSize(t, len(s)))
+		for _, pair := range s {
+			cons, ok := pair.(*instance.Cons)
+			if !ok {
+				return v, instance.NewDomainError(e, pair, class.Cons)
+			}
+			key, err := toGo(e, cons.Car, t.Key())
+			if err != nil {
+				return v, err
+			}
+			if v.MapIndex(key).IsValid() {
+				continue
+			}
+			value, err := toGo(e, cons.Cdr, t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.SetMapIndex(key, value)
+		}
+	case reflect.Ptr:
+		if obj == instance.Nil && t.Elem().Kind() != reflect.Slice && t.Elem().Kind() != reflect.Map {
+			return v, nil
+		}
+		w, err := toGo(e, obj, t.Elem())
+		if err != nil {
+			return v, err
+		}
+		v.Set(reflect.New(t.Elem()))
+		v.Elem().Set(w)
+	case reflect.Struct:
+		o, ok := obj.(instance.Instance)
+		if !ok {
+			return v, instance.NewDomainError(e, obj, class.StandardObject)
+		}
+		for _, f := range classOf(t).fields {
+			slot, ok := slotValue(o, f.slot)
+			if !ok {
+				continue
+			}
+			w, err := toGo(e, slot, t.FieldByIndex(f.index).Type)
+			if err != nil {
+				return v, err
+			}
+			v.FieldByIndex(f.index).Set(w)
+		}
+	case reflect.Interface:
+		if n := natural(obj); n != nil {
+			v.Set(reflect.ValueOf(n))
+		}
+	default:
+		return v, instance.NewDomainError(e, obj, class.Object)
+	}
+	return v, nil
+}
+
+// fromGo converts a Go value to an instance. Slices and arrays become
+// general vectors if vector is true and lists otherwise. It returns false
+// if v has no counterpart in ISLisp.
+func fromGo(e env.Environment, v reflect.Value, vector bool) (ilos.Instance, bool) {
+	if !v.IsValid() {
+		return instance.Nil, true
+	}
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return instance.Nil, true
+		}
+	}
+	if t == bigIntType {
+		return instance.NewBigInteger(v.Interface().(*big.Int)), true
+	}
+	if t.Implements(instanceType) {
+		return v.Interface().(ilos.Instance), true
+	}
+	switch t.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return fromGo(e, v.Elem(), vector)
+	case reflect.Bool:
+		if v.Bool() {
+			return instance.T, true
+		}
+		return instance.Nil, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return instance.NewBigInteger(big.NewInt(v.Int())), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return instance.NewBigInteger(new(big.Int).SetUint64(v.Uint())), true
+	case reflect.Float32, reflect.Float64:
+		return instance.NewFloat(v.Float()), true
+	case reflect.String:
+		return instance.NewString([]rune(v.String())), true
+	case reflect.Slice, reflect.Array:
+		s := make([]ilos.Instance, v.Len())
+		for i := range s {
+			obj, ok := fromGo(e, v.Index(i), false)
+			if !ok {
+				return nil, false
+			}
+			s[i] = obj
+		}
+		if vector {
+			return instance.NewGeneralVector(s), true
+		}
+		var list ilos.Instance = instance.Nil
+		for i := len(s) - 1; i >= 0; i-- {
+			list = instance.NewCons(s[i], list)
+		}
+		return list, true
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		var alist ilos.Instance = instance.Nil
+		for i := len(keys) - 1; i >= 0; i-- {
+			key, ok := fromGo(e, keys[i], false)
+			if !ok {
+				return nil, false
+			}
+			value, ok := fromGo(e, v.MapIndex(keys[i]), false)
+			if !ok {
+				return nil, false
+			}
+			alist = instance.NewCons(instance.NewCons(key, value), alist)
+		}
+		return alist, true
+	case reflect.Struct:
+		c := classOf(t)
+		initargs := []ilos.Instance{}
+		for _, f := range c.fields {
+			w := v.FieldByIndex(f.index)
+			if f.omitempty && isEmpty(w) {
+				continue
+			}
+			obj, ok := fromGo(e, w, f.vector)
+			if !ok {
+				return nil, false
+			}
+			initargs = append(initargs, f.slot, obj)
+		}
+		return instance.Create(e, c.class, initargs...), true
+	}
+	return nil, false
+}
+
+// isEmpty reports whether v is the zero value or an empty collection, as
+// encoding/json does for omitempty.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// Marshal converts a Go value to an instance: booleans to T or NIL,
+// numbers to integers and floats, strings to strings, slices and arrays to
+// lists, maps to association lists sorted by key, structs to instances of
+// the class made for the struct type, and nil pointers, slices and maps to
+// NIL. An ilos.Instance is returned as it is.
+func Marshal(v interface{}) (ilos.Instance, error) {
+	rv := reflect.ValueOf(v)
+	if rv.IsValid() && !convertible(rv.Type()) {
+		return nil, fmt.Errorf("iris: cannot marshal %v", rv.Type())
+	}
+	obj, ok := fromGo(env.NewEnvironment(nil, nil, nil, nil), rv, false)
+	if !ok {
+		return nil, fmt.Errorf("iris: cannot marshal %v", rv.Type())
+	}
+	return obj, nil
+}
+
+// Unmarshal stores obj in the value pointed to by v, converting it in the
+// reverse way of Marshal. Lists and general vectors can be stored in slices,
+// association lists in maps and instances of any class in structs, whose
+// fields are set from the slots of the same names. If obj does not fit,
+// Unmarshal returns an *Error with a <domain-error>.
+func Unmarshal(obj ilos.Instance, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("iris: Unmarshal needs a non-nil pointer, not %v", reflect.TypeOf(v))
+	}
+	w, err := toGo(env.NewEnvironment(nil, nil, nil, nil), obj, rv.Type().Elem())
+	if err != nil {
+		return &Error{Condition: err}
+	}
+	rv.Elem().Set(w)
+	return nil
+}
+
+// Defclass binds the class which the struct type of v is marshaled to, so
+// that ISLisp code can create its instances and read and write their slots.
+// For a struct Point with a field X it defines the class <POINT>, the reader
+// POINT-X and the writer (SETF POINT-X).
+func (i *Interpreter) Defclass(v interface{}) error {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || !convertible(t) {
+		return fmt.Errorf("iris: cannot define a class for %v", t)
+	}
+	c := classOf(t)
+	e := i.env
+	e.Class.Define(instance.NewSymbol("<"+c.name+">"), c.class)
+	for _, f := range c.fields {
+		slot := f.slot
+		reader := instance.NewSymbol(fmt.Sprintf("%v-%v", c.name, slot))
+		writer := instance.NewSymbol(fmt.Sprintf("(SETF %v-%v)", c.name, slot))
+		readerLambdaList, _ := runtime.List(e, instance.NewSymbol("INSTANCE"))
+		writerLambdaList, _ := runtime.List(e, instance.NewSymbol("Y"), instance.NewSymbol("X"))
+		runtime.Defgeneric(e, reader, readerLambdaList)
+		runtime.Defgeneric(e, writer, writerLambdaList)
+		g, _ := e.Function.Get(reader)
+		g.(*instance.GenericFunction).AddMethod(nil, readerLambdaList, []ilos.Class{c.class}, instance.NewFunction(reader, func(e env.Environment, object ilos.Instance) (ilos.Instance, ilos.Instance) {
+			if v, ok := object.(instance.Instance).GetSlotValue(slot, c.class); ok {
+				return v, nil
+			}
+			return runtime.SignalCondition(e, instance.NewUndefinedVariable(e, slot), runtime.Nil)
+		}))
+		g, _ = e.Function.Get(writer)
+		g.(*instance.GenericFunction).AddMethod(nil, writerLambdaList, []ilos.Class{class.Object, c.class}, instance.NewFunction(writer, func(e env.Environment, obj, object ilos.Instance) (ilos.Instance, ilos.Instance) {
+			object.(instance.Instance).SetSlotValue(slot, obj, c.class)
+			return obj, nil
+		}))
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package iris
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+type Point struct {
+	X, Y int
+}
+
+type HTTPServer struct {
+	Host     string            `iris:"host"`
+	Ports    []int             `iris:"ports,vector"`
+	Headers  map[string]string `iris:",omitempty"`
+	Origin   *Point
+	Next     *HTTPServer
+	Password string `iris:"-"`
+	private  int
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"nil", nil, "NIL"},
+		{"true", true, "T"},
+		{"false", false, "NIL"},
+		{"int", 42, "42"},
+		{"uint64", uint64(1) << 63, "9223372036854775808"},
+		{"big", big.NewInt(7), "7"},
+		{"float", 1.5, "1.5"},
+		{"string", "a\"b", `"a\"b"`},
+		{"slice", []string{"a", "b"}, `("a" "b")`},
+		{"empty slice", []int{}, "NIL"},
+		{"nil slice", []int(nil), "NIL"},
+		{"array", [2]bool{true, false}, "(T NIL)"},
+		{"map", map[string]int{"b": 2, "a": 1}, `(("a" . 1) ("b" . 2))`},
+		{"nested", [][]int{{1}, {2, 3}}, "((1) (2 3))"},
+		{"interface", []interface{}{1, "a", nil}, `(1 "a" NIL)`},
+		{"instance", instance.NewSymbol("FOO"), "FOO"},
+	}
+	for _, tt := range tests {
+		got, err := Marshal(tt.v)
+		if err != nil {
+			t.Errorf("%v: Marshal() err = %v", tt.name, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("%v: Marshal() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	obj, err := Marshal(&Point{1, 2})
+	if err != nil || obj.Class().String() != "<POINT>" {
+		t.Errorf("Marshal(&Point{1, 2}) = %v, %v, want a <POINT>", obj, err)
+	}
+	if _, err := Marshal(make(chan int)); err == nil {
+		t.Errorf("Marshal(chan int) err = nil")
+	}
+}
+
+func TestMarshal_Struct(t *testing.T) {
+	obj, err := Marshal(HTTPServer{Host: "localhost", Ports: []int{80, 443}, Password: "secret", Next: &HTTPServer{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ilos.InstanceOf(class.StandardObject, obj) {
+		t.Fatalf("Marshal() = %v, want a <standard-object>", obj)
+	}
+	if name := obj.Class().String(); name != "<HTTP-SERVER>" {
+		t.Errorf("class = %v, want <HTTP-SERVER>", name)
+	}
+	slots := map[string]string{
+		"HOST":     `"localhost"`,
+		"PORTS":    "#(80 443)",
+		"ORIGIN":   "NIL",
+		"HEADERS":  "",
+		"PASSWORD": "",
+		"PRIVATE":  "",
+	}
+	for name, want := range slots {
+		v, ok := obj.(instance.Instance).GetSlotValue(instance.NewSymbol(name), obj.Class())
+		if want == "" {
+			if ok {
+				t.Errorf("slot %v = %v, want unbound", name, v)
+			}
+			continue
+		}
+		if !ok || v.String() != want {
+			t.Errorf("slot %v = %v, want %v", name, v, want)
+		}
+	}
+	var got HTTPServer
+	if err := Unmarshal(obj, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := HTTPServer{Host: "localhost", Ports: []int{80, 443}, Next: &HTTPServer{Ports: []int{}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, want)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	i := New()
+	read := func(src string) ilos.Instance {
+		obj, err := i.EvalString(src)
+		if err != nil {
+			t.Fatalf("%v: %v", src, err)
+		}
+		return obj
+	}
+	var n int
+	var f float32
+	var s []string
+	var a [2]int
+	var m map[string][]int
+	var p *Point
+	var x interface{}
+	var b bool
+	tests := []struct {
+		src  string
+		v    interface{}
+		want interface{}
+	}{
+		{"42", &n, 42},
+		{"3", &f, float32(3)},
+		{`'("a" "b")`, &s, []string{"a", "b"}},
+		{`#("a")`, &s, []string{"a"}},
+		{"'(1 2)", &a, [2]int{1, 2}},
+		{`'(("a" 1 2) ("b") ("a" 3))`, &m, map[string][]int{"a": {1, 2}, "b": {}}},
+		{"nil", &p, (*Point)(nil)},
+		{"'(1 (2.5 \"c\") #\\d)", &x, []interface{}{1, []interface{}{2.5, "c"}, 'd'}},
+		{"'a", &b, true},
+	}
+	for _, tt := range tests {
+		if err := Unmarshal(read(tt.src), tt.v); err != nil {
+			t.Errorf("Unmarshal(%v) err = %v", tt.src, err)
+			continue
+		}
+		if got := reflect.ValueOf(tt.v).Elem().Interface(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Unmarshal(%v) = %#v, want %#v", tt.src, got, tt.want)
+		}
+	}
+	errors := []struct {
+		src string
+		v   interface{}
+	}{
+		{`"a"`, &n},
+		{"'(1 . 2)", &s},
+		{"'(1 2 3)", &a},
+		{"'(1 2)", &m},
+		{"1", &p},
+	}
+	for _, tt := range errors {
+		err := Unmarshal(read(tt.src), tt.v)
+		if e, ok := err.(*Error); !ok || !ilos.InstanceOf(class.DomainError, e.Condition) {
+			t.Errorf("Unmarshal(%v) err = %v, want a <domain-error>", tt.src, err)
+		}
+	}
+	if err := Unmarshal(instance.Nil, n); err == nil {
+		t.Errorf("Unmarshal() into a non-pointer err = nil")
+	}
+}
+
+func TestUnmarshal_Limits(t *testing.T) {
+	for _, v := range []interface{}{uint64(math.MaxUint64), int64(math.MinInt64), int64(math.MaxInt64), uint32(math.MaxUint32)} {
+		obj, err := Marshal(v)
+		if err != nil {
+			t.Errorf("Marshal(%v) err = %v", v, err)
+			continue
+		}
+		got := reflect.New(reflect.TypeOf(v))
+		if err := Unmarshal(obj, got.Interface()); err != nil {
+			t.Errorf("Unmarshal(%v) err = %v", obj, err)
+			continue
+		}
+		if got.Elem().Interface() != v {
+			t.Errorf("Unmarshal(%v) = %v, want %v", obj, got.Elem(), v)
+		}
+	}
+	var u uint32
+	var i int64
+	var b uint8
+	errors := []struct {
+		v    interface{}
+		into interface{}
+	}{
+		{uint64(math.MaxUint64), &u},
+		{uint64(math.MaxUint64), &i},
+		{int64(-1), &b},
+		{new(big.Int).Lsh(big.NewInt(1), 64), new(uint64)},
+	}
+	for _, tt := range errors {
+		obj, _ := Marshal(tt.v)
+		err := Unmarshal(obj, tt.into)
+		if e, ok := err.(*Error); !ok || !ilos.InstanceOf(class.DomainError, e.Condition) {
+			t.Errorf("Unmarshal(%v) into %T err = %v, want a <domain-error>", obj, tt.into, err)
+		}
+	}
+}
+
+func TestInterpreter_Defclass(t *testing.T) {
+	i := New()
+	if err := i.Defclass(Point{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Defun("origin", func() Point { return Point{} }); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Defun("norm", func(p Point) int { return p.X*p.X + p.Y*p.Y }); err != nil {
+		t.Fatal(err)
+	}
+	got, err := i.EvalString(`
+	  (defglobal p (create (class <point>) 'x 3 'y 0))
+	  (setf (point-y p) 4)
+	  (list (point-x p) (point-y p) (norm p) (instancep (origin) (class <point>)))`)
+	if err != nil || got.String() != "(3 4 25 T)" {
+		t.Errorf("EvalString() = %v, %v, want (3 4 25 T)", got, err)
+	}
+	if err := i.Defclass(1); err == nil {
+		t.Errorf("Defclass(1) err = nil")
+	}
+}
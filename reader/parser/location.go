@@ -0,0 +1,28 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"github.com/islisp-dev/iris/reader/tokenizer"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// setLocation records span as the location of obj in the environment of p,
+// if obj is a cons and p reads forms to be evaluated.
+func (p *parser) setLocation(obj ilos.Instance, span tokenizer.Span) {
+	if _, ok := obj.(*instance.Cons); ok && p.locate && p.e.Locations != nil {
+		p.e.Locations[obj] = span
+	}
+}
+
+// Location returns the span of the text which obj was read from, if it was
+// read by ReadForm in e or in an environment made from the same top level
+// environment.
+func Location(e env.Environment, obj ilos.Instance) (tokenizer.Span, bool) {
+	span, ok := e.Locations[obj]
+	return span, ok
+}
@@ -5,10 +5,12 @@
 package parser
 
 import (
-	"math"
+	"io"
+	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/islisp-dev/iris/reader/tokenizer"
 	"github.com/islisp-dev/iris/runtime/env"
@@ -20,56 +22,76 @@ import (
 var eop = instance.NewSymbol("End Of Parentheses")
 var bod = instance.NewSymbol("Begin Of Dot")
 
+// unescape removes each backslash and keeps the rune following it as is.
+func unescape(s string) string {
+	buf := new(strings.Builder)
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// parseInteger returns the integer of digits in base, which must be valid.
+func parseInteger(digits string, base int) ilos.Instance {
+	if n, err := strconv.ParseInt(digits, base, 0); err == nil {
+		return instance.NewInteger(int(n))
+	}
+	n, _ := new(big.Int).SetString(strings.TrimPrefix(digits, "+"), base)
+	return instance.NewBigInteger(n)
+}
+
 func ParseAtom(tok string) (ilos.Instance, ilos.Instance) {
 	//
 	// integer
 	//
 	if m, _ := regexp.MatchString("^[-+]?[[:digit:]]+$", tok); m {
-		n, _ := strconv.ParseInt(tok, 10, 64)
-		return instance.NewInteger(int(n)), nil
+		return parseInteger(tok, 10), nil
 	}
 	if r := regexp.MustCompile("^#[bB]([-+]?[01]+)$").FindStringSubmatch(tok); len(r) >= 2 {
-		n, _ := strconv.ParseInt(r[1], 2, 64)
-		return instance.NewInteger(int(n)), nil
+		return parseInteger(r[1], 2), nil
 	}
 	if r := regexp.MustCompile("^#[oO]([-+]?[0-7]+)$").FindStringSubmatch(tok); len(r) >= 2 {
-		n, _ := strconv.ParseInt(r[1], 8, 64)
-		return instance.NewInteger(int(n)), nil
+		return parseInteger(r[1], 8), nil
 	}
 	if r := regexp.MustCompile("^#[xX]([-+]?[[:xdigit:]]+)$").FindStringSubmatch(tok); len(r) >= 2 {
-		n, _ := strconv.ParseInt(r[1], 16, 64)
-		return instance.NewInteger(int(n)), nil
+		return parseInteger(r[1], 16), nil
 	}
 	//
 	// float
 	//
-	if m, _ := regexp.MatchString(`^[-+]?[[:digit:]]+\.[[:digit:]]+$`, tok); m {
-		n, _ := strconv.ParseFloat(tok, 64)
-		return instance.NewFloat(n), nil
-	}
-	if r := regexp.MustCompile(`^([-+]?[[:digit:]]+(?:\.[[:digit:]]+)?)[eE]([-+]?[[:digit:]]+)$`).FindStringSubmatch(tok); len(r) >= 3 {
-		n, _ := strconv.ParseFloat(r[1], 64)
-		e, _ := strconv.ParseInt(r[2], 10, 64)
-		return instance.NewFloat(n * math.Pow10(int(e))), nil
+	if m, _ := regexp.MatchString(`^[-+]?[[:digit:]]+(?:\.[[:digit:]]+)?(?:[eE][-+]?[[:digit:]]+)?$`, tok); m {
+		if n, err := strconv.ParseFloat(tok, 64); err == nil {
+			return instance.NewFloat(n), nil
+		}
+		return nil, instance.Create(env.NewEnvironment(nil, nil, nil, nil),
+			class.ParseError,
+			instance.NewSymbol("STRING"), instance.NewString([]rune(tok)),
+			instance.NewSymbol("EXPECTED-CLASS"), class.Float)
 	}
 	//
 	// character
 	//
-	if m, _ := regexp.MatchString(`^#\\newline$`, strings.ToLower(tok)); m {
-		return instance.NewCharacter('\n'), nil
-	}
-	if m, _ := regexp.MatchString(`^#\\space$`, strings.ToLower(tok)); m {
-		return instance.NewCharacter(' '), nil
-	}
-	if r := regexp.MustCompile(`^#\\([[:graph:]])$`).FindStringSubmatch(tok); len(r) >= 2 {
-		return instance.NewCharacter(rune(r[1][0])), nil
+	if strings.HasPrefix(tok, `#\`) {
+		name := tok[2:]
+		if utf8.RuneCountInString(name) == 1 {
+			r, _ := utf8.DecodeRuneInString(name)
+			return instance.NewCharacter(r), nil
+		}
+		if r, ok := instance.CharacterByName(name); ok {
+			return instance.NewCharacter(r), nil
+		}
 	}
 	//
 	// string
 	//
-	if r := regexp.MustCompile(`^"(.*)"$`).FindStringSubmatch(tok); len(r) >= 2 {
-		s := strings.Replace(r[1], "\\\\", "\\", -1)
-		return instance.NewString([]rune(s)), nil
+	if len(tok) >= 2 && strings.HasPrefix(tok, `"`) && strings.HasSuffix(tok, `"`) {
+		return instance.NewString([]rune(unescape(tok[1 : len(tok)-1]))), nil
 	}
 	//
 	// symbol
@@ -77,11 +99,13 @@ func ParseAtom(tok string) (ilos.Instance, ilos.Instance) {
 	if tok == "nil" {
 		return instance.Nil, nil
 	}
+	if len(tok) >= 2 && strings.HasPrefix(tok, "|") && strings.HasSuffix(tok, "|") {
+		return instance.NewSymbol(unescape(tok[1 : len(tok)-1])), nil
+	}
 	str := `^(`
 	str += `[:&][a-zA-Z]+|`
-	str += `\|.*\||`
 	str += `\+|-|1\+|1-|`
-	str += `[a-zA-Z<>/*=?_!$%[\]^{}~][-a-zA-Z0-9+<>/*=?_!$%[\]^{}~]*|`
+	str += `[a-zA-Z<>/*=?_!$%[\]^{}~][-a-zA-Z0-9+<>/*=?_!$%[\]^{}~.]*|`
 	str += `)$`
 	if m, _ := regexp.MatchString(str, tok); m {
 		return instance.NewSymbol(strings.ToUpper(tok)), nil
@@ -92,59 +116,36 @@ func ParseAtom(tok string) (ilos.Instance, ilos.Instance) {
 		instance.NewSymbol("EXPECTED-CLASS"), class.Object)
 }
 
-func parseMacro(tok string, t *tokenizer.Reader) (ilos.Instance, ilos.Instance) {
-	cdr, err := Parse(t)
-	if err != nil {
-		return nil, err
-	}
-	n := tok
-	if m, _ := regexp.MatchString("#[[:digit:]]+[aA]", tok); m {
-		i := strings.IndexRune(strings.ToLower(tok), 'a')
-		var v int64 = 1
-		if i != 1 {
-			var err error
-			v, err = strconv.ParseInt(tok[1:i], 10, 64)
-			if err != nil {
-				return nil, instance.Create(env.NewEnvironment(nil, nil, nil, nil),
-					class.ParseError,
-					instance.NewSymbol("STRING"), instance.NewString([]rune(tok)),
-					instance.NewSymbol("EXPECTED-CLASS"), class.Integer)
-			}
-		}
-		if int(v) == 1 {
-			return list2vector(cdr)
-		}
-		return list2array(int(v), cdr)
-	}
-	if tok == "#" {
-		return list2vector(cdr)
-	}
-	switch tok {
-	case "#'":
-		n = "FUNCTION"
-	case ",@":
-		n = "UNQUOTE-SPLICING"
-	case ",":
-		n = "UNQUOTE"
-	case "'":
-		n = "QUOTE"
-	case "`":
-		n = "QUASIQUOTE"
-	}
-	m := instance.NewSymbol(n)
-	return instance.NewCons(m, instance.NewCons(cdr, instance.Nil)), nil
+// unterminated returns a parse error for a form beginning at start with tok
+// which is cut off by the end of the stream.
+func unterminated(e env.Environment, start tokenizer.Position, tok string) ilos.Instance {
+	err := instance.Create(e,
+		class.ParseError,
+		instance.NewSymbol("STRING"), instance.NewString([]rune(tok)),
+		instance.NewSymbol("EXPECTED-CLASS"), class.Object)
+	err.(instance.Instance).SetSlotValue(instance.NewSymbol("IRIS.LOCATION"), instance.NewString([]rune(start.String())), class.SeriousCondition)
+	return err
+}
+
+type parser struct {
+	e      env.Environment
+	stream ilos.Instance
+	t      *tokenizer.Reader
+	table  *Readtable
+	locate bool
 }
-func parseCons(t *tokenizer.Reader) (ilos.Instance, ilos.Instance) {
-	car, err := Parse(t)
+
+func (p *parser) parseCons() (ilos.Instance, ilos.Instance) {
+	car, span, err := p.parse()
 	if err == eop {
 		return instance.Nil, nil
 	}
 	if err == bod {
-		cdr, err := Parse(t)
+		cdr, _, err := p.parse()
 		if err != nil {
 			return nil, err
 		}
-		if _, err := Parse(t); err != eop {
+		if _, _, err := p.parse(); err != eop {
 			return nil, err
 		}
 		return cdr, nil
@@ -152,48 +153,156 @@ func parseCons(t *tokenizer.Reader) (ilos.Instance, ilos.Instance) {
 	if err != nil {
 		return nil, err
 	}
-	cdr, err := parseCons(t)
+	cdr, err := p.parseCons()
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewCons(car, cdr), nil
+	cons := instance.NewCons(car, cdr)
+	span.End = p.t.Position()
+	p.setLocation(cons, span)
+	return cons, nil
 }
 
-// Parse builds a internal expression from tokens
-func Parse(t *tokenizer.Reader) (ilos.Instance, ilos.Instance) {
-	tok, err := t.Next()
-	if err != nil {
-		return nil, instance.Create(env.NewEnvironment(nil, nil, nil, nil), class.EndOfStream)
+// parseMacro reads a form with the reader macro function of the macro
+// character at the head of the input. It returns false if there is no such
+// macro character; then the runes read so far are returned as prefix.
+func (p *parser) parseMacro() (ilos.Instance, string, bool, ilos.Instance) {
+	t := p.t
+	ch, _ := t.PeekRune()
+	if f, ok := p.table.Macro(ch); ok {
+		t.ReadRune()
+		obj, err := f.(instance.Applicable).Apply(p.e.NewDynamic(), p.stream, instance.NewCharacter(ch))
+		return obj, "", true, err
 	}
-	for (len(tok) > 2 && tok[:2] == "#|") || tok[:1] == ";" {
-		tok, err = t.Next()
-		if err != nil {
-			return nil, instance.Create(env.NewEnvironment(nil, nil, nil, nil), class.EndOfStream)
-		}
+	if !p.table.IsDispatch(ch) {
+		return nil, "", false, nil
+	}
+	t.ReadRune()
+	prefix := string(ch)
+	sub, ok := t.PeekRune()
+	for ok && '0' <= sub && sub <= '9' {
+		t.ReadRune()
+		prefix += string(sub)
+		sub, ok = t.PeekRune()
+	}
+	f, found := p.table.Dispatch(ch, sub)
+	if !ok || !found {
+		return nil, prefix, false, nil
 	}
-	if tok == "(" {
-		cons, err := parseCons(t)
+	t.ReadRune()
+	var arg ilos.Instance = instance.Nil
+	if len(prefix) > 1 {
+		n, err := strconv.Atoi(prefix[1:])
 		if err != nil {
-			return nil, err
+			return nil, prefix, true, instance.Create(p.e,
+				class.ParseError,
+				instance.NewSymbol("STRING"), instance.NewString([]rune(prefix)),
+				instance.NewSymbol("EXPECTED-CLASS"), class.Integer)
 		}
-		return cons, err
-	}
-	if tok == ")" {
-		return nil, eop
+		arg = instance.NewInteger(n)
 	}
-	if tok == "." {
-		return nil, bod
+	obj, err := f.(instance.Applicable).Apply(p.e.NewDynamic(), p.stream, instance.NewCharacter(sub), arg)
+	return obj, prefix, true, err
+}
+
+func (p *parser) parse() (ilos.Instance, tokenizer.Span, ilos.Instance) {
+	t := p.t
+	t.Terminator = p.table
+	var tok *tokenizer.Token
+	for {
+		if !t.SkipSpace() {
+			return nil, tokenizer.Span{}, instance.Create(p.e, class.EndOfStream)
+		}
+		start := t.Position()
+		obj, prefix, ok, err := p.parseMacro()
+		t.Terminator = p.table
+		if ok {
+			span := tokenizer.Span{Start: start, End: t.Position()}
+			if err != nil {
+				return nil, span, err
+			}
+			p.setLocation(obj, span)
+			return obj, span, nil
+		}
+		var err1 error
+		if prefix != "" {
+			tok, err1 = t.NextFrom(start, prefix)
+		} else {
+			ru, _ := t.PeekRune()
+			prefix = string(ru)
+			tok, err1 = t.Next()
+		}
+		if err1 == io.ErrUnexpectedEOF {
+			return nil, tokenizer.Span{Start: start, End: t.Position()}, unterminated(p.e, start, prefix)
+		}
+		if err1 != nil {
+			return nil, tokenizer.Span{}, instance.Create(p.e, class.EndOfStream)
+		}
+		if !strings.HasPrefix(tok.Str, "#|") && !strings.HasPrefix(tok.Str, ";") {
+			break
+		}
 	}
-	if mat, _ := regexp.MatchString("^(?:#'|,@?|'|`|#[[:digit:]]*[aA]|#)$", tok); mat {
-		m, err := parseMacro(tok, t)
+	span := tok.Span
+	if tok.Str == "(" {
+		cons, err := p.parseCons()
+		if err != nil && ilos.InstanceOf(class.EndOfStream, err) {
+			return nil, span, unterminated(p.e, span.Start, "(")
+		}
 		if err != nil {
-			return nil, err
+			return nil, span, err
 		}
-		return m, nil
+		span.End = t.Position()
+		p.setLocation(cons, span)
+		return cons, span, err
+	}
+	if tok.Str == ")" {
+		return nil, span, eop
+	}
+	if tok.Str == "." {
+		return nil, span, bod
 	}
-	atom, err1 := ParseAtom(tok)
+	atom, err1 := ParseAtom(tok.Str)
 	if err1 != nil {
-		return nil, err1
+		err1.(instance.Instance).SetSlotValue(instance.NewSymbol("IRIS.LOCATION"), instance.NewString([]rune(span.String())), class.SeriousCondition)
+		return nil, span, err1
+	}
+	return atom, span, nil
+}
+
+// Parse builds a internal expression from tokens
+func Parse(t *tokenizer.Reader) (ilos.Instance, ilos.Instance) {
+	e := env.NewEnvironment(nil, nil, nil, nil)
+	stream := instance.Stream{Column: new(int), ElementClass: class.Character, Reader: t, Readtable: new(ilos.Instance)}
+	obj, _, err := (&parser{e, stream, t, CurrentReadtable(e, stream), false}).parse()
+	return obj, err
+}
+
+// Read reads a form from stream with the current readtable of it. Reader
+// macro functions are called in e.
+func Read(e env.Environment, stream ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return read(e, stream, false)
+}
+
+// ReadForm reads a form to be evaluated from stream as Read does, and records
+// the spans of the texts which its conses were read from in the locations of
+// e.
+func ReadForm(e env.Environment, stream ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return read(e, stream, true)
+}
+
+func read(e env.Environment, stream ilos.Instance, locate bool) (ilos.Instance, ilos.Instance) {
+	s := stream.(instance.Stream)
+	obj, span, err := (&parser{e, stream, s.Reader, CurrentReadtable(e, stream), locate}).parse()
+	if err == eop || err == bod {
+		tok := ")"
+		if err == bod {
+			tok = "."
+		}
+		err = instance.Create(e,
+			class.ParseError,
+			instance.NewSymbol("STRING"), instance.NewString([]rune(tok)),
+			instance.NewSymbol("EXPECTED-CLASS"), class.Object)
+		err.(instance.Instance).SetSlotValue(instance.NewSymbol("IRIS.LOCATION"), instance.NewString([]rune(span.String())), class.SeriousCondition)
 	}
-	return atom, nil
+	return obj, err
 }
@@ -5,10 +5,16 @@
 package parser
 
 import (
+	"math"
+	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/islisp-dev/iris/reader/tokenizer"
+	"github.com/islisp-dev/iris/runtime/env"
 	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
 	"github.com/islisp-dev/iris/runtime/ilos/instance"
 )
 
@@ -124,6 +130,18 @@ func Test_parseAtom(t *testing.T) {
 			want:      nil,
 			wantErr:   true,
 		},
+		{
+			name:      "bignum",
+			arguments: arguments{"+18446744073709551616"},
+			want:      instance.NewBigInteger(new(big.Int).Lsh(big.NewInt(1), 64)),
+			wantErr:   false,
+		},
+		{
+			name:      "hexadecimal bignum",
+			arguments: arguments{"#x-10000000000000000"},
+			want:      instance.NewBigInteger(new(big.Int).Lsh(big.NewInt(-1), 64)),
+			wantErr:   false,
+		},
 		//
 		// Character
 		//
@@ -145,12 +163,66 @@ func Test_parseAtom(t *testing.T) {
 			want:      instance.NewCharacter(' '),
 			wantErr:   false,
 		},
+		{
+			name:      "tab",
+			arguments: arguments{"#\\Tab"},
+			want:      instance.NewCharacter('\t'),
+			wantErr:   false,
+		},
+		{
+			name:      "linefeed",
+			arguments: arguments{"#\\LINEFEED"},
+			want:      instance.NewCharacter('\n'),
+			wantErr:   false,
+		},
+		{
+			name:      "unicode",
+			arguments: arguments{"#\\λ"},
+			want:      instance.NewCharacter('λ'),
+			wantErr:   false,
+		},
+		{
+			name:      "code point",
+			arguments: arguments{"#\\U+1F600"},
+			want:      instance.NewCharacter(0x1F600),
+			wantErr:   false,
+		},
 		{
 			name:      "invalid character name",
 			arguments: arguments{"#\\foo"},
 			want:      nil,
 			wantErr:   true,
 		},
+		//
+		// String
+		//
+		{
+			name:      "escaped string",
+			arguments: arguments{`"a\"b\\c\d"`},
+			want:      instance.NewString([]rune(`a"b\cd`)),
+			wantErr:   false,
+		},
+		{
+			name:      "multiline string",
+			arguments: arguments{"\"a\nb\""},
+			want:      instance.NewString([]rune("a\nb")),
+			wantErr:   false,
+		},
+		//
+		// Symbol
+		//
+		{
+			name:      "symbol",
+			arguments: arguments{"foo-bar"},
+			want:      instance.NewSymbol("FOO-BAR"),
+			wantErr:   false,
+		},
+		{
+			name:      "escaped symbol",
+			arguments: arguments{`|foo \|bar\\|`},
+			want:      instance.NewSymbol(`foo |bar\`),
+			wantErr:   false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -165,3 +237,175 @@ func Test_parseAtom(t *testing.T) {
 		})
 	}
 }
+
+func TestReadForm_Location(t *testing.T) {
+	e := env.NewEnvironment(nil, nil, nil, nil)
+	stream := instance.NewStream(strings.NewReader("; comment\n  (foo\n 'bar)"), nil, class.Character)
+	got, err := ReadForm(e, stream)
+	if err != nil {
+		t.Fatalf("ReadForm() error = %v", err)
+	}
+	tests := []struct {
+		name  string
+		form  ilos.Instance
+		start tokenizer.Position
+		end   tokenizer.Position
+	}{
+		{
+			name:  "list",
+			form:  got,
+			start: tokenizer.Position{Line: 2, Column: 3},
+			end:   tokenizer.Position{Line: 3, Column: 7},
+		},
+		{
+			name:  "rest",
+			form:  got.(*instance.Cons).Cdr,
+			start: tokenizer.Position{Line: 3, Column: 2},
+			end:   tokenizer.Position{Line: 3, Column: 7},
+		},
+		{
+			name:  "quote",
+			form:  got.(instance.List).Nth(1),
+			start: tokenizer.Position{Line: 3, Column: 2},
+			end:   tokenizer.Position{Line: 3, Column: 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span, ok := Location(e, tt.form)
+			if !ok || span.Start != tt.start || span.End != tt.end {
+				t.Errorf("Location() = %v-%v, want %v-%v", span.Start, span.End, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestRead_Location(t *testing.T) {
+	e := env.NewEnvironment(nil, nil, nil, nil)
+	stream := instance.NewStream(strings.NewReader("(data) (form)"), nil, class.Character)
+	data, err := Read(e, stream)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if _, ok := Location(e, data); ok {
+		t.Errorf("Location() of %v read as data = true, want false", data)
+	}
+	form, err := ReadForm(e, stream)
+	if err != nil {
+		t.Fatalf("ReadForm() error = %v", err)
+	}
+	if span, ok := Location(e, form); !ok || span.Start.Column != 8 || span.End.Column != 14 {
+		t.Errorf("Location() of %v = %v-%v, %v, want 1:8-1:14", form, span.Start, span.End, ok)
+	}
+	if _, ok := Location(env.NewEnvironment(nil, nil, nil, nil), form); ok {
+		t.Errorf("Location() of %v read in another environment = true, want false", form)
+	}
+}
+
+func TestParse_Unterminated(t *testing.T) {
+	tests := []struct {
+		src      string
+		class    ilos.Class
+		location string
+	}{
+		{"", class.EndOfStream, ""},
+		{"  ; comment", class.EndOfStream, ""},
+		{"\n (car (a b)", class.ParseError, "2:2"},
+		{` "abc`, class.ParseError, "1:2"},
+		{`|abc`, class.ParseError, "1:1"},
+		{`#| abc`, class.ParseError, "1:1"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tokenizer.NewReader(strings.NewReader(tt.src)))
+		if !ilos.InstanceOf(tt.class, err) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.src, err, tt.class)
+			continue
+		}
+		if tt.location == "" {
+			continue
+		}
+		location, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.LOCATION"), class.SeriousCondition)
+		if location.String() != `"`+tt.location+`"` {
+			t.Errorf("Parse(%q) location = %v, want %v", tt.src, location, tt.location)
+		}
+	}
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	tests := []ilos.Instance{
+		instance.NewCharacter('a'),
+		instance.NewCharacter(' '),
+		instance.NewCharacter('\t'),
+		instance.NewCharacter('\r'),
+		instance.NewCharacter(0x7f),
+		instance.NewCharacter(0x0),
+		instance.NewCharacter('('),
+		instance.NewCharacter('λ'),
+		instance.NewCharacter(0x200b),
+		instance.NewString([]rune("say \"hi\"\\\n")),
+		instance.NewSymbol("FOO"),
+		instance.NewSymbol("foo"),
+		instance.NewSymbol("a b|c\\"),
+		instance.NewSymbol("123"),
+		instance.NewSymbol(""),
+		instance.NewBigInteger(new(big.Int).Lsh(big.NewInt(-3), 100)),
+		instance.NewFloat(1.0),
+		instance.NewFloat(0.1),
+		instance.NewFloat(-2.5),
+		instance.NewFloat(1.5e10),
+		instance.NewFloat(1e-300),
+		instance.NewFloat(5e-324),
+		instance.NewFloat(math.MaxFloat64),
+		instance.NewFloat(123456.789),
+		instance.NewFloat(0.000999),
+	}
+	for _, tt := range tests {
+		t.Run(tt.String(), func(t *testing.T) {
+			got, err := Parse(tokenizer.NewReader(strings.NewReader(tt.String())))
+			if err != nil {
+				t.Fatalf("Parse(%v) error = %v", tt, err)
+			}
+			if !reflect.DeepEqual(got, tt) {
+				t.Errorf("Parse(%v) = %#v, want %#v", tt, got, tt)
+			}
+		})
+	}
+}
+
+func TestParse_Float(t *testing.T) {
+	tests := []struct {
+		tok  string
+		str  string
+		want float64
+	}{
+		{"1.0", "1.0", 1.0},
+		{"1.5e10", "1.5E10", 1.5e10},
+		{"15000000000.0", "1.5E10", 1.5e10},
+		{"-0.0", "-0.0", math.Copysign(0, -1)},
+		{"1E-5", "1.0E-5", 1e-5},
+		{"0.30000000000000004", "0.30000000000000004", 0.30000000000000004},
+		{"2.2250738585072011e-308", "2.225073858507201E-308", 2.2250738585072011e-308},
+		{"9007199254740993", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tok, func(t *testing.T) {
+			got, err := ParseAtom(tt.tok)
+			if err != nil {
+				t.Fatalf("ParseAtom(%v) error = %v", tt.tok, err)
+			}
+			f, ok := got.(instance.Float)
+			if tt.str == "" {
+				if ok {
+					t.Errorf("ParseAtom(%v) = %v, want an integer", tt.tok, got)
+				}
+				return
+			}
+			if !ok || math.Float64bits(float64(f)) != math.Float64bits(tt.want) {
+				t.Errorf("ParseAtom(%v) = %v, want %v", tt.tok, got, tt.want)
+			}
+			if got.String() != tt.str {
+				t.Errorf("%v.String() = %v, want %v", tt.tok, got.String(), tt.str)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package parser
+
+import (
+	"unicode"
+
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// Readtable maps macro characters to reader macro functions. A macro
+// function is called with the stream and the character and returns the
+// form it read. A dispatching macro character has a table of sub
+// characters whose functions take the stream, the sub character and the
+// decimal argument between them (or NIL).
+type Readtable struct {
+	macros      map[rune]ilos.Instance
+	dispatch    map[rune]map[rune]ilos.Instance
+	terminating map[rune]bool
+}
+
+// NewReadtable returns a readtable with the standard syntax.
+func NewReadtable() *Readtable {
+	return standard.Copy()
+}
+
+func (*Readtable) Class() ilos.Class {
+	return class.Readtable
+}
+
+func (*Readtable) String() string {
+	return "#<READTABLE>"
+}
+
+// Copy returns a readtable which has the same entries as r.
+func (r *Readtable) Copy() *Readtable {
+	c := &Readtable{
+		map[rune]ilos.Instance{},
+		map[rune]map[rune]ilos.Instance{},
+		map[rune]bool{},
+	}
+	for k, v := range r.macros {
+		c.macros[k] = v
+	}
+	for k, v := range r.dispatch {
+		c.dispatch[k] = map[rune]ilos.Instance{}
+		for l, w := range v {
+			c.dispatch[k][l] = w
+		}
+	}
+	for k, v := range r.terminating {
+		c.terminating[k] = v
+	}
+	return c
+}
+
+// SetMacro makes ch a macro character which reads with function.
+func (r *Readtable) SetMacro(ch rune, function ilos.Instance, nonTerminating bool) {
+	delete(r.dispatch, ch)
+	r.macros[ch] = function
+	r.terminating[ch] = !nonTerminating
+}
+
+// Macro returns the function of the macro character ch.
+func (r *Readtable) Macro(ch rune) (ilos.Instance, bool) {
+	f, ok := r.macros[ch]
+	return f, ok
+}
+
+// MakeDispatch makes ch a dispatching macro character with no sub characters.
+func (r *Readtable) MakeDispatch(ch rune, nonTerminating bool) {
+	delete(r.macros, ch)
+	r.dispatch[ch] = map[rune]ilos.Instance{}
+	r.terminating[ch] = !nonTerminating
+}
+
+// SetDispatch sets the function of sub under the dispatching macro
+// character ch. It returns false if ch is not a dispatching macro character.
+func (r *Readtable) SetDispatch(ch, sub rune, function ilos.Instance) bool {
+	table, ok := r.dispatch[ch]
+	if !ok {
+		return false
+	}
+	table[unicode.ToUpper(sub)] = function
+	return true
+}
+
+// Dispatch returns the function of sub under the dispatching macro character ch.
+func (r *Readtable) Dispatch(ch, sub rune) (ilos.Instance, bool) {
+	f, ok := r.dispatch[ch][unicode.ToUpper(sub)]
+	return f, ok
+}
+
+// IsDispatch reports whether ch is a dispatching macro character.
+func (r *Readtable) IsDispatch(ch rune) bool {
+	_, ok := r.dispatch[ch]
+	return ok
+}
+
+// IsTerminating reports whether ch is a terminating macro character.
+func (r *Readtable) IsTerminating(ch rune) bool {
+	return r.terminating[ch]
+}
+
+var readtableSymbol = instance.NewSymbol("*READTABLE*")
+
+// CurrentReadtable returns the readtable used to read from stream: the
+// one selected for the stream, otherwise the value of the dynamic
+// variable *READTABLE*, otherwise the standard readtable.
+func CurrentReadtable(e env.Environment, stream ilos.Instance) *Readtable {
+	if s, ok := stream.(instance.Stream); ok && s.Readtable != nil {
+		if r, ok := (*s.Readtable).(*Readtable); ok {
+			return r
+		}
+	}
+	if v, ok := e.DynamicVariable.Get(readtableSymbol); ok {
+		if r, ok := v.(*Readtable); ok {
+			return r
+		}
+	}
+	return standard
+}
+
+func readQuote(name string) func(env.Environment, ilos.Instance, ilos.Instance) (ilos.Instance, ilos.Instance) {
+	symbol := instance.NewSymbol(name)
+	return func(e env.Environment, stream, char ilos.Instance) (ilos.Instance, ilos.Instance) {
+		obj, err := Read(e, stream)
+		if err != nil {
+			return nil, err
+		}
+		return instance.NewCons(symbol, instance.NewCons(obj, instance.Nil)), nil
+	}
+}
+
+func readComma(e env.Environment, stream, char ilos.Instance) (ilos.Instance, ilos.Instance) {
+	t := stream.(instance.Stream).Reader
+	if ru, ok := t.PeekRune(); ok && ru == '@' {
+		t.ReadRune()
+		return readQuote("UNQUOTE-SPLICING")(e, stream, char)
+	}
+	return readQuote("UNQUOTE")(e, stream, char)
+}
+
+func readVector(e env.Environment, stream, char, arg ilos.Instance) (ilos.Instance, ilos.Instance) {
+	t := stream.(instance.Stream).Reader
+	t.UnreadRune()
+	obj, err := Read(e, stream)
+	if err != nil {
+		return nil, err
+	}
+	return list2vector(obj)
+}
+
+func readArray(e env.Environment, stream, char, arg ilos.Instance) (ilos.Instance, ilos.Instance) {
+	obj, err := Read(e, stream)
+	if err != nil {
+		return nil, err
+	}
+	rank, ok := arg.(instance.Integer)
+	if !ok || rank == 1 {
+		return list2vector(obj)
+	}
+	return list2array(int(rank), obj)
+}
+
+func readFunction(e env.Environment, stream, char, arg ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return readQuote("FUNCTION")(e, stream, char)
+}
+
+var standard *Readtable
+
+func init() {
+	standard = &Readtable{
+		map[rune]ilos.Instance{
+			'\'': instance.NewFunction(instance.NewSymbol("READ-QUOTE"), readQuote("QUOTE")),
+			'`':  instance.NewFunction(instance.NewSymbol("READ-QUASIQUOTE"), readQuote("QUASIQUOTE")),
+			',':  instance.NewFunction(instance.NewSymbol("READ-UNQUOTE"), readComma),
+		},
+		map[rune]map[rune]ilos.Instance{
+			'#': {
+				'\'': instance.NewFunction(instance.NewSymbol("READ-FUNCTION"), readFunction),
+				'(':  instance.NewFunction(instance.NewSymbol("READ-VECTOR"), readVector),
+				'A':  instance.NewFunction(instance.NewSymbol("READ-ARRAY"), readArray),
+			},
+		},
+		map[rune]bool{'\'': true, '`': true, ',': true},
+	}
+}
@@ -2,115 +2,357 @@ package tokenizer
 
 import (
 	"bufio"
+	"fmt"
 	"io"
-	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
+// Position is a point in a source text. Line and Column are 1-origin and
+// Column counts runes, not bytes.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%v:%v", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%v:%v:%v", p.File, p.Line, p.Column)
+}
+
+// Span is the half-open range [Start, End) of a text.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (s Span) String() string {
+	return s.Start.String()
+}
+
+// Token is a lexeme with the span it was read from.
+type Token struct {
+	Str string
+	Span
+}
+
+func (t *Token) String() string {
+	return t.Str
+}
+
+// Terminator reports whether a rune is a terminating macro character.
+type Terminator interface {
+	IsTerminating(rune) bool
+}
+
 // Reader interface type is the interface
 // for reading string with every token
-// Reader is like bufio.Reader but has PeekRune
-// which returns a rune without advancing pointer
+// Reader is like bufio.Reader but keeps track of
+// the position of the next rune to be read
 type Reader struct {
 	Raw io.Reader
 	*bufio.Reader
+	pos  Position
+	last Position
+	// Terminator tells which runes terminate an atom in addition to the
+	// standard delimiters. It is set by the parser from the readtable in
+	// use.
+	Terminator Terminator
+
+	// filling is closed when a read left waiting by Wait returns.
+	filling chan struct{}
 }
 
-// NewReader creates interal reader from io.RuneReader
+// NewReader creates interal reader from io.RuneReader.
+// If r has Name method like *os.File, it is used as the file name of positions.
 func NewReader(r io.Reader) *Reader {
-	return &Reader{r, bufio.NewReader(r)}
-}
-
-var str = `^1\+$|^1-$|` +
-	`^[-+]?[[:digit:]]+\.[[:digit:]]+$|` +
-	`^[-+]?[[:digit:]]+(?:\.[[:digit:]]+)?[eE][-+]?[[:digit:]]+$|` +
-	`^[-+]?[[:digit:]]+$|` +
-	`^#[bB][-+]?[01]+$|` +
-	`^#[oO][-+]?[0-7]+$|` +
-	`^#[xX][-+]?[[:xdigit:]]+$|` +
-	`^#\\[[:alpha:]]+$|` +
-	`^#\\[[:graph:]]$|` +
-	`^"(?:\\\\|\\.|[^\\"])*"$|` +
-	`^[:&][a-zA-Z]+$|` +
-	`^\+$|^-$|^[a-zA-Z<>/*=?_!$%[\]^{}~][-a-zA-Z0-9+<>/*=?_!$%[\]^{}~]*$|` +
-	`^\|(?:\\\\|\\.|[^\\|])*\|$|` +
-	`^[.()]$|` +
-	"^;.*\n$|" +
-	`^#\|.*?\|#$|` +
-	"^#'$|^,@?$|^'$|^`$|^#[[:digit:]]*[aA]$|^#$" // TODO: hangs at #ab or #3
-var re = regexp.MustCompile(str)
-
-// ReadToken returns error or string as token
-func (r *Reader) Next() (string, error) {
-	for {
-		bytes, err := r.Peek(1)
-		if err != nil {
-			return "", io.EOF
-		}
-		ru := rune(bytes[0])
-		if ru == 0 {
-			return "", io.EOF
+	file := ""
+	if n, ok := r.(interface{ Name() string }); ok {
+		file = n.Name()
+	}
+	return &Reader{Raw: r, Reader: bufio.NewReader(r), pos: Position{file, 1, 1}, last: Position{file, 1, 1}}
+}
+
+// Position returns the position of the next rune to be read.
+func (r *Reader) Position() Position {
+	return r.pos
+}
+
+func (r *Reader) advance(ru rune) {
+	r.last = r.pos
+	if ru == '\n' {
+		r.pos.Line++
+		r.pos.Column = 1
+		return
+	}
+	r.pos.Column++
+}
+
+// ReadRune reads a rune and advances the position
+func (r *Reader) ReadRune() (rune, int, error) {
+	ru, size, err := r.Reader.ReadRune()
+	if err == nil {
+		r.advance(ru)
+	}
+	return ru, size, err
+}
+
+// UnreadRune unreads the last rune and restores the position
+func (r *Reader) UnreadRune() error {
+	if err := r.Reader.UnreadRune(); err != nil {
+		return err
+	}
+	r.pos = r.last
+	return nil
+}
+
+// ReadLine reads a line and advances the position
+func (r *Reader) ReadLine() ([]byte, bool, error) {
+	line, isPrefix, err := r.Reader.ReadLine()
+	for _, ru := range string(line) {
+		r.advance(ru)
+	}
+	if err == nil && !isPrefix {
+		r.advance('\n')
+	}
+	return line, isPrefix, err
+}
+
+// Read reads bytes and advances the position
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	for b := p[:n]; len(b) > 0; {
+		ru, size := utf8.DecodeRune(b)
+		r.advance(ru)
+		b = b[size:]
+	}
+	return n, err
+}
+
+// Wait waits until the reader has input, or an error to return, without
+// blocking, or until done is closed. It reports whether there is input. If
+// done is closed first, the read goes on in the background and the next
+// call of Wait waits for it, so the reader must not be read from before
+// Wait has reported input again.
+func (r *Reader) Wait(done <-chan struct{}) bool {
+	if r.filling == nil {
+		if done == nil || r.Buffered() > 0 {
+			return true
 		}
-		if !strings.ContainsRune(" \t\n\r", ru) {
-			break
+		filling := make(chan struct{})
+		r.filling = filling
+		go func() {
+			r.Reader.Peek(1)
+			close(filling)
+		}()
+	}
+	select {
+	case <-r.filling:
+		r.filling = nil
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// PeekRune returns the next rune without advancing the reader.
+func (r *Reader) PeekRune() (rune, bool) {
+	ru, _, err := r.Reader.ReadRune()
+	if err != nil {
+		return 0, false
+	}
+	r.Reader.UnreadRune()
+	return ru, true
+}
+
+func isSpace(ru rune) bool {
+	return strings.ContainsRune(" \t\n\r\f", ru)
+}
+
+// isDelimiter reports whether ru terminates an atom.
+func isDelimiter(ru rune) bool {
+	return isSpace(ru) || strings.ContainsRune(`()";'`+"`,", ru)
+}
+
+func isDigit(ru rune) bool {
+	return '0' <= ru && ru <= '9'
+}
+
+// readAtom reads constituent runes until a delimiter or the end of input.
+func (r *Reader) readAtom(buf *strings.Builder) {
+	for {
+		ru, ok := r.PeekRune()
+		if !ok || ru == 0 || isDelimiter(ru) || (r.Terminator != nil && r.Terminator.IsTerminating(ru)) {
+			return
 		}
 		r.ReadRune()
+		buf.WriteRune(ru)
 	}
-	buf := ""
-	mat := false
-	num := false
-	shp := false
+}
+
+// readDelimited reads runes until an unescaped close, which is included.
+func (r *Reader) readDelimited(buf *strings.Builder, close rune) error {
 	for {
-		bytes, err := r.Peek(1)
+		ru, _, err := r.ReadRune()
 		if err != nil {
-			if mat {
-				return buf, nil
-			}
-			return "", io.EOF
+			return io.ErrUnexpectedEOF
 		}
-		ru := rune(bytes[0])
-		if ru == 0 {
-			if mat {
-				return buf, nil
+		buf.WriteRune(ru)
+		if ru == '\\' {
+			ru, _, err = r.ReadRune()
+			if err != nil {
+				return io.ErrUnexpectedEOF
 			}
-			return "", io.EOF
+			buf.WriteRune(ru)
+			continue
 		}
-		if buf == "" && strings.ContainsRune("1234567890", ru) {
-			num = true
+		if ru == close {
+			return nil
 		}
-		if buf == "" && ru == '#' {
-			shp = true
+	}
+}
+
+// readBlockComment reads a (possibly nested) comment after the opening #|.
+func (r *Reader) readBlockComment(buf *strings.Builder) error {
+	depth := 1
+	prev := rune(0)
+	for depth > 0 {
+		ru, _, err := r.ReadRune()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		buf.WriteRune(ru)
+		switch {
+		case prev == '|' && ru == '#':
+			depth--
+			ru = 0
+		case prev == '#' && ru == '|':
+			depth++
+			ru = 0
 		}
-		if mat && !re.MatchString(buf+string(ru)) {
-			if num && strings.ContainsRune(".Ee", ru) {
-				buf += string(ru)
-				r.ReadRune()
-				continue
+		prev = ru
+	}
+	return nil
+}
+
+// readSharp reads a token which starts with #.
+func (r *Reader) readSharp(buf *strings.Builder) error {
+	ru, ok := r.PeekRune()
+	if !ok {
+		return nil
+	}
+	switch {
+	case ru == '|':
+		r.ReadRune()
+		buf.WriteRune(ru)
+		return r.readBlockComment(buf)
+	case ru == '\'':
+		r.ReadRune()
+		buf.WriteRune(ru)
+		return nil
+	case ru == '(':
+		return nil
+	case ru == '\\':
+		r.ReadRune()
+		buf.WriteRune(ru)
+		ru, _, err := r.ReadRune()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		buf.WriteRune(ru)
+		r.readAtom(buf)
+		return nil
+	case isDigit(ru):
+		for ok && isDigit(ru) {
+			r.ReadRune()
+			buf.WriteRune(ru)
+			ru, ok = r.PeekRune()
+		}
+		if ok && (ru == 'a' || ru == 'A') {
+			r.ReadRune()
+			buf.WriteRune(ru)
+		}
+		r.readAtom(buf)
+		return nil
+	case ru == 'a' || ru == 'A':
+		r.ReadRune()
+		buf.WriteRune(ru)
+		r.readAtom(buf)
+		return nil
+	}
+	r.readAtom(buf)
+	return nil
+}
+
+// SkipSpace skips whitespace runes. It returns false if no rune is left.
+func (r *Reader) SkipSpace() bool {
+	for {
+		ru, ok := r.PeekRune()
+		if !ok || ru == 0 {
+			return false
+		}
+		if !isSpace(ru) {
+			return true
+		}
+		r.ReadRune()
+	}
+}
+
+// Next returns the next token. It returns io.EOF if no token is left
+// and io.ErrUnexpectedEOF if the input ends in the middle of a token.
+func (r *Reader) Next() (*Token, error) {
+	if !r.SkipSpace() {
+		return nil, io.EOF
+	}
+	start := r.pos
+	ru, _, _ := r.ReadRune()
+	return r.NextFrom(start, string(ru))
+}
+
+// NextFrom returns the token beginning with prefix, which has already
+// been read from start. The parser uses it when a rune read for a
+// dispatching macro character turns out to start an ordinary token.
+func (r *Reader) NextFrom(start Position, prefix string) (*Token, error) {
+	buf := new(strings.Builder)
+	buf.WriteString(prefix)
+	ru, _ := utf8.DecodeRuneInString(prefix)
+	var err error
+	switch ru {
+	case '(', ')', '\'', '`':
+	case ',':
+		if next, ok := r.PeekRune(); ok && next == '@' {
+			r.ReadRune()
+			buf.WriteRune(next)
+		}
+	case ';':
+		for {
+			ru, _, e := r.ReadRune()
+			if e != nil {
+				break
 			}
-			if shp {
-				if len(buf) == 1 && ru == '\\' {
-					buf += string(ru)
-					r.ReadRune()
-					continue
-				}
-				if len(buf) == 1 && (ru == 'b' || ru == 'B' || ru == 'o' || ru == 'O' || ru == 'x' || ru == 'X') {
-					buf += string(ru)
-					r.ReadRune()
-					continue
-				}
-				if matched, _ := regexp.MatchString(`^#[0123456789]*$`, buf+string(ru)); matched {
-					buf += string(ru)
-					r.ReadRune()
-					continue
-				}
+			buf.WriteRune(ru)
+			if ru == '\n' {
+				break
 			}
-			break
 		}
-		buf += string(ru)
-		if re.MatchString(buf) {
-			mat = true
+	case '"':
+		err = r.readDelimited(buf, '"')
+	case '|':
+		err = r.readDelimited(buf, '|')
+	case '#':
+		if len(prefix) > 1 {
+			r.readAtom(buf)
+		} else {
+			err = r.readSharp(buf)
 		}
-		r.ReadRune()
+	default:
+		r.readAtom(buf)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return buf, nil
+	return &Token{buf.String(), Span{start, r.pos}}, nil
 }
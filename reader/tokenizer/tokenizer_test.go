@@ -5,6 +5,7 @@
 package tokenizer
 
 import (
+	"io"
 	"strings"
 	"testing"
 
@@ -51,10 +52,110 @@ func TestTokenizer_Next(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, _ := tokenizer.Next()
-			if got != tt.want {
+			got, err := tokenizer.Next()
+			if err != nil || got.Str != tt.want {
 				t.Errorf("Tokenizer.Next() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestTokenizer_Sharp(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "unknown dispatch",
+			src:  "#ab",
+			want: []string{"#ab"},
+		},
+		{
+			name: "digits without a",
+			src:  "#3 x",
+			want: []string{"#3", "x"},
+		},
+		{
+			name: "array",
+			src:  "#2a((1) (2))",
+			want: []string{"#2a", "(", "(", "1", ")", "(", "2", ")", ")"},
+		},
+		{
+			name: "vector",
+			src:  "#(a)",
+			want: []string{"#", "(", "a", ")"},
+		},
+		{
+			name: "character",
+			src:  `#\( #\space #\)`,
+			want: []string{`#\(`, `#\space`, `#\)`},
+		},
+		{
+			name: "nested comment",
+			src:  "#| a #| b |# c |# d",
+			want: []string{"#| a #| b |# c |#", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.src))
+			for _, want := range tt.want {
+				got, err := r.Next()
+				if err != nil || got.Str != want {
+					t.Errorf("Tokenizer.Next() got = %v, want %v", got, want)
+					return
+				}
+			}
+			if got, err := r.Next(); err != io.EOF {
+				t.Errorf("Tokenizer.Next() got = %v, want EOF", got)
+			}
+		})
+	}
+}
+
+func TestTokenizer_Span(t *testing.T) {
+	r := NewReader(strings.NewReader("(foo\n  \"bar\")"))
+	tests := []struct {
+		want  string
+		start Position
+		end   Position
+	}{
+		{"(", Position{"", 1, 1}, Position{"", 1, 2}},
+		{"foo", Position{"", 1, 2}, Position{"", 1, 5}},
+		{`"bar"`, Position{"", 2, 3}, Position{"", 2, 8}},
+		{")", Position{"", 2, 8}, Position{"", 2, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := r.Next()
+			if err != nil || got.Str != tt.want || got.Start != tt.start || got.End != tt.end {
+				t.Errorf("Tokenizer.Next() got = %v %v-%v, want %v %v-%v", got, got.Start, got.End, tt.want, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestTokenizer_Unterminated(t *testing.T) {
+	r := NewReader(strings.NewReader(`"foo`))
+	if _, err := r.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Tokenizer.Next() err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestReader_Wait(t *testing.T) {
+	pr, pw := io.Pipe()
+	r := NewReader(pr)
+	done := make(chan struct{})
+	close(done)
+	if r.Wait(done) {
+		t.Fatal("Wait() = true with no input, want false")
+	}
+	go pw.Write([]byte("a"))
+	if !r.Wait(nil) {
+		t.Fatal("Wait(nil) = false, want true")
+	}
+	if ru, _, err := r.ReadRune(); ru != 'a' || err != nil {
+		t.Errorf("ReadRune() = %q, %v, want 'a'", ru, err)
+	}
+}
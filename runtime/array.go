@@ -52,14 +52,30 @@ func CreateArray(e env.Environment, dimensions ilos.Instance, initialElement ...
 	if err != nil {
 		return nil, err
 	}
-	for i := 0; i < int(length.(instance.Integer)); i++ {
+	// The arrays of each dimension are made for every element of the ones
+	// before, so size counts the elements of all of them. It is checked
+	// against the limit before it can overflow.
+	limit := maxLength
+	if b := e.Budget; b != nil && b.ElementLimit > 0 && b.ElementLimit < maxLength {
+		limit = b.ElementLimit
+	}
+	size, product := 0, 1
+	for i := 0; i < fixnum(length); i++ {
 		elt, err := Elt(e, dimensions, instance.NewInteger(i))
 		if err != nil {
 			return nil, err
 		}
-		if err := ensure(e, class.Integer, elt); err != nil {
-			return nil, err
+		if !ilos.InstanceOf(class.Integer, elt) || fixnum(elt) < 0 {
+			return SignalCondition(e, instance.NewDomainError(e, elt, class.Integer), Nil)
+		}
+		if d := fixnum(elt); d > 0 && product > (limit-size)/d {
+			if limit != maxLength {
+				return nil, instance.Create(e, class.AllocationLimitExceeded)
+			}
+			return SignalCondition(e, instance.Create(e, class.StorageExhausted), Nil)
 		}
+		product *= fixnum(elt)
+		size += product
 	}
 	// set the initial element
 	elt := Nil
@@ -69,8 +85,11 @@ func CreateArray(e env.Environment, dimensions ilos.Instance, initialElement ...
 	if len(initialElement) == 1 {
 		elt = initialElement[0]
 	}
+	if err := allocate(e, class.GeneralVector, size); err != nil {
+		return nil, err
+	}
 	// general-vector
-	if int(length.(instance.Integer)) == 1 {
+	if fixnum(length) == 1 {
 		return createGeneralVector(e, dimensions, elt)
 	}
 	return createGeneralArrayStar(e, dimensions, elt)
@@ -82,8 +101,8 @@ func createGeneralVector(e env.Environment, dimensions ilos.Instance, initialEle
 	if err != nil {
 		return nil, err
 	}
-	array := make([]ilos.Instance, int(dimension.(instance.Integer)))
-	for i := 0; i < int(dimension.(instance.Integer)); i++ {
+	array := make([]ilos.Instance, fixnum(dimension))
+	for i := 0; i < fixnum(dimension); i++ {
 		array[i] = initialElement
 	}
 	return instance.NewGeneralVector(array), nil
@@ -95,7 +114,7 @@ func createGeneralArrayStar(e env.Environment, dimensions ilos.Instance, initial
 		return nil, err
 	}
 	// 0-dimension array
-	if int(length.(instance.Integer)) == 0 {
+	if fixnum(length) == 0 {
 		return instance.NewGeneralArrayStar(nil, initialElement), nil
 	}
 	// N-dimensions array
@@ -103,7 +122,7 @@ func createGeneralArrayStar(e env.Environment, dimensions ilos.Instance, initial
 	if err != nil {
 		return nil, err
 	}
-	array := make([]*instance.GeneralArrayStar, int(dimension.(instance.Integer)))
+	array := make([]*instance.GeneralArrayStar, fixnum(dimension))
 	for i := range array {
 		cdr, err := Cdr(e, dimensions)
 		if err != nil {
@@ -138,7 +157,7 @@ func Aref(e env.Environment, basicArray ilos.Instance, dimensions ...ilos.Instan
 		if len(dimensions) != 1 {
 			return SignalCondition(e, instance.NewArityError(e), Nil)
 		}
-		index := int(dimensions[0].(instance.Integer))
+		index := fixnum(dimensions[0])
 		if len(basicArray.(instance.String)) <= index {
 			return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 		}
@@ -147,7 +166,7 @@ func Aref(e env.Environment, basicArray ilos.Instance, dimensions ...ilos.Instan
 		if len(dimensions) != 1 {
 			return SignalCondition(e, instance.NewArityError(e), Nil)
 		}
-		index := int(dimensions[0].(instance.Integer))
+		index := fixnum(dimensions[0])
 		if len(basicArray.(instance.GeneralVector)) <= index {
 			return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 		}
@@ -174,7 +193,7 @@ func Garef(e env.Environment, generalArray ilos.Instance, dimensions ...ilos.Ins
 		return generalArray.(*instance.GeneralArrayStar).Scalar, nil
 	}
 	array := generalArray.(*instance.GeneralArrayStar)
-	index := int(dimensions[0].(instance.Integer))
+	index := fixnum(dimensions[0])
 	if array.Vector == nil || len(array.Vector) <= index {
 		return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 	}
@@ -199,7 +218,7 @@ func SetAref(e env.Environment, obj, basicArray ilos.Instance, dimensions ...ilo
 		if len(dimensions) != 1 {
 			return SignalCondition(e, instance.NewArityError(e), Nil)
 		}
-		index := int(dimensions[0].(instance.Integer))
+		index := fixnum(dimensions[0])
 		if len(basicArray.(instance.String)) <= index {
 			return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 		}
@@ -209,7 +228,7 @@ func SetAref(e env.Environment, obj, basicArray ilos.Instance, dimensions ...ilo
 		if len(dimensions) != 1 {
 			return SignalCondition(e, instance.NewArityError(e), Nil)
 		}
-		index := int(dimensions[0].(instance.Integer))
+		index := fixnum(dimensions[0])
 		if len(basicArray.(instance.GeneralVector)) <= index {
 			return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 		}
@@ -238,7 +257,7 @@ func SetGaref(e env.Environment, obj, generalArray ilos.Instance, dimensions ...
 		return obj, nil
 	}
 	array := generalArray.(*instance.GeneralArrayStar)
-	index := int(dimensions[0].(instance.Integer))
+	index := fixnum(dimensions[0])
 	if array.Vector == nil || len(array.Vector) <= index {
 		return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 	}
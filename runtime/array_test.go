@@ -34,6 +34,16 @@ func TestCreateArray(t *testing.T) {
 			want:    `#(0.0 0.0)`,
 			wantErr: false,
 		},
+		{
+			exp:     `(create-array '(2 -1) 0)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(create-array '(4294967296 4294967296) 0)`,
+			want:    `nil`,
+			wantErr: true,
+		},
 	})
 }
 
@@ -30,6 +30,9 @@ func ReadByte(e env.Environment, args ...ilos.Instance) (ilos.Instance, ilos.Ins
 	if len(args) < 1 || len(args) > 3 {
 		return SignalCondition(e, instance.NewArityError(e), Nil)
 	}
+	if err := waitInput(e, str); err != nil {
+		return nil, err
+	}
 	buf := make([]byte, 1)
 	n, err := str.(instance.Stream).Reader.Read(buf)
 
@@ -0,0 +1,1251 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/islisp-dev/iris/reader/parser"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// opcode is an instruction of the virtual machine. Its operands follow it in
+// the code, one word each.
+type opcode int32
+
+const (
+	opConst         opcode = iota // constant: push the constant
+	opLocal                       // depth index: push a variable of a frame
+	opSetLocal                    // depth index: assign the top to a variable of a frame
+	opGlobal                      // symbol: push a variable of the environment
+	opSetGlobal                   // symbol: assign the top to a variable of the environment
+	opDynamic                     // symbol: push a dynamic variable
+	opFunction                    // symbol: push a function of the environment
+	opCallee                      // symbol fallback target: push the function to call, or the value of a macro form
+	opExpanded                    // macros fallback target: if a macro was defined since the form was expanded, push its value and jump
+	opClosure                     // proto: push a closure over the frame
+	opCall                        // count: call a function with the arguments over it
+	opTailCall                    // count: call a function in place of this one
+	opReturn                      // return the top
+	opPop                         // drop the top
+	opJump                        // target
+	opJumpIfNil                   // target: pop, and jump if it is nil
+	opJumpUnlessNil               // target: pop, and jump unless it is nil
+	opJumpUnlessT                 // target: pop, and jump unless it is t
+	opAnd                         // target: jump if the top is nil, or pop it
+	opOr                          // target: jump unless the top is nil, or pop it
+	opCase                        // table: pop a key and jump to the clause for it
+	opCaseUsing                   // table: pop a predicate and a key and jump to the clause for them
+	opEnter                       // size: make a frame over the frame
+	opLeave                       // go back to the frame under the frame
+	opBind                        // index: pop into a variable of the frame
+	opPushBlock                   // index target: establish a block
+	opPushCatch                   // target: pop a tag and establish a catch
+	opPushTagbody                 // index table: establish a tagbody
+	opPushUnwind                  // target: establish the cleanup of unwind-protect
+	opPushGuard                   // target: establish the guard of the cleanup forms
+	opPopHandler                  // disestablish the latest of the above
+	opEndUnwind                   // finish the cleanup forms
+	opLocalExit                   // handler target: pop, exit to a block of this function and push
+	opLocalGo                     // handler target: go to a tag of this function
+	opReturnFrom                  // depth index tag: pop, and exit to a block
+	opGo                          // depth index tag: go to a tag
+	opCheckTag                    // target: signal unless the top may be a catch tag
+	opThrow                       // pop an object and a tag, and throw
+	opDynamicLet                  // list: pop the values and bind them dynamically
+	opWithHandler                 // pop and establish a handler
+	opFallback                    // fallback: push the value of a form run by Eval
+	opRaise                       // constant: signal the constant
+	opCheckConstant               // symbol target: signal if a constant is named by the symbol
+	opDefun                       // symbol: pop a global function
+	opDefglobal                   // symbol: pop a global variable
+	opDefdynamic                  // symbol: pop a dynamic variable
+	opDefconstant                 // symbol: pop a constant
+	opQuasiquote                  // template count: pop the unquoted values and fill in the template
+	opConvert                     // symbol: convert the top to the class
+	opClass                       // symbol: push the class
+)
+
+var operands = [...]int{
+	opConst: 1, opLocal: 2, opSetLocal: 2, opGlobal: 1, opSetGlobal: 1,
+	opDynamic: 1, opFunction: 1, opCallee: 3, opExpanded: 3, opClosure: 1, opCall: 1,
+	opTailCall: 1, opReturn: 0, opPop: 0, opJump: 1, opJumpIfNil: 1,
+	opJumpUnlessNil: 1, opJumpUnlessT: 1, opAnd: 1, opOr: 1, opCase: 1,
+	opCaseUsing: 1, opEnter: 1, opLeave: 0, opBind: 1, opPushBlock: 2,
+	opPushCatch: 1, opPushTagbody: 2, opPushUnwind: 1, opPushGuard: 1,
+	opPopHandler: 0, opEndUnwind: 0, opLocalExit: 2, opLocalGo: 2,
+	opReturnFrom: 3, opGo: 3, opCheckTag: 1, opThrow: 0, opDynamicLet: 1,
+	opWithHandler: 0, opFallback: 1, opRaise: 1, opCheckConstant: 2,
+	opDefun: 1, opDefglobal: 1, opDefdynamic: 1, opDefconstant: 1,
+	opQuasiquote: 2, opConvert: 1, opClass: 1,
+}
+
+// proto is a function compiled to bytecode.
+type proto struct {
+	name       ilos.Instance
+	parameters int  // the number of required parameters
+	variadic   bool // the rest parameter follows them in the frame
+	size       int  // the size of the frame
+	code       []int32
+	forms      []ilos.Instance // the form each word was compiled from
+	constants  []ilos.Instance
+	protos     []*proto
+	tables     []map[ilos.Instance]int
+	cases      [][]caseClause
+	lists      [][]ilos.Instance
+	fallbacks  []*fallback
+}
+
+// caseClause is a clause of case or case-using.
+type caseClause struct {
+	keys     []ilos.Instance
+	fallback bool
+	target   int
+}
+
+// address is where a name is bound in the frames.
+type address struct {
+	name         ilos.Instance
+	depth, index int
+}
+
+// fallback is a form which the machine leaves to Eval. It is analysed when it
+// is first run. The bindings in the frames are copied into the environment it
+// runs in, and variables assigned by it are copied back.
+type fallback struct {
+	form      ilos.Instance
+	code      code
+	variables []address
+	functions []address
+	blocks    []address
+	tags      []address
+}
+
+// vmScope is a frame of the code being compiled.
+type vmScope struct {
+	variables []ilos.Instance // nil for the slots of blocks and tagbodies
+	functions map[ilos.Instance]int
+	up        *vmScope
+}
+
+func (s *vmScope) slot(name ilos.Instance) int {
+	s.variables = append(s.variables, name)
+	return len(s.variables) - 1
+}
+
+// vmBlock is a block or the tag of a tagbody the code is compiled in.
+type vmBlock struct {
+	name     ilos.Instance
+	scope    *vmScope
+	index    int // the slot of the uid of the block or tagbody
+	compiler *vmCompiler
+	handler  int
+	target   int   // the pc of a tag
+	patches  []int // the operands to patch with the target
+	up       *vmBlock
+}
+
+// vmCompiler compiles the code of one function.
+type vmCompiler struct {
+	e         env.Environment
+	proto     *proto
+	scope     *vmScope
+	blocks    *vmBlock
+	tags      *vmBlock
+	handlers  int   // how many handlers are established at this point
+	protected []int // which of them run cleanup forms
+	form      ilos.Instance
+}
+
+// unwinding is put on the stack of the machine while the cleanup forms of
+// unwind-protect run, with the condition which is on its way out if any.
+type unwinding struct {
+	err ilos.Instance
+}
+
+func (*unwinding) Class() ilos.Class {
+	return class.Object
+}
+
+func (u *unwinding) String() string {
+	return fmt.Sprint(u.err)
+}
+
+var normalExit = &unwinding{}
+
+func (c *vmCompiler) emit(op opcode, arguments ...int) int {
+	c.proto.code = append(c.proto.code, int32(op))
+	c.proto.forms = append(c.proto.forms, c.form)
+	for _, a := range arguments {
+		c.proto.code = append(c.proto.code, int32(a))
+		c.proto.forms = append(c.proto.forms, c.form)
+	}
+	return len(c.proto.code) - 1
+}
+
+// pc returns where the next instruction goes.
+func (c *vmCompiler) pc() int {
+	return len(c.proto.code)
+}
+
+// patch sets the operand at to the next instruction.
+func (c *vmCompiler) patch(at int) {
+	c.proto.code[at] = int32(c.pc())
+}
+
+func (c *vmCompiler) constant(obj ilos.Instance) int {
+	if _, ok := obj.(instance.Symbol); ok {
+		for i, d := range c.proto.constants {
+			if d == obj {
+				return i
+			}
+		}
+	}
+	c.proto.constants = append(c.proto.constants, obj)
+	return len(c.proto.constants) - 1
+}
+
+func (c *vmCompiler) lookup(name ilos.Instance, function bool) (int, int, bool) {
+	for depth, s := 0, c.scope; s != nil; depth, s = depth+1, s.up {
+		if function {
+			if i, ok := s.functions[name]; ok {
+				return depth, i, true
+			}
+			continue
+		}
+		for i := len(s.variables) - 1; i >= 0; i-- {
+			if s.variables[i] == name {
+				return depth, i, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// depth returns how many frames out s is.
+func (c *vmCompiler) depth(s *vmScope) int {
+	depth := 0
+	for t := c.scope; t != s; t = t.up {
+		depth++
+	}
+	return depth
+}
+
+// enter compiles the code of f in a frame over the frame, returning the
+// new scope.
+func (c *vmCompiler) enter() (*vmScope, int) {
+	c.scope = &vmScope{functions: map[ilos.Instance]int{}, up: c.scope}
+	return c.scope, c.emit(opEnter, 0)
+}
+
+func (c *vmCompiler) leave(s *vmScope, at int) {
+	c.proto.code[at] = int32(len(s.variables))
+	c.scope = s.up
+	c.emit(opLeave)
+}
+
+// newProto compiles a lambda expression in the frames of c.
+func (c *vmCompiler) newProto(name, lambdaList ilos.Instance, body []ilos.Instance) (*proto, bool) {
+	parameters, variables, variadic, ok := lambdaParameters(lambdaList)
+	if !ok {
+		return nil, false
+	}
+	seen := map[ilos.Instance]bool{}
+	for _, v := range variables {
+		if seen[v] {
+			return nil, false
+		}
+		seen[v] = true
+	}
+	p := &proto{name: name, variadic: variadic, parameters: len(parameters)}
+	if variadic {
+		p.parameters -= 2
+	}
+	d := &vmCompiler{
+		e:      c.e,
+		proto:  p,
+		scope:  &vmScope{variables: variables, functions: map[ilos.Instance]int{}, up: c.scope},
+		blocks: c.blocks,
+		tags:   c.tags,
+		form:   c.form,
+	}
+	d.body(body, true)
+	d.emit(opReturn)
+	p.size = len(d.scope.variables)
+	return p, true
+}
+
+// compileProto compiles obj into a function of no arguments.
+func compileProto(e env.Environment, obj ilos.Instance) *proto {
+	p := &proto{name: instance.NewSymbol("TOP-LEVEL")}
+	c := &vmCompiler{e: e, proto: p, scope: &vmScope{functions: map[ilos.Instance]int{}}}
+	c.compile(obj, true)
+	c.emit(opReturn)
+	p.size = len(c.scope.variables)
+	return p
+}
+
+func (c *vmCompiler) compile(obj ilos.Instance, tail bool) {
+	if obj == Nil {
+		c.emit(opConst, c.constant(Nil))
+		return
+	}
+	switch obj := obj.(type) {
+	case instance.Symbol:
+		if depth, index, ok := c.lookup(obj, false); ok {
+			c.emit(opLocal, depth, index)
+			return
+		}
+		c.emit(opGlobal, c.constant(obj))
+	case *instance.Cons:
+		form := c.form
+		if _, ok := parser.Location(c.e, obj); ok {
+			c.form = obj
+		}
+		c.compileCons(obj, tail)
+		c.form = form
+	default:
+		c.emit(opConst, c.constant(obj))
+	}
+}
+
+// body compiles forms evaluated in sequence.
+func (c *vmCompiler) body(forms []ilos.Instance, tail bool) {
+	if len(forms) == 0 {
+		c.emit(opConst, c.constant(Nil))
+		return
+	}
+	for i, form := range forms {
+		if i > 0 {
+			c.emit(opPop)
+		}
+		c.compile(form, tail && i == len(forms)-1)
+	}
+}
+
+// fallback compiles form to be run by Eval in the environment with the
+// bindings of the frames.
+func (c *vmCompiler) fallback(form ilos.Instance) {
+	c.emit(opFallback, c.newFallback(form))
+}
+
+func (c *vmCompiler) newFallback(form ilos.Instance) int {
+	f := &fallback{form: form}
+	seen := map[ilos.Instance]bool{}
+	seenFunctions := map[ilos.Instance]bool{}
+	for depth, s := 0, c.scope; s != nil; depth, s = depth+1, s.up {
+		for i := len(s.variables) - 1; i >= 0; i-- {
+			if name := s.variables[i]; name != nil && !seen[name] {
+				seen[name] = true
+				f.variables = append(f.variables, address{name, depth, i})
+			}
+		}
+		for name, i := range s.functions {
+			if !seenFunctions[name] {
+				seenFunctions[name] = true
+				f.functions = append(f.functions, address{name, depth, i})
+			}
+		}
+	}
+	f.blocks = c.addresses(c.blocks)
+	f.tags = c.addresses(c.tags)
+	c.proto.fallbacks = append(c.proto.fallbacks, f)
+	return len(c.proto.fallbacks) - 1
+}
+
+func (c *vmCompiler) addresses(b *vmBlock) []address {
+	addresses := []address{}
+	seen := map[ilos.Instance]bool{}
+	for ; b != nil; b = b.up {
+		if !seen[b.name] {
+			seen[b.name] = true
+			addresses = append(addresses, address{b.name, c.depth(b.scope), b.index})
+		}
+	}
+	return addresses
+}
+
+// vmCompilers are the special forms which the machine runs itself. The
+// others are left to Eval, as are malformed forms, so that they signal the
+// same conditions.
+var vmCompilers map[ilos.Instance]func(*vmCompiler, []ilos.Instance, bool) bool
+
+func init() {
+	vmCompilers = map[ilos.Instance]func(*vmCompiler, []ilos.Instance, bool) bool{
+		instance.NewSymbol("AND"):            (*vmCompiler).and,
+		instance.NewSymbol("BLOCK"):          (*vmCompiler).block,
+		instance.NewSymbol("CASE"):           (*vmCompiler).caseForm,
+		instance.NewSymbol("CASE-USING"):     (*vmCompiler).caseUsing,
+		instance.NewSymbol("CATCH"):          (*vmCompiler).catch,
+		instance.NewSymbol("CLASS"):          (*vmCompiler).class,
+		instance.NewSymbol("COND"):           (*vmCompiler).cond,
+		instance.NewSymbol("CONVERT"):        (*vmCompiler).convert,
+		instance.NewSymbol("DEFCONSTANT"):    (*vmCompiler).defconstant,
+		instance.NewSymbol("DEFDYNAMIC"):     (*vmCompiler).defdynamic,
+		instance.NewSymbol("DEFGLOBAL"):      (*vmCompiler).defglobal,
+		instance.NewSymbol("DEFUN"):          (*vmCompiler).defun,
+		instance.NewSymbol("DYNAMIC"):        (*vmCompiler).dynamic,
+		instance.NewSymbol("DYNAMIC-LET"):    (*vmCompiler).dynamicLet,
+		instance.NewSymbol("FLET"):           (*vmCompiler).flet,
+		instance.NewSymbol("FOR"):            (*vmCompiler).forForm,
+		instance.NewSymbol("FUNCTION"):       (*vmCompiler).function,
+		instance.NewSymbol("GO"):             (*vmCompiler).goForm,
+		instance.NewSymbol("IF"):             (*vmCompiler).ifForm,
+		instance.NewSymbol("LABELS"):         (*vmCompiler).labels,
+		instance.NewSymbol("LAMBDA"):         (*vmCompiler).lambda,
+		instance.NewSymbol("LET"):            (*vmCompiler).let,
+		instance.NewSymbol("LET*"):           (*vmCompiler).letStar,
+		instance.NewSymbol("OR"):             (*vmCompiler).or,
+		instance.NewSymbol("PROGN"):          (*vmCompiler).progn,
+		instance.NewSymbol("QUASIQUOTE"):     (*vmCompiler).quasiquote,
+		instance.NewSymbol("QUOTE"):          (*vmCompiler).quote,
+		instance.NewSymbol("RETURN-FROM"):    (*vmCompiler).returnFrom,
+		instance.NewSymbol("SETF"):           (*vmCompiler).setf,
+		instance.NewSymbol("SETQ"):           (*vmCompiler).setq,
+		instance.NewSymbol("TAGBODY"):        (*vmCompiler).tagbody,
+		instance.NewSymbol("THROW"):          (*vmCompiler).throw,
+		instance.NewSymbol("UNWIND-PROTECT"): (*vmCompiler).unwindProtect,
+		instance.NewSymbol("WHILE"):          (*vmCompiler).while,
+		instance.NewSymbol("WITH-HANDLER"):   (*vmCompiler).withHandler,
+	}
+}
+
+func (c *vmCompiler) compileCons(form *instance.Cons, tail bool) {
+	car := form.Car
+	arguments, ok := properList(form.Cdr)
+	if !ok {
+		c.fallback(form)
+		return
+	}
+	// lambda form
+	if lambda, ok := car.(*instance.Cons); ok && lambda.Car == instance.NewSymbol("LAMBDA") {
+		c.compile(car, false)
+		c.call(arguments, tail)
+		return
+	}
+	// special form
+	if _, ok := c.e.Special.Get(car); ok {
+		if compiler, ok := vmCompilers[car]; ok {
+			saved := *c
+			at := c.pc()
+			if compiler(c, arguments, tail) {
+				return
+			}
+			// The form is malformed, so leave it to signal as Eval does.
+			*c = saved
+			c.proto.code = c.proto.code[:at]
+			c.proto.forms = c.proto.forms[:at]
+		}
+		c.fallback(form)
+		return
+	}
+	// local function call
+	if depth, index, ok := c.lookup(car, true); ok {
+		c.emit(opLocal, depth, index)
+		c.call(arguments, tail)
+		return
+	}
+	// macro form
+	if macro, ok := c.e.Macro.Get(car); ok {
+		depth := c.e.Depth
+		if depth.Limit > 0 && depth.Current >= depth.Limit {
+			// A macro which expands into itself without end; Eval signals
+			// <storage-exhausted> for it.
+			c.fallback(form)
+			return
+		}
+		depth.Current++
+		defer func() { depth.Current-- }()
+		expansion, err := macro.(instance.Applicable).Apply(c.e.NewDynamic(), arguments...)
+		if err != nil {
+			c.emit(opRaise, c.constant(err))
+			return
+		}
+		// Once a macro is defined again, the form is expanded by Eval.
+		at := c.emit(opExpanded, *c.e.Macros, c.newFallback(form), 0)
+		c.compile(expansion, tail)
+		c.patch(at)
+		return
+	}
+	// function call
+	// A macro defined after the form was compiled is expanded by Eval.
+	at := c.emit(opCallee, c.constant(car), c.newFallback(form), 0)
+	c.call(arguments, tail)
+	c.patch(at)
+}
+
+func (c *vmCompiler) call(arguments []ilos.Instance, tail bool) {
+	for _, argument := range arguments {
+		c.compile(argument, false)
+	}
+	if tail && c.handlers == 0 {
+		c.emit(opTailCall, len(arguments))
+		return
+	}
+	c.emit(opCall, len(arguments))
+}
+
+func (c *vmCompiler) quote(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 1 {
+		return false
+	}
+	c.emit(opConst, c.constant(arguments[0]))
+	return true
+}
+
+func (c *vmCompiler) function(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 1 {
+		return false
+	}
+	name, ok := arguments[0].(instance.Symbol)
+	if !ok {
+		return false
+	}
+	if depth, index, ok := c.lookup(name, true); ok {
+		c.emit(opLocal, depth, index)
+		return true
+	}
+	c.emit(opFunction, c.constant(name))
+	return true
+}
+
+func (c *vmCompiler) lambda(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	p, ok := c.newProto(instance.NewSymbol("ANONYMOUS-FUNCTION"), arguments[0], arguments[1:])
+	if !ok {
+		return false
+	}
+	c.proto.protos = append(c.proto.protos, p)
+	c.emit(opClosure, len(c.proto.protos)-1)
+	return true
+}
+
+func (c *vmCompiler) defun(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 2 {
+		return false
+	}
+	name, ok := arguments[0].(instance.Symbol)
+	if !ok {
+		return false
+	}
+	p, ok := c.newProto(name, arguments[1], arguments[2:])
+	if !ok {
+		return false
+	}
+	c.proto.protos = append(c.proto.protos, p)
+	c.emit(opClosure, len(c.proto.protos)-1)
+	c.emit(opDefun, c.constant(name))
+	return true
+}
+
+func (c *vmCompiler) define(op opcode, arguments []ilos.Instance) bool {
+	if len(arguments) != 2 {
+		return false
+	}
+	name, ok := arguments[0].(instance.Symbol)
+	if !ok {
+		return false
+	}
+	at := c.emit(opCheckConstant, c.constant(name), 0)
+	c.compile(arguments[1], false)
+	c.emit(op, c.constant(name))
+	c.patch(at)
+	return true
+}
+
+func (c *vmCompiler) defglobal(arguments []ilos.Instance, tail bool) bool {
+	return c.define(opDefglobal, arguments)
+}
+
+func (c *vmCompiler) defdynamic(arguments []ilos.Instance, tail bool) bool {
+	return c.define(opDefdynamic, arguments)
+}
+
+func (c *vmCompiler) defconstant(arguments []ilos.Instance, tail bool) bool {
+	return c.define(opDefconstant, arguments)
+}
+
+// definitions compiles the local functions of flet or labels, returning
+// their names.
+func (c *vmCompiler) definitions(functions ilos.Instance) ([]ilos.Instance, []*proto, bool) {
+	list, ok := properList(functions)
+	if !ok {
+		return nil, nil, false
+	}
+	names := []ilos.Instance{}
+	protos := []*proto{}
+	seen := map[ilos.Instance]bool{}
+	for _, function := range list {
+		d, ok := properList(function)
+		if !ok || len(d) < 2 {
+			return nil, nil, false
+		}
+		if _, ok := d[0].(instance.Symbol); !ok || seen[d[0]] {
+			return nil, nil, false
+		}
+		seen[d[0]] = true
+		p, ok := c.newProto(d[0], d[1], d[2:])
+		if !ok {
+			return nil, nil, false
+		}
+		names = append(names, d[0])
+		protos = append(protos, p)
+	}
+	return names, protos, true
+}
+
+func (c *vmCompiler) flet(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	names, protos, ok := c.definitions(arguments[0])
+	if !ok {
+		return false
+	}
+	for _, p := range protos {
+		c.proto.protos = append(c.proto.protos, p)
+		c.emit(opClosure, len(c.proto.protos)-1)
+	}
+	s, at := c.enter()
+	for i := len(names) - 1; i >= 0; i-- {
+		s.functions[names[i]] = i
+		c.emit(opBind, i)
+	}
+	for range names {
+		s.slot(nil)
+	}
+	c.body(arguments[1:], tail)
+	c.leave(s, at)
+	return true
+}
+
+func (c *vmCompiler) labels(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	list, ok := properList(arguments[0])
+	if !ok {
+		return false
+	}
+	s, at := c.enter()
+	for _, function := range list {
+		if d, ok := properList(function); ok && len(d) > 0 {
+			s.functions[d[0]] = s.slot(nil)
+		}
+	}
+	names, protos, ok := c.definitions(arguments[0])
+	if !ok {
+		return false
+	}
+	for i, p := range protos {
+		c.proto.protos = append(c.proto.protos, p)
+		c.emit(opClosure, len(c.proto.protos)-1)
+		c.emit(opBind, s.functions[names[i]])
+	}
+	c.body(arguments[1:], tail)
+	c.leave(s, at)
+	return true
+}
+
+func (c *vmCompiler) progn(arguments []ilos.Instance, tail bool) bool {
+	c.body(arguments, tail)
+	return true
+}
+
+func (c *vmCompiler) ifForm(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 2 && len(arguments) != 3 {
+		return false
+	}
+	c.compile(arguments[0], false)
+	els := c.emit(opJumpIfNil, 0)
+	c.compile(arguments[1], tail)
+	end := c.emit(opJump, 0)
+	c.patch(els)
+	if len(arguments) == 3 {
+		c.compile(arguments[2], tail)
+	} else {
+		c.emit(opConst, c.constant(Nil))
+	}
+	c.patch(end)
+	return true
+}
+
+func (c *vmCompiler) cond(arguments []ilos.Instance, tail bool) bool {
+	clauses := [][]ilos.Instance{}
+	for _, argument := range arguments {
+		clause, ok := properList(argument)
+		if !ok || len(clause) == 0 {
+			return false
+		}
+		clauses = append(clauses, clause)
+	}
+	ends := []int{}
+	for _, clause := range clauses {
+		c.compile(clause[0], false)
+		next := c.emit(opJumpUnlessT, 0)
+		c.body(clause[1:], tail)
+		ends = append(ends, c.emit(opJump, 0))
+		c.patch(next)
+	}
+	c.emit(opConst, c.constant(Nil))
+	for _, end := range ends {
+		c.patch(end)
+	}
+	return true
+}
+
+// caseClauses parses the clauses of case or case-using.
+func caseClauses(arguments []ilos.Instance) ([][]ilos.Instance, []caseClause, bool) {
+	clauses := [][]ilos.Instance{}
+	table := []caseClause{}
+	for i, argument := range arguments {
+		clause, ok := properList(argument)
+		if !ok || len(clause) == 0 {
+			return nil, nil, false
+		}
+		if i == len(arguments)-1 && clause[0] == T {
+			table = append(table, caseClause{fallback: true})
+		} else {
+			keys, ok := properList(clause[0])
+			if !ok {
+				return nil, nil, false
+			}
+			table = append(table, caseClause{keys: keys})
+		}
+		clauses = append(clauses, clause)
+	}
+	return clauses, table, true
+}
+
+// clauses compiles the clauses of case or case-using after the instruction
+// which jumps to them.
+func (c *vmCompiler) clauses(op opcode, clauses [][]ilos.Instance, table []caseClause, tail bool) {
+	c.proto.cases = append(c.proto.cases, table)
+	c.emit(op, len(c.proto.cases)-1)
+	c.emit(opConst, c.constant(Nil))
+	ends := []int{c.emit(opJump, 0)}
+	for i, clause := range clauses {
+		table[i].target = c.pc()
+		c.body(clause[1:], tail)
+		ends = append(ends, c.emit(opJump, 0))
+	}
+	for _, end := range ends {
+		c.patch(end)
+	}
+}
+
+func (c *vmCompiler) caseForm(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	clauses, table, ok := caseClauses(arguments[1:])
+	if !ok {
+		return false
+	}
+	c.compile(arguments[0], false)
+	c.clauses(opCase, clauses, table, tail)
+	return true
+}
+
+func (c *vmCompiler) caseUsing(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 2 {
+		return false
+	}
+	clauses, table, ok := caseClauses(arguments[2:])
+	if !ok {
+		return false
+	}
+	c.compile(arguments[1], false)
+	c.compile(arguments[0], false)
+	c.clauses(opCaseUsing, clauses, table, tail)
+	return true
+}
+
+func (c *vmCompiler) connective(op opcode, empty ilos.Instance, arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) == 0 {
+		c.emit(opConst, c.constant(empty))
+		return true
+	}
+	ends := []int{}
+	for i, argument := range arguments {
+		c.compile(argument, tail && i == len(arguments)-1)
+		if i < len(arguments)-1 {
+			ends = append(ends, c.emit(op, 0))
+		}
+	}
+	for _, end := range ends {
+		c.patch(end)
+	}
+	return true
+}
+
+func (c *vmCompiler) and(arguments []ilos.Instance, tail bool) bool {
+	return c.connective(opAnd, T, arguments, tail)
+}
+
+func (c *vmCompiler) or(arguments []ilos.Instance, tail bool) bool {
+	return c.connective(opOr, Nil, arguments, tail)
+}
+
+// bindings returns the variables and forms of a list of (var form) pairs.
+func bindings(obj ilos.Instance) ([]ilos.Instance, []ilos.Instance, bool) {
+	list, ok := properList(obj)
+	if !ok {
+		return nil, nil, false
+	}
+	variables := []ilos.Instance{}
+	forms := []ilos.Instance{}
+	for _, binding := range list {
+		pair, ok := properList(binding)
+		if !ok || len(pair) != 2 {
+			return nil, nil, false
+		}
+		variables = append(variables, pair[0])
+		forms = append(forms, pair[1])
+	}
+	return variables, forms, true
+}
+
+func (c *vmCompiler) let(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	variables, forms, ok := bindings(arguments[0])
+	if !ok {
+		return false
+	}
+	for _, form := range forms {
+		c.compile(form, false)
+	}
+	s, at := c.enter()
+	slots := map[ilos.Instance]int{}
+	for _, v := range variables {
+		if _, ok := slots[v]; !ok {
+			slots[v] = s.slot(v)
+		}
+	}
+	// The last value of a variable named more than once is the one bound.
+	bound := map[ilos.Instance]bool{}
+	for i := len(variables) - 1; i >= 0; i-- {
+		if bound[variables[i]] {
+			c.emit(opPop)
+			continue
+		}
+		bound[variables[i]] = true
+		c.emit(opBind, slots[variables[i]])
+	}
+	c.body(arguments[1:], tail)
+	c.leave(s, at)
+	return true
+}
+
+func (c *vmCompiler) letStar(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	variables, forms, ok := bindings(arguments[0])
+	if !ok {
+		return false
+	}
+	seen := map[ilos.Instance]bool{}
+	for _, v := range variables {
+		if seen[v] {
+			return false
+		}
+		seen[v] = true
+	}
+	s, at := c.enter()
+	for i, form := range forms {
+		c.compile(form, false)
+		c.emit(opBind, s.slot(variables[i]))
+	}
+	c.body(arguments[1:], tail)
+	c.leave(s, at)
+	return true
+}
+
+func (c *vmCompiler) dynamicLet(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	variables, forms, ok := bindings(arguments[0])
+	if !ok {
+		return false
+	}
+	for _, form := range forms {
+		c.compile(form, false)
+	}
+	c.proto.lists = append(c.proto.lists, variables)
+	c.emit(opDynamicLet, len(c.proto.lists)-1)
+	c.handlers++
+	c.body(arguments[1:], false)
+	c.handlers--
+	c.emit(opPopHandler)
+	return true
+}
+
+func (c *vmCompiler) dynamic(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 1 {
+		return false
+	}
+	if _, ok := arguments[0].(instance.Symbol); !ok {
+		return false
+	}
+	c.emit(opDynamic, c.constant(arguments[0]))
+	return true
+}
+
+func (c *vmCompiler) setq(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 2 {
+		return false
+	}
+	c.compile(arguments[1], false)
+	if depth, index, ok := c.lookup(arguments[0], false); ok {
+		c.emit(opSetLocal, depth, index)
+		return true
+	}
+	c.emit(opSetGlobal, c.constant(arguments[0]))
+	return true
+}
+
+func (c *vmCompiler) setf(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 2 {
+		return false
+	}
+	if _, ok := arguments[0].(instance.Symbol); ok {
+		return c.setq(arguments, tail)
+	}
+	place, ok := properList(arguments[0])
+	if !ok || len(place) == 0 {
+		return false
+	}
+	c.emit(opFunction, c.constant(instance.NewSymbol(fmt.Sprintf("(SETF %v)", place[0]))))
+	c.call(append([]ilos.Instance{arguments[1]}, place[1:]...), false)
+	return true
+}
+
+func (c *vmCompiler) while(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	test := c.pc()
+	c.compile(arguments[0], false)
+	end := c.emit(opJumpUnlessT, 0)
+	c.body(arguments[1:], false)
+	c.emit(opPop)
+	c.emit(opJump, test)
+	c.patch(end)
+	c.emit(opConst, c.constant(Nil))
+	return true
+}
+
+// forForm binds all the variables of a for form in one frame, and the
+// stepped ones again in a frame over it which is made anew each iteration.
+func (c *vmCompiler) forForm(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 2 {
+		return false
+	}
+	specs, ok := properList(arguments[0])
+	if !ok {
+		return false
+	}
+	variables := []ilos.Instance{}
+	stepped := []ilos.Instance{}
+	inits := []ilos.Instance{}
+	steps := []ilos.Instance{}
+	seen := map[ilos.Instance]bool{}
+	for _, spec := range specs {
+		is, ok := properList(spec)
+		if !ok || (len(is) != 2 && len(is) != 3) || seen[is[0]] {
+			return false
+		}
+		seen[is[0]] = true
+		variables = append(variables, is[0])
+		inits = append(inits, is[1])
+		if len(is) == 3 {
+			stepped = append(stepped, is[0])
+			steps = append(steps, is[2])
+		}
+	}
+	ends, ok := properList(arguments[1])
+	if !ok || len(ends) == 0 {
+		return false
+	}
+	for _, init := range inits {
+		c.compile(init, false)
+	}
+	all, outer := c.enter()
+	for _, v := range variables {
+		all.slot(v)
+	}
+	for i := len(variables) - 1; i >= 0; i-- {
+		c.emit(opBind, i)
+	}
+	for _, v := range stepped {
+		_, index, _ := c.lookup(v, false)
+		c.emit(opLocal, 0, index)
+	}
+	current, inner := c.enter()
+	for _, v := range stepped {
+		current.slot(v)
+	}
+	for i := len(stepped) - 1; i >= 0; i-- {
+		c.emit(opBind, i)
+	}
+	test := c.pc()
+	c.compile(ends[0], false)
+	end := c.emit(opJumpUnlessNil, 0)
+	c.body(arguments[2:], false)
+	c.emit(opPop)
+	for _, step := range steps {
+		c.compile(step, false)
+	}
+	// A new frame for the stepped variables, which closures made in the
+	// last iteration keep.
+	c.emit(opLeave)
+	again := c.emit(opEnter, 0)
+	for i := len(stepped) - 1; i >= 0; i-- {
+		c.emit(opBind, i)
+	}
+	c.emit(opJump, test)
+	c.patch(end)
+	c.body(ends[1:], false)
+	c.proto.code[again] = int32(len(current.variables))
+	c.leave(current, inner)
+	c.leave(all, outer)
+	return true
+}
+
+// protect records that the handler being established runs cleanup forms,
+// so that exits through it are made by signalling.
+func (c *vmCompiler) protect() {
+	c.protected = append(c.protected, c.handlers)
+	c.handlers++
+}
+
+func (c *vmCompiler) unprotect() {
+	c.protected = c.protected[:len(c.protected)-1]
+	c.handlers--
+}
+
+// local reports whether an exit to the handler b of this function may jump.
+func (c *vmCompiler) local(b *vmBlock) bool {
+	if b.compiler != c {
+		return false
+	}
+	for _, h := range c.protected {
+		if h > b.handler {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *vmCompiler) block(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	tag := arguments[0]
+	if ilos.InstanceOf(class.Number, tag) || ilos.InstanceOf(class.Character, tag) {
+		return false
+	}
+	b := &vmBlock{name: tag, scope: c.scope, index: c.scope.slot(nil), compiler: c, handler: c.handlers, up: c.blocks}
+	at := c.emit(opPushBlock, b.index, 0)
+	c.blocks = b
+	c.handlers++
+	c.body(arguments[1:], false)
+	c.handlers--
+	c.blocks = b.up
+	c.emit(opPopHandler)
+	c.patch(at)
+	for _, p := range b.patches {
+		c.patch(p)
+	}
+	return true
+}
+
+func (c *vmCompiler) returnFrom(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 2 {
+		return false
+	}
+	var b *vmBlock
+	for b = c.blocks; b != nil && b.name != arguments[0]; b = b.up {
+	}
+	if b == nil {
+		return false
+	}
+	c.compile(arguments[1], false)
+	if c.local(b) {
+		b.patches = append(b.patches, c.emit(opLocalExit, b.handler, 0))
+		return true
+	}
+	c.emit(opReturnFrom, c.depth(b.scope), b.index, c.constant(b.name))
+	return true
+}
+
+func (c *vmCompiler) catch(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	c.compile(arguments[0], false)
+	check := c.emit(opCheckTag, 0)
+	at := c.emit(opPushCatch, 0)
+	c.handlers++
+	c.body(arguments[1:], false)
+	c.handlers--
+	c.emit(opPopHandler)
+	c.patch(check)
+	c.patch(at)
+	return true
+}
+
+func (c *vmCompiler) throw(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 2 {
+		return false
+	}
+	c.compile(arguments[0], false)
+	check := c.emit(opCheckTag, 0)
+	c.compile(arguments[1], false)
+	c.emit(opThrow)
+	c.patch(check)
+	return true
+}
+
+func (c *vmCompiler) tagbody(arguments []ilos.Instance, tail bool) bool {
+	index := c.scope.slot(nil)
+	tags := c.tags
+	seen := map[ilos.Instance]bool{}
+	for _, argument := range arguments {
+		if _, ok := argument.(*instance.Cons); ok {
+			continue
+		}
+		if seen[argument] {
+			return false
+		}
+		seen[argument] = true
+		tags = &vmBlock{name: argument, scope: c.scope, index: index, compiler: c, handler: c.handlers, target: -1, up: tags}
+	}
+	table := map[ilos.Instance]int{}
+	c.proto.tables = append(c.proto.tables, table)
+	c.emit(opPushTagbody, index, len(c.proto.tables)-1)
+	outer := c.tags
+	c.tags = tags
+	c.handlers++
+	for _, argument := range arguments {
+		if _, ok := argument.(*instance.Cons); ok {
+			c.compile(argument, false)
+			c.emit(opPop)
+			continue
+		}
+		table[argument] = c.pc()
+	}
+	c.handlers--
+	for t := tags; t != outer; t = t.up {
+		for _, p := range t.patches {
+			c.proto.code[p] = int32(table[t.name])
+		}
+	}
+	c.tags = outer
+	c.emit(opPopHandler)
+	c.emit(opConst, c.constant(Nil))
+	return true
+}
+
+func (c *vmCompiler) goForm(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 1 {
+		return false
+	}
+	var t *vmBlock
+	for t = c.tags; t != nil && t.name != arguments[0]; t = t.up {
+	}
+	if t == nil {
+		return false
+	}
+	if c.local(t) {
+		t.patches = append(t.patches, c.emit(opLocalGo, t.handler, 0))
+		return true
+	}
+	c.emit(opGo, c.depth(t.scope), t.index, c.constant(t.name))
+	return true
+}
+
+func (c *vmCompiler) unwindProtect(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	cleanup := c.emit(opPushUnwind, 0)
+	c.protect()
+	c.compile(arguments[0], false)
+	c.unprotect()
+	c.emit(opPopHandler)
+	c.emit(opConst, c.constant(normalExit))
+	c.patch(cleanup)
+	end := c.emit(opPushGuard, 0)
+	c.protect()
+	for _, form := range arguments[1:] {
+		c.compile(form, false)
+		c.emit(opPop)
+	}
+	c.unprotect()
+	c.emit(opPopHandler)
+	c.emit(opEndUnwind)
+	c.patch(end)
+	return true
+}
+
+func (c *vmCompiler) withHandler(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) < 1 {
+		return false
+	}
+	c.compile(arguments[0], false)
+	c.emit(opWithHandler)
+	c.handlers++
+	c.body(arguments[1:], false)
+	c.handlers--
+	c.emit(opPopHandler)
+	return true
+}
+
+func (c *vmCompiler) quasiquote(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 1 {
+		return false
+	}
+	// Expanding the template once finds the forms it unquotes, in the
+	// order they are evaluated.
+	unquoted := []ilos.Instance{}
+	if _, err := expand(c.e, arguments[0], 0, func(_ env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+		unquoted = append(unquoted, obj)
+		return Nil, nil
+	}); err != nil {
+		return false
+	}
+	for _, form := range unquoted {
+		c.compile(form, false)
+	}
+	c.emit(opQuasiquote, c.constant(arguments[0]), len(unquoted))
+	return true
+}
+
+func (c *vmCompiler) convert(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 2 {
+		return false
+	}
+	c.compile(arguments[0], false)
+	c.emit(opConvert, c.constant(arguments[1]))
+	return true
+}
+
+func (c *vmCompiler) class(arguments []ilos.Instance, tail bool) bool {
+	if len(arguments) != 1 {
+		return false
+	}
+	c.emit(opClass, c.constant(arguments[0]))
+	return true
+}
@@ -6,7 +6,6 @@ package runtime
 
 import (
 	"fmt"
-	"reflect"
 
 	"github.com/islisp-dev/iris/runtime/env"
 	"github.com/islisp-dev/iris/runtime/ilos"
@@ -18,22 +17,54 @@ func ClassOf(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance
 	return obj.Class(), nil
 }
 
-func Instancep(e env.Environment, obj ilos.Instance, class ilos.Class) (ilos.Instance, ilos.Instance) {
-	if ilos.InstanceOf(class, obj) {
+// ensureClasses returns objs as classes, or signals a domain-error for the
+// first of them which is not a class.
+func ensureClasses(e env.Environment, objs ...ilos.Instance) ([]ilos.Class, ilos.Instance) {
+	classes := make([]ilos.Class, len(objs))
+	for i, obj := range objs {
+		c, ok := obj.(ilos.Class)
+		if !ok {
+			_, err := SignalCondition(e, instance.NewDomainError(e, obj, class.StandardClass), Nil)
+			return nil, err
+		}
+		classes[i] = c
+	}
+	return classes, nil
+}
+
+func Instancep(e env.Environment, obj, class ilos.Instance) (ilos.Instance, ilos.Instance) {
+	classes, err := ensureClasses(e, class)
+	if err != nil {
+		return nil, err
+	}
+	if ilos.InstanceOf(classes[0], obj) {
 		return T, nil
 	}
 	return Nil, nil
 }
 
-func Subclassp(e env.Environment, class1, class2 ilos.Class) (ilos.Instance, ilos.Instance) {
-	if ilos.SubclassOf(class1, class2) {
+func Subclassp(e env.Environment, class1, class2 ilos.Instance) (ilos.Instance, ilos.Instance) {
+	classes, err := ensureClasses(e, class1, class2)
+	if err != nil {
+		return nil, err
+	}
+	if ilos.SubclassOf(classes[1], classes[0]) {
 		return T, nil
 	}
 	return Nil, nil
 }
 
-func Class(e env.Environment, className ilos.Instance) (ilos.Class, ilos.Instance) {
-	if v, ok := e.Class[:1].Get(className); ok {
+// Class returns the class named by className.
+func Class(e env.Environment, className ilos.Instance) (ilos.Instance, ilos.Instance) {
+	c, err := findClass(e, className)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func findClass(e env.Environment, className ilos.Instance) (ilos.Class, ilos.Instance) {
+	if v, ok := e.Class.Get(className); ok {
 		return v.(ilos.Class), nil
 	}
 	_, err := SignalCondition(e, instance.NewUndefinedClass(e, className), Nil)
@@ -41,7 +72,7 @@ func Class(e env.Environment, className ilos.Instance) (ilos.Class, ilos.Instanc
 }
 
 func checkSuperClass(a, b ilos.Class) bool {
-	if reflect.DeepEqual(a, class.StandardObject) || reflect.DeepEqual(b, class.StandardObject) {
+	if a == class.StandardObject || b == class.StandardObject {
 		return false
 	}
 	if ilos.SubclassOf(a, b) || ilos.SubclassOf(b, a) {
@@ -69,7 +100,7 @@ func Defclass(e env.Environment, className, scNames, slotSpecs ilos.Instance, cl
 	}
 	supers := []ilos.Class{class.StandardObject}
 	for _, scName := range scNames.(instance.List).Slice() {
-		super, err := Class(e, scName)
+		super, err := findClass(e, scName)
 		if err != nil {
 			return nil, err
 		}
@@ -111,7 +142,7 @@ func Defclass(e env.Environment, className, scNames, slotSpecs ilos.Instance, cl
 		var err ilos.Instance
 		switch classOpt.(*instance.Cons).Car {
 		case instance.NewSymbol(":METACLASS"):
-			if metaclass, err = Class(e, classOpt.(instance.List).Nth(1)); err != nil {
+			if metaclass, err = findClass(e, classOpt.(instance.List).Nth(1)); err != nil {
 				return nil, err
 			}
 		case instance.NewSymbol(":ABSTRACTP"):
@@ -121,7 +152,7 @@ func Defclass(e env.Environment, className, scNames, slotSpecs ilos.Instance, cl
 		}
 	}
 	classObject := instance.NewStandardClass(className, supers, slots, initforms, initargs, metaclass, abstractp)
-	e.Class[:1].Define(className, classObject)
+	e.Class.Define(className, classObject)
 	for _, slotSpec := range slotSpecs.(instance.List).Slice() {
 		if ilos.InstanceOf(class.Symbol, slotSpec) {
 			continue
@@ -151,7 +182,7 @@ func Defclass(e env.Environment, className, scNames, slotSpecs ilos.Instance, cl
 				Defgeneric(e, readerFunctionName, lambdaList)
 			}
 			fun, _ := e.Function.Get(readerFunctionName)
-			fun.(*instance.GenericFunction).AddMethod(nil, lambdaList, []ilos.Class{classObject}, instance.NewFunction(readerFunctionName, func(e env.Environment, object ilos.Instance) (ilos.Instance, ilos.Instance) {
+			untraced(fun).(*instance.GenericFunction).AddMethod(nil, lambdaList, []ilos.Class{classObject}, instance.NewFunction(readerFunctionName, func(e env.Environment, object ilos.Instance) (ilos.Instance, ilos.Instance) {
 				slot, ok := object.(instance.Instance).GetSlotValue(slotName, classObject)
 				if ok {
 					return slot, nil
@@ -168,7 +199,7 @@ func Defclass(e env.Environment, className, scNames, slotSpecs ilos.Instance, cl
 				Defgeneric(e, writerFunctionName, lambdaList)
 			}
 			fun, _ := e.Function.Get(writerFunctionName)
-			fun.(*instance.GenericFunction).AddMethod(nil, lambdaList, []ilos.Class{class.Object, classObject}, instance.NewFunction(writerFunctionName, func(e env.Environment, obj, object ilos.Instance) (ilos.Instance, ilos.Instance) {
+			untraced(fun).(*instance.GenericFunction).AddMethod(nil, lambdaList, []ilos.Class{class.Object, classObject}, instance.NewFunction(writerFunctionName, func(e env.Environment, obj, object ilos.Instance) (ilos.Instance, ilos.Instance) {
 				ok := object.(instance.Instance).SetSlotValue(obj, slotName, classObject)
 				if ok {
 					return obj, nil
@@ -185,7 +216,7 @@ func Defclass(e env.Environment, className, scNames, slotSpecs ilos.Instance, cl
 				Defgeneric(e, boundpFunctionName, lambdaList)
 			}
 			fun, _ := e.Function.Get(boundpFunctionName)
-			fun.(*instance.GenericFunction).AddMethod(nil, lambdaList, []ilos.Class{classObject}, instance.NewFunction(boundpFunctionName, func(e env.Environment, object ilos.Instance) (ilos.Instance, ilos.Instance) {
+			untraced(fun).(*instance.GenericFunction).AddMethod(nil, lambdaList, []ilos.Class{classObject}, instance.NewFunction(boundpFunctionName, func(e env.Environment, object ilos.Instance) (ilos.Instance, ilos.Instance) {
 				_, ok := object.(instance.Instance).GetSlotValue(slotName, classObject)
 				if ok {
 					return T, nil
@@ -242,7 +273,7 @@ func Defmethod(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, il
 		if ilos.InstanceOf(class.Symbol, pp) {
 			classList = append(classList, class.Object)
 		} else {
-			class, ok := e.Class[:1].Get(pp.(instance.List).Nth(1))
+			class, ok := e.Class.Get(pp.(instance.List).Nth(1))
 			if !ok {
 				return SignalCondition(e, instance.NewUndefinedClass(e, pp.(instance.List).Nth(1)), Nil)
 
@@ -250,15 +281,15 @@ func Defmethod(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, il
 			classList = append(classList, class.(ilos.Class))
 		}
 	}
-	fun, err := newNamedFunction(e, name, lambdaList, arguments[i+2:]...)
+	fun, err := newMethod(e, name, lambdaList, arguments[i+2:]...)
 	if err != nil {
 		return nil, err
 	}
-	gen, ok := e.Function[:1].Get(name)
+	gen, ok := e.Function.Global.Get(name)
 	if !ok {
 		return SignalCondition(e, instance.NewUndefinedFunction(e, name), Nil)
 	}
-	if !gen.(*instance.GenericFunction).AddMethod(qualifier, lambdaList, classList, fun) {
+	if !untraced(gen).(*instance.GenericFunction).AddMethod(qualifier, lambdaList, classList, fun) {
 		return SignalCondition(e, instance.NewUndefinedFunction(e, name), Nil)
 	}
 	return name, nil
@@ -273,7 +304,7 @@ func Defgeneric(e env.Environment, funcSpec, lambdaList ilos.Instance, optionsOr
 		case instance.NewSymbol(":METHOD-COMBINATION"):
 			methodCombination = optionOrMethodDesc.(instance.List).Nth(1)
 		case instance.NewSymbol(":GENERIC-FUNCTION-CLASS"):
-			class, ok := e.Class[:1].Get(optionOrMethodDesc.(instance.List).Nth(1))
+			class, ok := e.Class.Get(optionOrMethodDesc.(instance.List).Nth(1))
 			if !ok {
 				return SignalCondition(e, instance.NewUndefinedClass(e, optionOrMethodDesc.(instance.List).Nth(1)), Nil)
 			}
@@ -282,16 +313,18 @@ func Defgeneric(e env.Environment, funcSpec, lambdaList ilos.Instance, optionsOr
 			forms = append(forms, instance.NewCons(instance.NewSymbol("DEFMETHOD"), optionOrMethodDesc.(instance.List).NthCdr(1)))
 		}
 	}
-	e.Function[:1].Define(
-		instance.NewSymbol(
-			fmt.Sprint(funcSpec),
-		),
-		instance.NewGenericFunction(
+	name := funcSpec
+	if !ilos.InstanceOf(class.Symbol, funcSpec) {
+		name = instance.NewSymbol(fmt.Sprint(funcSpec))
+	}
+	e.Function.Define(
+		name,
+		retraced(e, name, instance.NewGenericFunction(
 			funcSpec,
 			lambdaList,
 			methodCombination,
 			genericFunctionClass,
-		),
+		)),
 	)
 	Progn(e, forms...)
 	return funcSpec, nil
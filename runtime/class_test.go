@@ -74,3 +74,90 @@ func TestDefclass(t *testing.T) {
 	}
 	execTests(t, Defclass, tests)
 }
+
+func TestDefmethod(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(defglobal method-log nil)`,
+			want:    `'method-log`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defgeneric method-g (x))`,
+			want:    `'method-g`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defmethod method-g :before ((x <integer>)) (setq method-log (cons 'before method-log)))`,
+			want:    `'method-g`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defmethod method-g ((x <integer>)) (setq method-log (cons 'primary method-log)) x)`,
+			want:    `'method-g`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (method-g 1) method-log)`,
+			want:    `'(1 (primary before))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(method-g "a")`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(defmethod method-g :after ((x <number>)) (setq method-log (cons 'after method-log)))`,
+			want:    `'method-g`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defmethod method-g ((x <number>)) (if (next-method-p) 'more 'number))`,
+			want:    `'method-g`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defmethod method-g ((x <integer>)) (list 'integer (call-next-method)))`,
+			want:    `'method-g`,
+			wantErr: false,
+		},
+		{
+			exp:     `(progn (setq method-log nil) (list (method-g 1) method-log))`,
+			want:    `'((integer number) (after before))`,
+			wantErr: false,
+		},
+	}
+	execTests(t, Defmethod, tests)
+}
+
+func TestSubclassp(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(defclass <shape> () ())`,
+			want:    `'<shape>`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defclass <named> () ())`,
+			want:    `'<named>`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defclass <circle> (<shape> <named>) ())`,
+			want:    `'<circle>`,
+			wantErr: false,
+		},
+		{
+			exp:     `(list (subclassp (class <circle>) (class <named>)) (subclassp (class <shape>) (class <circle>)) (subclassp (class <circle>) (class <circle>)))`,
+			want:    `'(t nil nil)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((old (create (class <shape>)))) (defclass <shape> () ()) (list (instancep old (class <shape>)) (subclassp (class <circle>) (class <shape>))))`,
+			want:    `'(nil nil)`,
+			wantErr: false,
+		},
+	}
+	execTests(t, Subclassp, tests)
+}
@@ -0,0 +1,389 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// code is a form analysed by compile. Running it evaluates the form in e.
+type code func(e env.Environment) (ilos.Instance, ilos.Instance)
+
+// scope is a frame of lexical variables or functions bound by analysed code.
+// When the code runs it is a frame of e.Variable or e.Function, so a binding
+// found n scopes of its kind out at index i is in the frame n frames out at
+// index i. The nil scope stands for the frames around the analysed form,
+// which are searched by name.
+type scope struct {
+	names    []ilos.Instance
+	function bool
+	outer    *scope
+}
+
+// with returns a scope of the variables over s.
+func (s *scope) with(variables ...ilos.Instance) *scope {
+	return &scope{variables, false, s}
+}
+
+// withFunctions returns a scope of the functions over s.
+func (s *scope) withFunctions(functions ...ilos.Instance) *scope {
+	return &scope{functions, true, s}
+}
+
+// address returns how many frames out name is bound and its index there. A
+// name bound twice in a frame is found at the later index.
+func (s *scope) address(name ilos.Instance, function bool) (int, int, bool) {
+	for n := 0; s != nil; s = s.outer {
+		if s.function != function {
+			continue
+		}
+		for i := len(s.names) - 1; i >= 0; i-- {
+			if s.names[i] == name {
+				return n, i, true
+			}
+		}
+		n++
+	}
+	return 0, 0, false
+}
+
+// duplicate reports whether any of names appears twice.
+func duplicate(names []ilos.Instance) bool {
+	for i := range names {
+		for j := 0; j < i; j++ {
+			if names[i] == names[j] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compiler analyses the arguments of a special form. It returns false if
+// they are malformed, and the special form itself reports that when it is
+// run.
+type compiler func(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool)
+
+var compilers map[ilos.Instance]compiler
+
+func init() {
+	compilers = map[ilos.Instance]compiler{
+		instance.NewSymbol("AND"):            compileAnd,
+		instance.NewSymbol("BLOCK"):          compileBlock,
+		instance.NewSymbol("CASE"):           compileCase,
+		instance.NewSymbol("CASE-USING"):     compileCaseUsing,
+		instance.NewSymbol("CATCH"):          compileCatch,
+		instance.NewSymbol("CLASS"):          compileClass,
+		instance.NewSymbol("COND"):           compileCond,
+		instance.NewSymbol("CONVERT"):        compileConvert,
+		instance.NewSymbol("DYNAMIC"):        compileDynamic,
+		instance.NewSymbol("DYNAMIC-LET"):    compileDynamicLet,
+		instance.NewSymbol("FLET"):           compileFlet,
+		instance.NewSymbol("FOR"):            compileFor,
+		instance.NewSymbol("FUNCTION"):       compileFunction,
+		instance.NewSymbol("GO"):             compileGo,
+		instance.NewSymbol("IF"):             compileIf,
+		instance.NewSymbol("LABELS"):         compileLabels,
+		instance.NewSymbol("LAMBDA"):         compileLambda,
+		instance.NewSymbol("LET"):            compileLet,
+		instance.NewSymbol("LET*"):           compileLetStar,
+		instance.NewSymbol("OR"):             compileOr,
+		instance.NewSymbol("PROGN"):          compileProgn,
+		instance.NewSymbol("QUASIQUOTE"):     compileQuasiquote,
+		instance.NewSymbol("QUOTE"):          compileQuote,
+		instance.NewSymbol("RETURN-FROM"):    compileReturnFrom,
+		instance.NewSymbol("SETF"):           compileSetf,
+		instance.NewSymbol("SETQ"):           compileSetq,
+		instance.NewSymbol("TAGBODY"):        compileTagbody,
+		instance.NewSymbol("THROW"):          compileThrow,
+		instance.NewSymbol("UNWIND-PROTECT"): compileUnwindProtect,
+		instance.NewSymbol("WHILE"):          compileWhile,
+		instance.NewSymbol("WITH-HANDLER"):   compileWithHandler,
+	}
+}
+
+// properList returns the elements of obj if it is a proper list.
+func properList(obj ilos.Instance) ([]ilos.Instance, bool) {
+	list := []ilos.Instance{}
+	for {
+		cons, ok := obj.(*instance.Cons)
+		if !ok {
+			return list, obj == Nil
+		}
+		list = append(list, cons.Car)
+		obj = cons.Cdr
+	}
+}
+
+func constant(obj ilos.Instance) code {
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		return obj, nil
+	}
+}
+
+// compile analyses obj in the scope s once into code. Macros are expanded
+// and special forms dispatched now, with the bindings of e; functions are
+// looked up when the code runs, so they may be defined later. If tail is
+// set, obj is in tail position and a call there may be returned as a
+// pending *tailCall when the environment asks for one.
+func compile(e env.Environment, s *scope, obj ilos.Instance, tail bool) code {
+	if obj == Nil {
+		return constant(Nil)
+	}
+	switch obj := obj.(type) {
+	case instance.Symbol:
+		return compileVariable(s, obj)
+	case *instance.Cons:
+		if cov := e.Coverage; cov != nil && cov.Counts != nil {
+			cover(cov, obj)
+		}
+		return located(obj, compileCons(e, s, obj, tail))
+	}
+	return constant(obj)
+}
+
+// located counts the code as one form being evaluated, and as a run of form
+// while coverage is on, and records the location of form in any condition
+// it signals. While stepping it pauses before the code, and stepping stops
+// when the outermost form is done.
+func located(form ilos.Instance, c code) code {
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		var ret, err ilos.Instance
+		depth := e.Depth
+		depth.Forms++
+		if cov := e.Coverage; cov != nil && cov.Counts != nil {
+			if n, ok := cov.Counts[form]; ok {
+				cov.Counts[form] = n + 1
+			}
+		}
+		if st := e.Stepper; st != nil && st.Stepping {
+			if err = stepTo(e, form); err == nil {
+				ret, err = c(e)
+			}
+		} else {
+			ret, err = c(e)
+		}
+		depth.Forms--
+		if depth.Forms == 0 && e.Stepper != nil {
+			// stepping started by a breakpoint ends with the evaluation
+			e.Stepper.Stepping = false
+		}
+		if err != nil {
+			attachLocation(e, err, form)
+			return nil, err
+		}
+		return ret, nil
+	}
+}
+
+func compileVariable(s *scope, variable ilos.Instance) code {
+	n, i, ok := s.address(variable, false)
+	if !ok {
+		return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			return evalVariable(e, variable)
+		}
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		if v := e.Variable.Frame.Out(n).Values[i]; v != nil {
+			return v, nil
+		}
+		// a variable of let* which is not bound yet
+		return evalVariable(e, variable)
+	}
+}
+
+func compileCons(e env.Environment, s *scope, form *instance.Cons, tail bool) code {
+	car, cdr := form.Car, form.Cdr
+	// lambda form
+	if lambda, ok := car.(*instance.Cons); ok && lambda.Car == instance.NewSymbol("LAMBDA") {
+		function := compile(e, s, car, false)
+		arguments := compileArguments(e, s, cdr)
+		return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			fun, err := function(e)
+			if err != nil {
+				return nil, err
+			}
+			return call(e, form, fun, arguments, tail)
+		}
+	}
+	// special form
+	if special, ok := e.Special.Get(car); ok {
+		if c, ok := compilers[car]; ok {
+			if arguments, ok := properList(cdr); ok {
+				if code, ok := c(e, s, arguments, tail); ok {
+					return code
+				}
+			}
+		}
+		arguments := cdr.(instance.List).Slice()
+		return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			return special.(instance.Applicable).Apply(e.NewLexical(), arguments...)
+		}
+	}
+	// local function call
+	if n, i, ok := s.address(car, true); ok {
+		arguments := compileArguments(e, s, cdr)
+		return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			return call(e, form, e.Function.Frame.Out(n).Values[i], arguments, tail)
+		}
+	}
+	// macro form
+	if macro, ok := e.Macro.Get(car); ok {
+		macros, c := *e.Macros, expandMacro(e, s, form, macro, tail)
+		return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			if *e.Macros != macros {
+				// A macro was defined again since the form was expanded,
+				// so it is expanded again as Eval would.
+				macro, _ := e.Macro.Get(car)
+				macros, c = *e.Macros, expandMacro(e, s, form, macro, tail)
+			}
+			return c(e)
+		}
+	}
+	// function call
+	arguments := compileArguments(e, s, cdr)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		fun, ok := e.Function.Get(car)
+		if !ok {
+			if macro, ok := e.Macro.Get(car); ok {
+				// defined after the form was analysed
+				expansion, err := macro.(instance.Applicable).Apply(e.NewDynamic(), cdr.(instance.List).Slice()...)
+				if err != nil {
+					return nil, err
+				}
+				return Eval(e, expansion)
+			}
+			return SignalCondition(e, instance.NewUndefinedFunction(e, car), Nil)
+		}
+		return call(e, form, fun, arguments, tail)
+	}
+}
+
+// expandMacro analyses the expansion of form, a call of macro.
+func expandMacro(e env.Environment, s *scope, form *instance.Cons, macro ilos.Instance, tail bool) code {
+	depth := e.Depth
+	depth.Current++
+	defer func() { depth.Current-- }()
+	var expansion, err ilos.Instance
+	if depth.Limit > 0 && depth.Current > depth.Limit {
+		// a macro which expands into itself without end
+		err = instance.Create(e, class.StorageExhausted)
+	} else {
+		expansion, err = macro.(instance.Applicable).Apply(e.NewDynamic(), form.Cdr.(instance.List).Slice()...)
+	}
+	if err != nil {
+		return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			if ilos.InstanceOf(class.StorageExhausted, err) {
+				return SignalCondition(e, err, Nil)
+			}
+			return nil, err
+		}
+	}
+	return compile(e, s, expansion, tail)
+}
+
+// compileArguments analyses the arguments of a call, which are evaluated
+// from left to right.
+func compileArguments(e env.Environment, s *scope, arguments ilos.Instance) func(env.Environment) ([]ilos.Instance, ilos.Instance) {
+	forms, ok := properList(arguments)
+	if !ok {
+		return func(e env.Environment) ([]ilos.Instance, ilos.Instance) {
+			list, err := evalArguments(e, arguments)
+			if err != nil {
+				return nil, err
+			}
+			return list.(instance.List).Slice(), nil
+		}
+	}
+	codes := make([]code, len(forms))
+	for i, form := range forms {
+		codes[i] = compile(e, s, form, false)
+	}
+	return func(e env.Environment) ([]ilos.Instance, ilos.Instance) {
+		values := make([]ilos.Instance, len(codes))
+		for i, c := range codes {
+			v, err := c(e)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+}
+
+// call applies function to the arguments, or returns the call as a pending
+// *tailCall if it is in tail position of a function whose caller runs it.
+func call(e env.Environment, form, function ilos.Instance, arguments func(env.Environment) ([]ilos.Instance, ilos.Instance), tail bool) (ilos.Instance, ilos.Instance) {
+	values, err := arguments(e)
+	if err != nil {
+		return nil, err
+	}
+	if err := step(e); err != nil {
+		return nil, err
+	}
+	if tail && e.TailCall {
+		return &tailCall{function, values, form, e.Variable.Frame, e.Function.Frame}, nil
+	}
+	if st := e.Stepper; st != nil && len(st.Breakpoints) > 0 {
+		if err := breakAt(e, form); err != nil {
+			return nil, err
+		}
+	}
+	n := e.Stack.Push(env.Call{Form: form, Function: function, Arguments: values, Variables: e.Variable.Frame, Functions: e.Function.Frame})
+	ret, err := apply(e.NewDynamic(), function, values)
+	if err != nil {
+		attachBacktrace(e, err)
+	}
+	e.Stack.Pop(n)
+	return ret, err
+}
+
+// compileBody analyses forms evaluated in sequence, whose value is the value
+// of the last one or nil if there is none.
+func compileBody(e env.Environment, s *scope, forms []ilos.Instance, tail bool) code {
+	if len(forms) == 0 {
+		return constant(Nil)
+	}
+	codes := make([]code, len(forms))
+	for i, form := range forms {
+		codes[i] = compile(e, s, form, tail && i == len(forms)-1)
+	}
+	if len(codes) == 1 {
+		return codes[0]
+	}
+	first, last := codes[:len(codes)-1], codes[len(codes)-1]
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		for _, c := range first {
+			if _, err := c(e); err != nil {
+				return nil, err
+			}
+		}
+		return last(e)
+	}
+}
+
+// lambdaParameters returns the parameters of lambdaList and the variables
+// they bind, or false if lambdaList is malformed.
+func lambdaParameters(lambdaList ilos.Instance) (parameters, variables []ilos.Instance, variadic, ok bool) {
+	parameters, ok = properList(lambdaList)
+	if !ok {
+		return nil, nil, false, false
+	}
+	for i, p := range parameters {
+		if p == instance.NewSymbol(":REST") || p == instance.NewSymbol("&REST") {
+			if i != len(parameters)-2 {
+				return nil, nil, false, false
+			}
+			variadic = true
+			continue
+		}
+		variables = append(variables, p)
+	}
+	return parameters, variables, variadic, true
+}
@@ -0,0 +1,921 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// The compilers below analyse the special forms which evaluate subforms, and
+// behave as the special forms themselves do. Only if, cond, case,
+// case-using, progn, let, let*, and, or, flet and labels pass tail position
+// on to their last subform.
+
+func compileQuote(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 1 {
+		return nil, false
+	}
+	return constant(arguments[0]), true
+}
+
+func compileFunction(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 1 {
+		return nil, false
+	}
+	name, ok := arguments[0].(instance.Symbol)
+	if !ok {
+		return nil, false
+	}
+	if n, i, ok := s.address(name, true); ok {
+		return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			return e.Function.Frame.Out(n).Values[i], nil
+		}, true
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		if f, ok := e.Function.Get(name); ok {
+			return f, nil
+		}
+		return SignalCondition(e, instance.NewUndefinedFunction(e, name), Nil)
+	}, true
+}
+
+func compileLambda(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	parameters, variables, variadic, ok := lambdaParameters(arguments[0])
+	if !ok {
+		return nil, false
+	}
+	name := instance.NewSymbol("ANONYMOUS-FUNCTION")
+	body := compileBody(e, s.with(variables...), arguments[1:], true)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		return newClosure(e, name, parameters, variadic, body), nil
+	}, true
+}
+
+// definition is a local function of flet or labels.
+type definition struct {
+	name       ilos.Instance
+	parameters []ilos.Instance
+	variadic   bool
+	body       code
+}
+
+// compileDefinitions analyses the local functions of flet or labels. Their
+// bodies are in the scope s, or s with the functions themselves for labels.
+func compileDefinitions(e env.Environment, s *scope, functions ilos.Instance, recursive bool) ([]ilos.Instance, []definition, bool) {
+	list, ok := properList(functions)
+	if !ok {
+		return nil, nil, false
+	}
+	names := []ilos.Instance{}
+	for _, function := range list {
+		d, ok := properList(function)
+		if !ok || len(d) < 2 {
+			return nil, nil, false
+		}
+		if _, ok := d[0].(instance.Symbol); !ok {
+			return nil, nil, false
+		}
+		names = append(names, d[0])
+	}
+	if recursive {
+		s = s.withFunctions(names...)
+	}
+	definitions := []definition{}
+	for _, function := range list {
+		d, _ := properList(function)
+		parameters, variables, variadic, ok := lambdaParameters(d[1])
+		if !ok {
+			return nil, nil, false
+		}
+		body := compileBody(e, s.with(variables...), d[2:], true)
+		definitions = append(definitions, definition{d[0], parameters, variadic, body})
+	}
+	return names, definitions, true
+}
+
+func compileLabels(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	names, definitions, ok := compileDefinitions(e, s, arguments[0], true)
+	if !ok {
+		return nil, false
+	}
+	immutable := duplicate(names)
+	body := compileBody(e, s.withFunctions(names...), arguments[1:], tail)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		if immutable {
+			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
+		}
+		ne := e
+		ne.Function = e.Function.Push(names, make([]ilos.Instance, len(definitions)))
+		for i, d := range definitions {
+			ne.Function.Frame.Values[i] = newClosure(ne, d.name, d.parameters, d.variadic, d.body)
+		}
+		return body(ne)
+	}, true
+}
+
+func compileFlet(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	names, definitions, ok := compileDefinitions(e, s, arguments[0], false)
+	if !ok {
+		return nil, false
+	}
+	immutable := duplicate(names)
+	body := compileBody(e, s.withFunctions(names...), arguments[1:], tail)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		if immutable {
+			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
+		}
+		values := make([]ilos.Instance, len(definitions))
+		for i, d := range definitions {
+			values[i] = newClosure(e, d.name, d.parameters, d.variadic, d.body)
+		}
+		ne := e
+		ne.Function = e.Function.Push(names, values)
+		return body(ne)
+	}, true
+}
+
+func compileProgn(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	return compileBody(e, s, arguments, tail), true
+}
+
+func compileIf(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 2 && len(arguments) != 3 {
+		return nil, false
+	}
+	test := compile(e, s, arguments[0], false)
+	then := compile(e, s, arguments[1], tail)
+	els := constant(Nil)
+	if len(arguments) == 3 {
+		els = compile(e, s, arguments[2], tail)
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		t, err := test(e)
+		if err != nil {
+			return nil, err
+		}
+		if t != Nil {
+			return then(e)
+		}
+		return els(e)
+	}, true
+}
+
+// clause is a clause of cond, case or case-using. For cond, test is the test
+// form; for the others, keys are the keys unless the clause is the default.
+type clause struct {
+	test     code
+	keys     []ilos.Instance
+	fallback bool
+	body     code
+}
+
+func compileCond(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	clauses := []clause{}
+	for _, argument := range arguments {
+		c, ok := properList(argument)
+		if !ok || len(c) == 0 {
+			return nil, false
+		}
+		test := compile(e, s, c[0], false)
+		clauses = append(clauses, clause{test: test, body: compileBody(e, s, c[1:], tail)})
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		for _, c := range clauses {
+			ret, err := c.test(e)
+			if err != nil {
+				return nil, err
+			}
+			if ret == T {
+				return c.body(e)
+			}
+		}
+		return Nil, nil
+	}, true
+}
+
+func compileClauses(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) ([]clause, bool) {
+	clauses := []clause{}
+	for i, argument := range arguments {
+		c, ok := properList(argument)
+		if !ok || len(c) == 0 {
+			return nil, false
+		}
+		body := compileBody(e, s, c[1:], tail)
+		if i == len(arguments)-1 && c[0] == T {
+			clauses = append(clauses, clause{fallback: true, body: body})
+			continue
+		}
+		keys, ok := properList(c[0])
+		if !ok {
+			return nil, false
+		}
+		clauses = append(clauses, clause{keys: keys, body: body})
+	}
+	return clauses, true
+}
+
+func compileCase(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	key := compile(e, s, arguments[0], false)
+	clauses, ok := compileClauses(e, s, arguments[1:], tail)
+	if !ok {
+		return nil, false
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		k, err := key(e)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range clauses {
+			if c.fallback {
+				return c.body(e)
+			}
+			for _, key := range c.keys {
+				if ok, _ := Eql(e, key, k); ok == T {
+					return c.body(e)
+				}
+			}
+		}
+		return Nil, nil
+	}, true
+}
+
+func compileCaseUsing(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 2 {
+		return nil, false
+	}
+	predicate := compile(e, s, arguments[0], false)
+	key := compile(e, s, arguments[1], false)
+	clauses, ok := compileClauses(e, s, arguments[2:], tail)
+	if !ok {
+		return nil, false
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		k, err := key(e)
+		if err != nil {
+			return nil, err
+		}
+		pred, err := predicate(e)
+		if err != nil {
+			return nil, err
+		}
+		if err := ensure(e, class.Function, pred); err != nil {
+			return nil, err
+		}
+		for _, c := range clauses {
+			if c.fallback {
+				return c.body(e)
+			}
+			for _, key := range c.keys {
+				ret, err := pred.(instance.Applicable).Apply(e.NewDynamic(), key, k)
+				if err != nil {
+					return nil, err
+				}
+				if ret != Nil {
+					return c.body(e)
+				}
+			}
+		}
+		return Nil, nil
+	}, true
+}
+
+func compileAnd(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) == 0 {
+		return constant(T), true
+	}
+	codes := make([]code, len(arguments))
+	for i, argument := range arguments {
+		codes[i] = compile(e, s, argument, tail && i == len(arguments)-1)
+	}
+	first, last := codes[:len(codes)-1], codes[len(codes)-1]
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		for _, c := range first {
+			ret, err := c(e)
+			if err != nil {
+				return nil, err
+			}
+			if ret == Nil {
+				return Nil, nil
+			}
+		}
+		return last(e)
+	}, true
+}
+
+func compileOr(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) == 0 {
+		return constant(Nil), true
+	}
+	codes := make([]code, len(arguments))
+	for i, argument := range arguments {
+		codes[i] = compile(e, s, argument, tail && i == len(arguments)-1)
+	}
+	first, last := codes[:len(codes)-1], codes[len(codes)-1]
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		for _, c := range first {
+			ret, err := c(e)
+			if err != nil {
+				return nil, err
+			}
+			if ret != Nil {
+				return ret, nil
+			}
+		}
+		return last(e)
+	}, true
+}
+
+// compileBindings analyses a list of (var form) pairs.
+func compileBindings(e env.Environment, s *scope, bindings ilos.Instance) ([]ilos.Instance, []code, bool) {
+	list, ok := properList(bindings)
+	if !ok {
+		return nil, nil, false
+	}
+	variables := []ilos.Instance{}
+	forms := []ilos.Instance{}
+	for _, binding := range list {
+		pair, ok := properList(binding)
+		if !ok || len(pair) != 2 {
+			return nil, nil, false
+		}
+		variables = append(variables, pair[0])
+		forms = append(forms, pair[1])
+	}
+	codes := make([]code, len(forms))
+	for i, form := range forms {
+		codes[i] = compile(e, s, form, false)
+	}
+	return variables, codes, true
+}
+
+func compileLet(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	variables, forms, ok := compileBindings(e, s, arguments[0])
+	if !ok {
+		return nil, false
+	}
+	body := compileBody(e, s.with(variables...), arguments[1:], tail)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		values := make([]ilos.Instance, len(forms))
+		for i, form := range forms {
+			v, err := form(e)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		ne := e
+		ne.Variable = e.Variable.Push(variables, values)
+		return body(ne)
+	}, true
+}
+
+func compileLetStar(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	list, ok := properList(arguments[0])
+	if !ok {
+		return nil, false
+	}
+	variables := []ilos.Instance{}
+	for _, binding := range list {
+		pair, ok := properList(binding)
+		if !ok || len(pair) != 2 {
+			return nil, false
+		}
+		variables = append(variables, pair[0])
+	}
+	// Each form sees the variables before it, and looks up those after it
+	// by name when they are not bound yet.
+	inner := s.with(variables...)
+	variables, forms, _ := compileBindings(e, inner, arguments[0])
+	body := compileBody(e, inner, arguments[1:], tail)
+	// Binding a variable the second time signals an error.
+	immutable := len(variables)
+	for i := range variables {
+		if duplicate(variables[:i+1]) {
+			immutable = i
+			break
+		}
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		ne := e
+		ne.Variable = e.Variable.Push(variables, make([]ilos.Instance, len(variables)))
+		for i, form := range forms {
+			v, err := form(ne)
+			if err != nil {
+				return nil, err
+			}
+			if i == immutable {
+				return SignalCondition(ne, instance.NewImmutableBinding(ne), Nil)
+			}
+			ne.Variable.Frame.Values[i] = v
+		}
+		return body(ne)
+	}, true
+}
+
+func compileDynamicLet(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	variables, forms, ok := compileBindings(e, s, arguments[0])
+	if !ok {
+		return nil, false
+	}
+	body := compileBody(e, s, arguments[1:], false)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		frame := make(map[ilos.Instance]ilos.Instance, len(variables))
+		for i, form := range forms {
+			v, err := form(e)
+			if err != nil {
+				return nil, err
+			}
+			frame[variables[i]] = v
+		}
+		ne := e
+		ne.DynamicVariable = e.DynamicVariable.Push(frame)
+		return body(ne)
+	}, true
+}
+
+func compileDynamic(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 1 {
+		return nil, false
+	}
+	variable, ok := arguments[0].(instance.Symbol)
+	if !ok {
+		return nil, false
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		if v, ok := e.DynamicVariable.Get(variable); ok {
+			return v, nil
+		}
+		return SignalCondition(e, instance.NewUndefinedVariable(e, variable), Nil)
+	}, true
+}
+
+func compileSetq(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 2 {
+		return nil, false
+	}
+	variable := arguments[0]
+	form := compile(e, s, arguments[1], false)
+	n, i, bound := s.address(variable, false)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		v, err := form(e)
+		if err != nil {
+			return nil, err
+		}
+		if bound {
+			if f := e.Variable.Frame.Out(n); f.Values[i] != nil {
+				f.Values[i] = v
+				return v, nil
+			}
+		}
+		if e.Variable.Set(variable, v) {
+			return v, nil
+		}
+		return SignalCondition(e, instance.NewUndefinedVariable(e, variable), Nil)
+	}, true
+}
+
+func compileSetf(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 2 {
+		return nil, false
+	}
+	if _, ok := arguments[0].(instance.Symbol); ok {
+		return compileSetq(e, s, arguments, tail)
+	}
+	place, ok := properList(arguments[0])
+	if !ok || len(place) == 0 {
+		return nil, false
+	}
+	name := instance.NewSymbol(fmt.Sprintf("(SETF %v)", place[0]))
+	forms := append([]ilos.Instance{arguments[1]}, place[1:]...)
+	codes := make([]code, len(forms))
+	for i, form := range forms {
+		codes[i] = compile(e, s, form, false)
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		fun, ok := e.Function.Get(name)
+		if !ok {
+			return SignalCondition(e, instance.NewUndefinedFunction(e, name), Nil)
+		}
+		values := make([]ilos.Instance, len(codes))
+		for i, c := range codes {
+			v, err := c(e)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return fun.(instance.Applicable).Apply(e.NewDynamic(), values...)
+	}, true
+}
+
+func compileWhile(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	test := compile(e, s, arguments[0], false)
+	body := compileBody(e, s, arguments[1:], false)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		for {
+			t, err := test(e)
+			if err != nil {
+				return nil, err
+			}
+			if t != T {
+				return Nil, nil
+			}
+			if _, err := body(e); err != nil {
+				return nil, err
+			}
+			if err := step(e); err != nil {
+				return nil, err
+			}
+		}
+	}, true
+}
+
+// compileFor binds all the variables of a for form in one frame, and the
+// stepped ones again in a frame over it which is made anew each iteration.
+func compileFor(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 2 {
+		return nil, false
+	}
+	specs, ok := properList(arguments[0])
+	if !ok {
+		return nil, false
+	}
+	variables := []ilos.Instance{}
+	inits := []ilos.Instance{}
+	stepped := []ilos.Instance{}
+	steps := []ilos.Instance{}
+	isStepped := []bool{}
+	for _, spec := range specs {
+		is, ok := properList(spec)
+		if !ok || (len(is) != 2 && len(is) != 3) {
+			return nil, false
+		}
+		variables = append(variables, is[0])
+		inits = append(inits, is[1])
+		isStepped = append(isStepped, len(is) == 3)
+		if len(is) == 3 {
+			stepped = append(stepped, is[0])
+			steps = append(steps, is[2])
+		}
+	}
+	ends, ok := properList(arguments[1])
+	if !ok || len(ends) == 0 {
+		return nil, false
+	}
+	initCodes := make([]code, len(inits))
+	for i, init := range inits {
+		initCodes[i] = compile(e, s, init, false)
+	}
+	inner := s.with(variables...).with(stepped...)
+	stepCodes := make([]code, len(steps))
+	for i, step := range steps {
+		stepCodes[i] = compile(e, inner, step, false)
+	}
+	endTest := compile(e, inner, ends[0], false)
+	results := compileBody(e, inner, ends[1:], false)
+	body := compileBody(e, inner, arguments[2:], false)
+	immutable := duplicate(variables)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		all := make([]ilos.Instance, len(variables))
+		for i, init := range initCodes {
+			v, err := init(e)
+			if err != nil {
+				return nil, err
+			}
+			all[i] = v
+		}
+		if immutable {
+			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
+		}
+		current := make([]ilos.Instance, 0, len(stepped))
+		for i := range variables {
+			if isStepped[i] {
+				current = append(current, all[i])
+			}
+		}
+		ne := e
+		ne.Variable = e.Variable.Push(variables, all)
+		outer := ne.Variable
+		ne.Variable = outer.Push(stepped, current)
+		test, err := endTest(ne)
+		if err != nil {
+			return nil, err
+		}
+		for test == Nil {
+			if _, err := body(ne); err != nil {
+				return nil, err
+			}
+			if err := step(e); err != nil {
+				return nil, err
+			}
+			next := make([]ilos.Instance, len(stepped))
+			for i, step := range stepCodes {
+				v, err := step(ne)
+				if err != nil {
+					return nil, err
+				}
+				next[i] = v
+			}
+			ne.Variable = outer.Push(stepped, next)
+			test, err = endTest(ne)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return results(ne)
+	}, true
+}
+
+// escape returns the tag of fail if it is a non-local exit of class c to the
+// destination established with uid.
+func escape(fail ilos.Instance, c ilos.Class, uid ilos.Instance) (ilos.Instance, bool) {
+	if !ilos.InstanceOf(c, fail) {
+		return nil, false
+	}
+	uid1, _ := fail.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.UID"), class.Escape)
+	if uid1 != uid {
+		return nil, false
+	}
+	tag, _ := fail.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.TAG"), class.Escape)
+	return tag, true
+}
+
+func compileBlock(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	tag := arguments[0]
+	if ilos.InstanceOf(class.Number, tag) || ilos.InstanceOf(class.Character, tag) {
+		return nil, false
+	}
+	body := compileBody(e, s, arguments[1:], false)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		uid := instance.NewInteger(uniqueInt())
+		frame := map[ilos.Instance]ilos.Instance{tag: uid}
+		ne := e
+		ne.BlockTag = e.BlockTag.Push(frame)
+		ret, err := body(ne)
+		delete(frame, tag)
+		if err != nil {
+			if tag1, ok := escape(err, class.BlockTag, uid); ok && tag1 == tag {
+				obj, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.OBJECT"), class.BlockTag)
+				return obj, nil
+			}
+			return nil, err
+		}
+		return ret, nil
+	}, true
+}
+
+func compileReturnFrom(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 2 {
+		return nil, false
+	}
+	tag := arguments[0]
+	if ilos.InstanceOf(class.Number, tag) || ilos.InstanceOf(class.Character, tag) {
+		return nil, false
+	}
+	object := compile(e, s, arguments[1], false)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		obj, err := object(e)
+		if err != nil {
+			return nil, err
+		}
+		uid, ok := e.BlockTag.Get(tag)
+		if !ok {
+			return SignalCondition(e, instance.NewControlError(e), Nil)
+		}
+		return nil, instance.NewBlockTag(tag, uid, obj)
+	}, true
+}
+
+func compileCatch(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	tagForm := compile(e, s, arguments[0], false)
+	body := compileBody(e, s, arguments[1:], false)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		tag, err := tagForm(e)
+		if err != nil {
+			return nil, err
+		}
+		uid := instance.NewInteger(uniqueInt())
+		if ilos.InstanceOf(class.Number, tag) || ilos.InstanceOf(class.Character, tag) {
+			return SignalCondition(e, instance.NewDomainError(e, tag, class.Object), Nil)
+		}
+		frame := map[ilos.Instance]ilos.Instance{tag: uid}
+		ne := e
+		ne.CatchTag = e.CatchTag.Push(frame)
+		ret, err := body(ne)
+		delete(frame, tag)
+		if err != nil {
+			if tag1, ok := escape(err, class.CatchTag, uid); ok && tag1 == tag {
+				obj, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.OBJECT"), class.CatchTag)
+				return obj, nil
+			}
+			return nil, err
+		}
+		return ret, nil
+	}, true
+}
+
+func compileThrow(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 2 {
+		return nil, false
+	}
+	tagForm := compile(e, s, arguments[0], false)
+	object := compile(e, s, arguments[1], false)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		tag, err := tagForm(e)
+		if err != nil {
+			return nil, err
+		}
+		if ilos.InstanceOf(class.Number, tag) || ilos.InstanceOf(class.Character, tag) {
+			return SignalCondition(e, instance.NewDomainError(e, tag, class.Object), Nil)
+		}
+		obj, err := object(e)
+		if err != nil {
+			return nil, err
+		}
+		uid, ok := e.CatchTag.Get(tag)
+		if !ok {
+			return SignalCondition(e, instance.NewControlError(e), Nil)
+		}
+		return nil, instance.NewCatchTag(tag, uid, obj)
+	}, true
+}
+
+// compileTagbody analyses the statements of a tagbody form. A go to one of
+// its tags continues with the statement after the tag.
+func compileTagbody(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	tags := []ilos.Instance{}
+	targets := map[ilos.Instance]int{}
+	statements := []code{}
+	for _, argument := range arguments {
+		if _, ok := argument.(*instance.Cons); ok {
+			statements = append(statements, compile(e, s, argument, false))
+			continue
+		}
+		tags = append(tags, argument)
+		if _, ok := targets[argument]; !ok {
+			targets[argument] = len(statements)
+		}
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		uid := instance.NewInteger(uniqueInt())
+		ne := e
+		ne.TagbodyTag = e.TagbodyTag.Push(map[ilos.Instance]ilos.Instance{})
+		for _, tag := range tags {
+			if !ne.TagbodyTag.Define(tag, uid) {
+				return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
+			}
+		}
+		for i := 0; i < len(statements); {
+			if _, err := statements[i](ne); err != nil {
+				if tag, ok := escape(err, class.TagbodyTag, uid); ok {
+					if target, ok := targets[tag]; ok {
+						if err := step(e); err != nil {
+							return nil, err
+						}
+						i = target
+						continue
+					}
+				}
+				return nil, err
+			}
+			i++
+		}
+		return Nil, nil
+	}, true
+}
+
+func compileGo(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 1 {
+		return nil, false
+	}
+	tag := arguments[0]
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		uid, ok := e.TagbodyTag.Get(tag)
+		if !ok {
+			return SignalCondition(e, instance.NewControlError(e), Nil)
+		}
+		return nil, instance.NewTagbodyTag(tag, uid)
+	}, true
+}
+
+func compileUnwindProtect(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	form := compile(e, s, arguments[0], false)
+	cleanup := compileBody(e, s, arguments[1:], false)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		ret1, err1 := form(e)
+		ret2, err2 := cleanup(uninterrupted(e, err1))
+		if err2 != nil {
+			if ilos.InstanceOf(class.Escape, err2) {
+				return SignalCondition(e, instance.NewControlError(e), Nil)
+			}
+			return ret2, err2
+		}
+		return ret1, err1
+	}, true
+}
+
+func compileWithHandler(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) < 1 {
+		return nil, false
+	}
+	handler := compile(e, s, arguments[0], false)
+	body := compileBody(e, s, arguments[1:], false)
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		fun, err := handler(e)
+		if err != nil {
+			return nil, err
+		}
+		ne := e
+		ne.Handler = fun
+		return body(ne)
+	}, true
+}
+
+func compileQuasiquote(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 1 {
+		return nil, false
+	}
+	form := arguments[0]
+	// Expanding the template once finds the forms it unquotes.
+	unquoted := map[ilos.Instance]code{}
+	if _, err := expand(e, form, 0, func(_ env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+		unquoted[obj] = compile(e, s, obj, false)
+		return Nil, nil
+	}); err != nil {
+		return nil, false
+	}
+	eval := func(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+		if c, ok := unquoted[obj]; ok {
+			return c(e)
+		}
+		return Eval(e, obj)
+	}
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		return expand(e, form, 0, eval)
+	}, true
+}
+
+func compileConvert(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 2 {
+		return nil, false
+	}
+	object := compile(e, s, arguments[0], false)
+	className := arguments[1]
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		obj, err := object(e)
+		if err != nil {
+			return nil, err
+		}
+		return convert(e, obj, className)
+	}, true
+}
+
+func compileClass(e env.Environment, s *scope, arguments []ilos.Instance, tail bool) (code, bool) {
+	if len(arguments) != 1 {
+		return nil, false
+	}
+	name := arguments[0]
+	return func(e env.Environment) (ilos.Instance, ilos.Instance) {
+		return Class(e, name)
+	}, true
+}
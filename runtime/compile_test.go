@@ -0,0 +1,99 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+)
+
+type evaluator func(env.Environment, ilos.Instance) (ilos.Instance, ilos.Instance)
+
+func benchmarkEval(b *testing.B, eval evaluator, definitions, exp string) {
+	obj, err := readFromString(definitions)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := eval(TopLevel, obj); err != nil {
+		b.Fatal(err)
+	}
+	obj, err = readFromString(exp)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := eval(TopLevel, obj); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+const (
+	benchFib = `
+	(defun bench-fib (n)
+	  (if (< n 2) n (+ (bench-fib (- n 1)) (bench-fib (- n 2)))))`
+	benchLoop = `
+	(defun bench-loop (n acc)
+	  (if (= n 0) acc (bench-loop (- n 1) (+ acc n))))`
+	benchIncf = `
+	(defmacro bench-incf (v) (list 'setq v (list '+ v 1)))`
+	benchFor = `
+	(let ((s 0))
+	  (for ((i 0 (+ i 1))) ((= i 1000) s)
+	    (let ((j i)) (bench-incf s) (setq s (+ s j)))))`
+	benchSpecialBody = `
+	(let ((s 0))
+	  (for ((i 0 (+ i 1))) ((= i 1000) s)
+	    (with-error-output (error-output) (setq s (+ s i)))))`
+	benchIdentity = `
+	(defun bench-identity (x) x)`
+	benchGeneric = `
+	(progn
+	  (defgeneric bench-generic (x))
+	  (defmethod bench-generic ((x <integer>)) x))`
+)
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkEval(b, Eval, benchFib, `(bench-fib 15)`)
+}
+
+func BenchmarkTailLoop(b *testing.B) {
+	benchmarkEval(b, Eval, benchLoop, `(bench-loop 1000 0)`)
+}
+
+func BenchmarkFor(b *testing.B) {
+	benchmarkEval(b, Eval, benchIncf, benchFor)
+}
+
+// The benchmarks below make one call or binding each, so their allocations
+// per op are those of one call or binding.
+
+func BenchmarkSpecialBody(b *testing.B) {
+	benchmarkEval(b, Eval, `nil`, benchSpecialBody)
+}
+
+func BenchmarkCall(b *testing.B) {
+	benchmarkEval(b, Eval, benchIdentity, `(bench-identity 1)`)
+}
+
+func BenchmarkLet(b *testing.B) {
+	benchmarkEval(b, Eval, `nil`, `(let ((x 1)) x)`)
+}
+
+func BenchmarkLocalCall(b *testing.B) {
+	benchmarkEval(b, Eval, `nil`, `(flet ((f (x) x)) (f 1))`)
+}
+
+func BenchmarkClosureCall(b *testing.B) {
+	benchmarkEval(b, Eval, `nil`, `(funcall (let ((y 1)) (lambda (x) y)) 1)`)
+}
+
+func BenchmarkGenericCall(b *testing.B) {
+	benchmarkEval(b, Eval, benchGeneric, `(bench-generic 1)`)
+}
@@ -5,6 +5,10 @@
 package runtime
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/islisp-dev/iris/reader/parser"
 	"github.com/islisp-dev/iris/runtime/env"
 	"github.com/islisp-dev/iris/runtime/ilos"
 	"github.com/islisp-dev/iris/runtime/ilos/class"
@@ -16,6 +20,8 @@ func SignalCondition(e env.Environment, condition, continuable ilos.Instance) (i
 		return nil, err
 	}
 	condition.(instance.Instance).SetSlotValue(instance.NewSymbol("IRIS.CONTINUABLE"), continuable, class.SeriousCondition)
+	attachBacktrace(e, condition)
+	e.TailCall = false
 	_, c := e.Handler.(instance.Applicable).Apply(e, condition)
 	if ilos.InstanceOf(class.Continue, c) {
 		o, _ := c.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.OBJECT"), class.Continue)
@@ -24,6 +30,152 @@ func SignalCondition(e env.Environment, condition, continuable ilos.Instance) (i
 	return nil, c
 }
 
+// attachLocation records the location of form, which was executing in e when
+// condition was signaled, unless condition already has a more inner one.
+func attachLocation(e env.Environment, condition, form ilos.Instance) {
+	if !ilos.InstanceOf(class.SeriousCondition, condition) {
+		return
+	}
+	key := instance.NewSymbol("IRIS.LOCATION")
+	if _, ok := condition.(instance.Instance).GetSlotValue(key, class.SeriousCondition); ok {
+		return
+	}
+	if span, ok := parser.Location(e, form); ok {
+		condition.(instance.Instance).SetSlotValue(key, instance.NewString([]rune(span.String())), class.SeriousCondition)
+	}
+}
+
+// ConditionLocation returns the location as file:line:col of the form which
+// signaled condition.
+func ConditionLocation(condition ilos.Instance) (string, bool) {
+	if !ilos.InstanceOf(class.SeriousCondition, condition) {
+		return "", false
+	}
+	if location, ok := condition.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.LOCATION"), class.SeriousCondition); ok {
+		return string(location.(instance.String)), true
+	}
+	return "", false
+}
+
+// backtrace is a copy of the calls which were in progress when a condition
+// was signalled, innermost first, and the locations of the forms read where
+// it was signalled.
+type backtrace struct {
+	calls     []env.Call
+	locations env.Locations
+}
+
+func (backtrace) Class() ilos.Class {
+	return class.Object
+}
+
+func (b backtrace) String() string {
+	return fmt.Sprintf("#<BACKTRACE %v>", len(b.calls))
+}
+
+// attachBacktrace records the calls in progress in e when condition was
+// signalled, unless condition already has the calls of a more inner signal.
+// Conditions which are returned without being signalled get them when they
+// leave a call.
+func attachBacktrace(e env.Environment, condition ilos.Instance) {
+	if e.Stack == nil || !ilos.InstanceOf(class.SeriousCondition, condition) {
+		return
+	}
+	key := instance.NewSymbol("IRIS.BACKTRACE")
+	if _, ok := condition.(instance.Instance).GetSlotValue(key, class.SeriousCondition); ok {
+		return
+	}
+	calls := e.Stack.Calls
+	b := backtrace{make([]env.Call, len(calls)), e.Locations}
+	for i, call := range calls {
+		call.Arguments = append([]ilos.Instance(nil), call.Arguments...)
+		b.calls[len(calls)-1-i] = call
+	}
+	condition.(instance.Instance).SetSlotValue(key, b, class.SeriousCondition)
+}
+
+func conditionBacktrace(condition ilos.Instance) backtrace {
+	if !ilos.InstanceOf(class.SeriousCondition, condition) {
+		return backtrace{}
+	}
+	b, _ := condition.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.BACKTRACE"), class.SeriousCondition)
+	calls, _ := b.(backtrace)
+	return calls
+}
+
+// callName returns the name by which call was made, or the name of the
+// function called if it was not made by name, or the function itself if it
+// has none.
+func callName(call env.Call) ilos.Instance {
+	if form, ok := call.Form.(*instance.Cons); ok {
+		if _, ok := form.Car.(instance.Symbol); ok {
+			return form.Car
+		}
+	}
+	if f, ok := call.Function.(interface{ Name() ilos.Instance }); ok && f.Name() != nil {
+		return f.Name()
+	}
+	return call.Function
+}
+
+// describeCall returns call as the name of the function with its arguments
+// followed by the location of the call if it is one of locations.
+func describeCall(locations env.Locations, call env.Call) string {
+	words := []string{callName(call).String()}
+	for _, argument := range call.Arguments {
+		words = append(words, argument.String())
+	}
+	line := "(" + strings.Join(words, " ") + ")"
+	if span, ok := locations[call.Form]; ok {
+		line += " at " + span.String()
+	}
+	return line
+}
+
+// Backtrace returns the calls which were in progress when condition was
+// signalled, innermost first, each as the name of the function with its
+// arguments followed by the location of the call if it is known.
+func Backtrace(condition ilos.Instance) []string {
+	lines := []string{}
+	b := conditionBacktrace(condition)
+	for _, call := range b.calls {
+		lines = append(lines, describeCall(b.locations, call))
+	}
+	return lines
+}
+
+// ConditionBacktrace returns the calls which were in progress when condition
+// was signalled as a list, innermost first. Each call is a list of the name
+// of the function, or the function if it was not called by name, the list
+// of its arguments, the form which called it and the location of the form
+// as a string, or nil for those which are not known.
+func ConditionBacktrace(e env.Environment, condition ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, class.SeriousCondition, condition); err != nil {
+		return nil, err
+	}
+	calls := []ilos.Instance{}
+	b := conditionBacktrace(condition)
+	for _, call := range b.calls {
+		arguments, err := List(e, call.Arguments...)
+		if err != nil {
+			return nil, err
+		}
+		form, location := Nil, Nil
+		if call.Form != nil {
+			form = call.Form
+		}
+		if span, ok := b.locations[call.Form]; ok {
+			location = instance.NewString([]rune(span.String()))
+		}
+		c, err := List(e, callName(call), arguments, form, location)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, c)
+	}
+	return List(e, calls...)
+}
+
 func Cerror(e env.Environment, continueString, errorString ilos.Instance, objs ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	arguments, err := List(e, objs...)
 	if err != nil {
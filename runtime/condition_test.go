@@ -4,7 +4,13 @@
 
 package runtime
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
 
 func TestSignalCondition(t *testing.T) {
 	tests := []test{
@@ -35,3 +41,107 @@ func TestSignalCondition(t *testing.T) {
 	}
 	execTests(t, SignalCondition, tests)
 }
+
+func TestConditionLocation(t *testing.T) {
+	tests := []struct {
+		exp  string
+		want string
+	}{
+		{
+			exp:  "(car 1)",
+			want: "1:1",
+		},
+		{
+			exp:  "(progn\n  (list 1\n    (car 1)))",
+			want: "3:5",
+		},
+		{
+			exp:  "(progn\n  undefined-variable)",
+			want: "1:1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.exp, func(t *testing.T) {
+			obj, err := ReadForm(TopLevel, instance.NewStream(strings.NewReader(tt.exp), nil, class.Character))
+			if err != nil {
+				t.Fatalf("ParseError %v", err)
+			}
+			_, err = Eval(TopLevel, obj)
+			if got, ok := ConditionLocation(err); !ok || got != tt.want {
+				t.Errorf("ConditionLocation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorageExhausted(t *testing.T) {
+	defer func(limit int) { TopLevel.Depth.Limit = limit }(TopLevel.Depth.Limit)
+	TopLevel.Depth.Limit = 500
+	tests := []test{
+		{
+			exp:     `(defun runaway (n) (+ 1 (runaway n)))`,
+			want:    `'runaway`,
+			wantErr: false,
+		},
+		{
+			exp:     `(runaway 0)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp: `
+				(catch 'exhausted
+					(with-handler
+						(lambda (condition)
+							(throw 'exhausted (instancep condition (class <storage-exhausted>))))
+						(runaway 0)))
+				`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(with-handler (lambda (condition) (runaway 0)) (runaway 0))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, SignalCondition, tests)
+	if TopLevel.Depth.Current != 0 {
+		t.Errorf("Depth.Current = %v, want 0", TopLevel.Depth.Current)
+	}
+}
+
+func TestConditionBacktrace(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(defun backtrace-inner (x) (car x))`,
+			want:    `'backtrace-inner`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun backtrace-outer (x) (list (backtrace-inner x)))`,
+			want:    `'backtrace-outer`,
+			wantErr: false,
+		},
+		{
+			exp: `
+				(mapcar (lambda (call) (list (car call) (car (cdr call))))
+					(condition-backtrace
+						(catch 'c
+							(with-handler (lambda (condition) (throw 'c condition))
+								(backtrace-outer 1)))))
+				`,
+			want:    `'((car (1)) (backtrace-inner (1)) (backtrace-outer (1)))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(condition-backtrace 1)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, ConditionBacktrace, tests)
+	if n := len(TopLevel.Stack.Calls); n != 0 {
+		t.Errorf("len(Stack.Calls) = %v, want 0", n)
+	}
+}
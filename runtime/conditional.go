@@ -92,7 +92,7 @@ func Case(e env.Environment, key ilos.Instance, pattern ...ilos.Instance) (ilos.
 			return nil, err
 		}
 		for _, k := range form[0].(instance.List).Slice() {
-			if k == key {
+			if ok, _ := Eql(e, k, key); ok == T {
 				return Progn(e, form[1:]...)
 			}
 		}
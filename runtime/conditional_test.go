@@ -109,6 +109,11 @@ func TestCase(t *testing.T) {
 			want:    `'vowels`,
 			wantErr: false,
 		},
+		{
+			exp:     `(case 100000000000000000000 ((100000000000000000000) 'hit) (t 'miss))`,
+			want:    `'hit`,
+			wantErr: false,
+		},
 	})
 }
 
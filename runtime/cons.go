@@ -25,6 +25,9 @@ func Consp(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance)
 // requested cons cannot be allocated (error-id. cannot-create-cons). Both obj1
 // and obj2 may be any ISLISP object.
 func Cons(e env.Environment, obj1, obj2 ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := allocate(e, class.Cons, 1); err != nil {
+		return nil, err
+	}
 	return instance.NewCons(obj1, obj2), nil
 }
 
@@ -5,6 +5,9 @@
 package runtime
 
 import (
+	"math"
+	"unicode"
+
 	"github.com/islisp-dev/iris/runtime/env"
 	"github.com/islisp-dev/iris/runtime/ilos"
 	"github.com/islisp-dev/iris/runtime/ilos/class"
@@ -16,13 +19,17 @@ func Convert(e env.Environment, object, class1 ilos.Instance) (ilos.Instance, il
 	if err != nil {
 		return nil, err
 	}
-	class1, err = Class(e, class1)
+	return convert(e, object, class1)
+}
+
+func convert(e env.Environment, object, class1 ilos.Instance) (ilos.Instance, ilos.Instance) {
+	class1, err := findClass(e, class1)
 	if err != nil {
 		return nil, err
 	}
 	switch object.Class().String() {
 	case class.Character.String():
-		switch class1.(instance.BuiltInClass).String() {
+		switch class1.String() {
 		case class.Character.String():
 			return object, nil
 		case class.Integer.String():
@@ -30,18 +37,20 @@ func Convert(e env.Environment, object, class1 ilos.Instance) (ilos.Instance, il
 		case class.Float.String():
 		case class.Symbol.String():
 		case class.String.String():
-			return instance.NewString([]rune(object.String()[2:])), nil
+			return instance.NewString([]rune{rune(object.(instance.Character))}), nil
 		case class.GeneralVector.String():
 		case class.List.String():
 		}
 	case class.Integer.String():
 		switch class1.String() {
 		case class.Character.String():
-			return instance.NewCharacter(rune(int(object.(instance.Integer)))), nil
+			if n := fixnum(object); 0 <= n && n <= unicode.MaxRune {
+				return instance.NewCharacter(rune(n)), nil
+			}
 		case class.Integer.String():
 			return object, nil
 		case class.Float.String():
-			return instance.NewFloat(float64(int(object.(instance.Integer)))), nil
+			return instance.NewFloat(float(object)), nil
 		case class.Symbol.String():
 		case class.String.String():
 			return instance.NewString([]rune(object.String())), nil
@@ -52,7 +61,7 @@ func Convert(e env.Environment, object, class1 ilos.Instance) (ilos.Instance, il
 		switch class1.String() {
 		case class.Character.String():
 		case class.Integer.String():
-			return instance.NewInteger(int(float64(object.(instance.Float)))), nil
+			return integer(e, object, math.Trunc(float(object)))
 		case class.Float.String():
 			return object, nil
 		case class.Symbol.String():
@@ -69,7 +78,7 @@ func Convert(e env.Environment, object, class1 ilos.Instance) (ilos.Instance, il
 		case class.Symbol.String():
 			return object, nil
 		case class.String.String():
-			return instance.NewString([]rune(object.String())), nil
+			return instance.NewString([]rune(string(object.(instance.Symbol)))), nil
 		case class.GeneralVector.String():
 		case class.List.String():
 		}
@@ -84,6 +93,9 @@ func Convert(e env.Environment, object, class1 ilos.Instance) (ilos.Instance, il
 		case class.String.String():
 			return object, nil
 		case class.GeneralVector.String():
+			if err := allocate(e, class.GeneralVector, len(object.(instance.String))); err != nil {
+				return nil, err
+			}
 			v := make([]ilos.Instance, len(object.(instance.String)))
 			for i, c := range object.(instance.String) {
 				v[i] = instance.NewCharacter(c)
@@ -92,6 +104,9 @@ func Convert(e env.Environment, object, class1 ilos.Instance) (ilos.Instance, il
 		case class.List.String():
 			l := Nil
 			s := object.(instance.String)
+			if err := allocate(e, class.Cons, len(s)); err != nil {
+				return nil, err
+			}
 			for i := len(s) - 1; i >= 0; i-- {
 				l = instance.NewCons(instance.NewCharacter(s[i]), l)
 			}
@@ -8,6 +8,26 @@ import "testing"
 
 func TestConvert(t *testing.T) {
 	execTests(t, Convert, []test{
+		{
+			exp:     `(convert (expt 2 70) <string>)`,
+			want:    `"1180591620717411303424"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(convert (expt 2 70) <float>)`,
+			want:    `1180591620717411303424.0`,
+			wantErr: false,
+		},
+		{
+			exp:     `(convert "1180591620717411303424" <integer>)`,
+			want:    `(expt 2 70)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(convert (expt 2 70) <character>)`,
+			want:    `nil`,
+			wantErr: true,
+		},
 		{
 			exp:     `(convert 1.0 <integer>)`,
 			want:    `1`,
@@ -0,0 +1,140 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/islisp-dev/iris/reader/tokenizer"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+)
+
+// cover counts form as not evaluated yet if it was read from a source text
+// and is not counted already.
+func cover(cov *env.Coverage, form ilos.Instance) {
+	if _, ok := cov.Counts[form]; ok {
+		return
+	}
+	if _, ok := cov.Locations[form]; ok {
+		cov.Counts[form] = 0
+	}
+}
+
+// StartCoverage starts counting how often each form read by ReadForm is
+// evaluated in e, and in every environment made from it. Only the forms
+// analysed for evaluation from then on are counted, so it is started before
+// the source texts are loaded. Forms run on the virtual machine are not
+// counted.
+func StartCoverage(e env.Environment) {
+	e.Coverage.Counts = map[ilos.Instance]int{}
+	e.Coverage.Locations = e.Locations
+}
+
+// StopCoverage stops counting the forms evaluated in e and returns what was
+// counted, or nil if they were not.
+func StopCoverage(e env.Environment) *env.Coverage {
+	if e.Coverage.Counts == nil {
+		return nil
+	}
+	cov := &env.Coverage{Counts: e.Coverage.Counts, Locations: e.Coverage.Locations}
+	e.Coverage.Counts = nil
+	return cov
+}
+
+// coverageBlock is a form which was counted and the span of the text it was
+// read from.
+type coverageBlock struct {
+	span  tokenizer.Span
+	count int
+}
+
+// coverageBlocks returns the forms counted in cov by file, each in the
+// order of the texts they were read from.
+func coverageBlocks(cov *env.Coverage) (files []string, blocks map[string][]coverageBlock) {
+	blocks = map[string][]coverageBlock{}
+	for form, count := range cov.Counts {
+		span, ok := cov.Locations[form]
+		if !ok {
+			continue
+		}
+		file := span.Start.File
+		if _, ok := blocks[file]; !ok {
+			files = append(files, file)
+		}
+		blocks[file] = append(blocks[file], coverageBlock{span, count})
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		b := blocks[file]
+		sort.Slice(b, func(i, j int) bool {
+			if p, q := b[i].span.Start, b[j].span.Start; p.Line != q.Line {
+				return p.Line < q.Line
+			} else if p.Column != q.Column {
+				return p.Column < q.Column
+			}
+			// the outer of two forms at the same place first
+			p, q := b[i].span.End, b[j].span.End
+			return p.Line > q.Line || p.Line == q.Line && p.Column > q.Column
+		})
+	}
+	return files, blocks
+}
+
+// WriteCoverprofile writes cov to w in the format of the profiles which go
+// test -coverprofile writes, in count mode. Each form is a block of one
+// statement, so blocks nest as forms do.
+func WriteCoverprofile(w io.Writer, cov *env.Coverage) error {
+	if _, err := fmt.Fprintln(w, "mode: count"); err != nil {
+		return err
+	}
+	files, blocks := coverageBlocks(cov)
+	for _, file := range files {
+		for _, b := range blocks[file] {
+			start, end := b.span.Start, b.span.End
+			if _, err := fmt.Fprintf(w, "%v:%v.%v,%v.%v 1 %v\n", file, start.Line, start.Column, end.Line, end.Column, b.count); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteLCOV writes cov to w in the LCOV tracefile format. The count of a
+// line is the least count of the forms which start on it, so that a line is
+// only covered if all of them were evaluated.
+func WriteLCOV(w io.Writer, cov *env.Coverage) error {
+	files, blocks := coverageBlocks(cov)
+	for _, file := range files {
+		if _, err := fmt.Fprintf(w, "SF:%v\n", file); err != nil {
+			return err
+		}
+		lines, counts := []int{}, map[int]int{}
+		for _, b := range blocks[file] {
+			line := b.span.Start.Line
+			if count, ok := counts[line]; !ok {
+				lines = append(lines, line)
+				counts[line] = b.count
+			} else if b.count < count {
+				counts[line] = b.count
+			}
+		}
+		hit := 0
+		for _, line := range lines {
+			if counts[line] > 0 {
+				hit++
+			}
+			if _, err := fmt.Fprintf(w, "DA:%v,%v\n", line, counts[line]); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "LF:%v\nLH:%v\nend_of_record\n", len(lines), hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
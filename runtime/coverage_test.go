@@ -0,0 +1,81 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// namedReader reads a source text as if from the file name.
+type namedReader struct {
+	*strings.Reader
+	name string
+}
+
+func (r namedReader) Name() string { return r.name }
+
+func TestCoverage(t *testing.T) {
+	src := `(defun cover-sign (n)
+  (if (< n 0)
+      'negative
+      'positive))
+(defun cover-unused (x) (* x 2))
+(cover-sign 1)
+(cover-sign 2)
+`
+	e := NewEnvironment(TopLevel.StandardInput, TopLevel.StandardOutput, TopLevel.ErrorOutput)
+	StartCoverage(e)
+	stream := instance.NewStream(namedReader{strings.NewReader(src), "sign.lsp"}, nil, class.Character)
+	for {
+		form, err := ReadForm(e, stream)
+		if err != nil {
+			if !ilos.InstanceOf(class.EndOfStream, err) {
+				t.Fatal(err)
+			}
+			break
+		}
+		if _, err := Eval(e, form); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cov := StopCoverage(e)
+	if StopCoverage(e) != nil {
+		t.Error("StopCoverage() did not stop the coverage")
+	}
+	var lcov bytes.Buffer
+	if err := WriteLCOV(&lcov, cov); err != nil {
+		t.Fatal(err)
+	}
+	want := `SF:sign.lsp
+DA:1,1
+DA:2,2
+DA:3,0
+DA:4,2
+DA:5,0
+DA:6,1
+DA:7,1
+LF:7
+LH:5
+end_of_record
+`
+	if got := lcov.String(); got != want {
+		t.Errorf("WriteLCOV() = %q, want %q", got, want)
+	}
+	var profile bytes.Buffer
+	if err := WriteCoverprofile(&profile, cov); err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range []string{"mode: count\n", "sign.lsp:2.3,4.17 1 2\n", "sign.lsp:3.7,3.16 1 0\n", "sign.lsp:5.25,5.32 1 0\n"} {
+		if !strings.Contains(profile.String(), block) {
+			t.Errorf("WriteCoverprofile() = %q, want a line %q", profile.String(), block)
+		}
+	}
+}
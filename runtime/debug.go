@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+)
+
+// Frame is a function call in progress as a debugger sees it. Env is the
+// environment of the function called, in which forms may be evaluated in
+// the frame. It has the variables and local functions of the function only
+// if they are known: builtins have none, and those of functions run on the
+// virtual machine are not kept.
+type Frame struct {
+	env.Call
+	Env env.Environment
+}
+
+// Frames returns the function calls in progress when a condition was
+// signalled in e, innermost first, as the handler of the condition sees
+// them.
+func Frames(e env.Environment) []Frame {
+	calls := e.Stack.Calls
+	frames := make([]Frame, len(calls))
+	// The bindings of a function are those where it made the next call,
+	// or where the condition was signalled.
+	variables, functions := e.Variable.Frame, e.Function.Frame
+	for i := len(calls) - 1; i >= 0; i-- {
+		fe := e.NewDynamic()
+		fe.Variable.Frame, fe.Function.Frame = variables, functions
+		frames[len(calls)-1-i] = Frame{calls[i], fe}
+		variables, functions = calls[i].Variables, calls[i].Functions
+	}
+	return frames
+}
+
+// Locals returns the variables bound in the frame and their values,
+// innermost first. A variable shadowed by another of the same name is left
+// out.
+func (f Frame) Locals() (names, values []ilos.Instance) {
+	seen := map[ilos.Instance]bool{}
+	for fr := f.Env.Variable.Frame; fr != nil; fr = fr.Up {
+		for i := len(fr.Names) - 1; i >= 0; i-- {
+			name := fr.Names[i]
+			if i >= len(fr.Values) || fr.Values[i] == nil || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+			values = append(values, fr.Values[i])
+		}
+	}
+	return names, values
+}
+
+// String returns the call of the frame as Backtrace does.
+func (f Frame) String() string {
+	return describeCall(f.Env.Locations, f.Call)
+}
@@ -0,0 +1,55 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+func TestFrames(t *testing.T) {
+	e := NewEnvironment(TopLevel.StandardInput, TopLevel.StandardOutput, TopLevel.ErrorOutput)
+	var frames []Frame
+	e.Handler = instance.NewFunction(instance.NewSymbol("TEST-HANDLER"), func(e env.Environment, c ilos.Instance) (ilos.Instance, ilos.Instance) {
+		frames = Frames(e)
+		return nil, c
+	})
+	for _, exp := range []string{
+		`(defun frames-inner (x) (let ((y (+ x 1)) (x 0)) (car y)))`,
+		`(defun frames-outer (x) (list (frames-inner (* x 2))))`,
+		`(frames-outer 1)`,
+	} {
+		obj, err := readFromString(exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Eval(e, obj)
+	}
+	want := []string{"(CAR 3)", "(FRAMES-INNER 2)", "(FRAMES-OUTER 1)"}
+	if len(frames) != len(want) {
+		t.Fatalf("len(Frames()) = %v, want %v", len(frames), len(want))
+	}
+	for i, frame := range frames {
+		if got := frame.String(); !strings.HasPrefix(got, want[i]) {
+			t.Errorf("Frames()[%v] = %v, want %v", i, got, want[i])
+		}
+	}
+	names, values := frames[1].Locals()
+	wantNames := []ilos.Instance{instance.NewSymbol("X"), instance.NewSymbol("Y")}
+	wantValues := []ilos.Instance{instance.NewInteger(0), instance.NewInteger(3)}
+	if !reflect.DeepEqual(names, wantNames) || !reflect.DeepEqual(values, wantValues) {
+		t.Errorf("Locals() = %v, %v, want %v, %v", names, values, wantNames, wantValues)
+	}
+	for i, want := range map[int]ilos.Instance{1: instance.NewInteger(0), 2: instance.NewInteger(1)} {
+		if got, err := Eval(frames[i].Env, instance.NewSymbol("X")); err != nil || got != want {
+			t.Errorf("Eval(X) in frame %v = %v, %v, want %v", i, got, err, want)
+		}
+	}
+}
@@ -84,10 +84,6 @@ func DynamicLet(e env.Environment, varForm ilos.Instance, bodyForm ...ilos.Insta
 		}
 		vfs[cadr.(instance.List).Nth(0)] = f
 	}
-	for v, f := range vfs {
-		if !e.DynamicVariable.Define(v, f) {
-			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
-		}
-	}
+	e.DynamicVariable = e.DynamicVariable.Push(vfs)
 	return Progn(e, bodyForm...)
 }
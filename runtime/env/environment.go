@@ -5,23 +5,31 @@
 package env
 
 import (
+	"context"
+	"math"
+	"runtime/debug"
+	"time"
+
+	"github.com/islisp-dev/iris/reader/tokenizer"
 	"github.com/islisp-dev/iris/runtime/ilos"
 )
 
-// Environment struct is the struct for keeping functions and variables
+// Environment struct is the struct for keeping functions and variables. The
+// global namespaces are tables which every environment made from one top
+// level environment shares, so making an environment allocates nothing.
 type Environment struct {
 	// Lexical
 	BlockTag   stack
 	TagbodyTag stack
-	Function   stack
-	Variable   stack
+	Function   namespace
+	Variable   namespace
 
 	// Global
-	Class    stack
-	Macro    stack
-	Special  stack
+	Class    table
+	Macro    table
+	Special  table
 	Property map2
-	Constant stack
+	Constant table
 
 	// Dynamic
 	CatchTag        stack
@@ -30,8 +38,194 @@ type Environment struct {
 	StandardOutput  ilos.Instance
 	ErrorOutput     ilos.Instance
 	Handler         ilos.Instance
+
+	// Depth is shared by all the environments made from one top level
+	// environment.
+	Depth *Depth
+
+	// Context is checked while evaluating: once it is done, evaluation
+	// stops with an <interrupted> condition.
+	Context context.Context
+
+	// Stack is the stack of the function calls in progress. Like Depth it
+	// is shared.
+	Stack *Stack
+
+	// Budget, if not nil, limits the resources which evaluation may use.
+	// Like Depth it is shared.
+	Budget *Budget
+
+	// Stepper pauses evaluation for stepping and at breakpoints. Like
+	// Depth it is shared.
+	Stepper *Stepper
+
+	// Coverage counts the forms evaluated, while it is on. Like Depth it
+	// is shared.
+	Coverage *Coverage
+
+	// Locations are the locations of the forms read to be evaluated. Like
+	// Depth they are shared.
+	Locations Locations
+
+	// Compiled keeps the code which forms were analysed into when they
+	// were evaluated, so that the forms which special forms evaluate each
+	// time they run are analysed only once. Like Depth it is shared.
+	Compiled map[ilos.Instance]func(Environment) (ilos.Instance, ilos.Instance)
+
+	// Macros counts the definitions of macros, so that the code which a
+	// form was expanded into is made again once a macro is defined again.
+	// Like Depth it is shared.
+	Macros *int
+
+	// TailCall is set when the caller runs pending tail calls itself, so
+	// a call in tail position may be returned instead of being made. It
+	// is never inherited by NewLexical or NewDynamic.
+	TailCall bool
+}
+
+// DefaultDepthLimit is the depth limit of a new top level environment. It
+// is as many calls as fit in the Go stack of a goroutine, so that runaway
+// recursion signals <storage-exhausted> rather than overflowing it, but any
+// recursion which fits in the stack is allowed.
+var DefaultDepthLimit = DepthLimit(maxStack())
+
+// stackPerCall is the Go stack which a nested function call takes at most.
+// Measured by recursing until the Go stack overflowed, a call of a function
+// whose body is an if around a call takes about 7KB, and one whose body
+// nests let, cond, block, catch and unwind-protect around it about 14KB;
+// the rest is room for bodies which nest more, and for the handler of
+// <storage-exhausted>, which may go deeper than the limit.
+const stackPerCall = 24 << 10
+
+// maxStack returns the most Go stack which a goroutine may use, as set by
+// debug.SetMaxStack.
+func maxStack() int {
+	n := debug.SetMaxStack(math.MaxInt32)
+	debug.SetMaxStack(n)
+	return n
+}
+
+// DepthLimit returns how many function calls may be nested in a goroutine
+// whose stack may grow to maxStack bytes. A stack grows by doubling, so it
+// can only use the largest power of two which is not more than maxStack.
+func DepthLimit(maxStack int) int {
+	usable := 1
+	for usable <= maxStack/2 {
+		usable *= 2
+	}
+	return usable / stackPerCall
+}
+
+// Depth counts the function calls in progress, and the expansions of
+// macros, in Current. Limit is the most which may be nested; zero means no
+// limit. Forms counts the forms being evaluated, which the stepper goes by.
+type Depth struct {
+	Current int
+	Limit   int
+	Forms   int
+}
+
+// Call is a function call in progress: the form which made it, if any, the
+// function and its arguments. Variables and Functions are the lexical
+// bindings where it was made, if they are known.
+type Call struct {
+	Form      ilos.Instance
+	Function  ilos.Instance
+	Arguments []ilos.Instance
+	Variables *Frame
+	Functions *Frame
 }
 
+// Stack is a shadow of the Go stack which keeps the function calls in
+// progress, innermost last, for backtraces. If Profile is set, it counts
+// the calls as they are pushed and popped.
+type Stack struct {
+	Calls   []Call
+	Profile *Profile
+}
+
+// Push pushes a call and returns the height of the stack before it, to
+// which Pop returns the stack when the call ends.
+func (s *Stack) Push(c Call) int {
+	s.Calls = append(s.Calls, c)
+	if s.Profile != nil {
+		s.Profile.Enter(s.Profile.Name(c), len(s.Calls)-1)
+	}
+	return len(s.Calls) - 1
+}
+
+// Pop drops the calls above height n, so that they can be collected.
+func (s *Stack) Pop(n int) {
+	if s.Profile != nil {
+		s.Profile.Leave(n)
+	}
+	for i := n; i < len(s.Calls); i++ {
+		s.Calls[i] = Call{}
+	}
+	s.Calls = s.Calls[:n]
+}
+
+// Budget limits the steps, the allocations and the time which an
+// evaluation may take. Steps are function calls and iterations of loops.
+// Conses and Elements count the conses and vector elements allocated so
+// far; a string may be no longer than StringLimit and an integer may have
+// no more than IntegerLimit bits. A zero limit means no limit.
+type Budget struct {
+	Steps        int
+	StepLimit    int
+	Conses       int
+	ConsLimit    int
+	Elements     int
+	ElementLimit int
+	StringLimit  int
+	IntegerLimit int
+	Deadline     time.Time
+}
+
+// Stepper pauses evaluation, if Pause is set, before each form evaluated at
+// most Until levels deep while Stepping, and at each call of a function
+// named in Breakpoints. Pause is called with the environment in which form
+// is about to be evaluated. It returns how evaluation goes on, or a
+// condition to stop it with. Evaluation does not pause again while Paused
+// is set, as it is while Pause runs.
+type Stepper struct {
+	Stepping    bool
+	Until       int
+	Breakpoints map[ilos.Instance]bool
+	Pause       func(e Environment, form ilos.Instance) (StepAction, ilos.Instance)
+	Paused      bool
+}
+
+// Coverage counts how often each form read from a source text is evaluated
+// while Counts is not nil. Every such form which is analysed for evaluation
+// is counted, so that those never evaluated are counted as 0. Locations are
+// where the forms were read from.
+type Coverage struct {
+	Counts    map[ilos.Instance]int
+	Locations Locations
+}
+
+// Locations maps the conses of the forms read to be evaluated to the spans
+// of the texts they were read from. The forms are kept as long as the
+// environment they were read in.
+type Locations map[ilos.Instance]tokenizer.Span
+
+// StepAction is how evaluation goes on after a pause.
+type StepAction int
+
+const (
+	// StepInto pauses before the next form.
+	StepInto StepAction = iota
+	// StepOver pauses before the next form which is not part of the form
+	// paused at.
+	StepOver
+	// StepOut pauses before the next form which is not part of the form
+	// around the one paused at.
+	StepOut
+	// Continue stops stepping until the next breakpoint.
+	Continue
+)
+
 // New creates new eironment
 func NewEnvironment(stdin, stdout, stderr, handler ilos.Instance) Environment {
 	e := new(Environment)
@@ -39,14 +233,14 @@ func NewEnvironment(stdin, stdout, stderr, handler ilos.Instance) Environment {
 	// Lexical
 	e.BlockTag = NewStack()
 	e.TagbodyTag = NewStack()
-	e.Function = NewStack()
-	e.Variable = NewStack()
+	e.Function = newNamespace()
+	e.Variable = newNamespace()
 
 	// Global
-	e.Macro = NewStack()
-	e.Class = NewStack()
-	e.Special = NewStack()
-	e.Constant = NewStack()
+	e.Macro = table{}
+	e.Class = table{}
+	e.Special = table{}
+	e.Constant = table{}
 	e.Property = NewMap2()
 
 	// Dynamic
@@ -56,73 +250,33 @@ func NewEnvironment(stdin, stdout, stderr, handler ilos.Instance) Environment {
 	e.StandardOutput = stdout
 	e.ErrorOutput = stderr
 	e.Handler = handler
+	e.Depth = &Depth{Limit: DefaultDepthLimit}
+	e.Stack = new(Stack)
+	e.Stepper = &Stepper{Breakpoints: map[ilos.Instance]bool{}}
+	e.Coverage = new(Coverage)
+	e.Locations = Locations{}
+	e.Compiled = map[ilos.Instance]func(Environment) (ilos.Instance, ilos.Instance){}
+	e.Macros = new(int)
+	e.Context = context.Background()
 	return *e
 }
 
-func (e *Environment) MergeLexical(before Environment) {
-	e.BlockTag = before.BlockTag.Append(e.BlockTag[1:])
-	e.TagbodyTag = before.TagbodyTag.Append(e.TagbodyTag[1:])
-	e.Variable = before.Variable.Append(e.Variable[1:])
-	e.Function = before.Function.Append(e.Function[1:])
-
-	e.Macro = before.Macro.Append(e.Macro[1:])
-	e.Class = before.Class.Append(e.Class[1:])
-	e.Special = before.Special.Append(e.Special[1:])
-	e.Constant = before.Constant.Append(e.Constant[1:])
-	e.Property = before.Property
-
-	e.CatchTag = before.CatchTag.Append(e.CatchTag[1:])
-	e.DynamicVariable = before.DynamicVariable.Append(e.DynamicVariable[1:])
-	e.StandardInput = before.StandardInput
-	e.StandardOutput = before.StandardOutput
-	e.ErrorOutput = before.ErrorOutput
-	e.Handler = before.Handler
-}
-
+// NewLexical returns an environment with the bindings of before. Forms which
+// bind something push frames of their own onto it.
 func (before *Environment) NewLexical() Environment {
-	e := NewEnvironment(before.StandardInput, before.StandardOutput, before.ErrorOutput, before.Handler)
-
-	e.BlockTag = before.BlockTag.Append(e.BlockTag)
-	e.TagbodyTag = before.TagbodyTag.Append(e.TagbodyTag)
-	e.Variable = before.Variable.Append(e.Variable)
-	e.Function = before.Function.Append(e.Function)
-
-	e.Macro = before.Macro.Append(e.Macro)
-	e.Class = before.Class.Append(e.Class)
-	e.Special = before.Special.Append(e.Special)
-	e.Constant = before.Constant.Append(e.Constant)
-	e.Property = before.Property
-
-	e.CatchTag = before.CatchTag.Append(e.CatchTag)
-	e.DynamicVariable = before.DynamicVariable.Append(e.DynamicVariable)
-	e.StandardInput = before.StandardInput
-	e.StandardOutput = before.StandardOutput
-	e.ErrorOutput = before.ErrorOutput
-	e.Handler = before.Handler
-
+	e := *before
+	e.TailCall = false
 	return e
 }
 
+// NewDynamic returns an environment with the global and dynamic bindings of
+// before but none of its lexical ones, in which a function is applied.
 func (before *Environment) NewDynamic() Environment {
-	e := NewEnvironment(before.StandardInput, before.StandardOutput, before.ErrorOutput, before.Handler)
-
-	e.BlockTag = stack{before.BlockTag[0]}.Append(e.BlockTag)
-	e.TagbodyTag = stack{before.TagbodyTag[0]}.Append(e.TagbodyTag)
-	e.Variable = stack{before.Variable[0]}.Append(e.Variable)
-	e.Function = stack{before.Function[0]}.Append(e.Function)
-
-	e.Macro = stack{before.Macro[0]}.Append(e.Macro)
-	e.Class = stack{before.Class[0]}.Append(e.Class)
-	e.Special = stack{before.Special[0]}.Append(e.Special)
-	e.Constant = stack{before.Constant[0]}.Append(e.Constant)
-	e.Property = before.Property
-
-	e.CatchTag = before.CatchTag.Append(e.CatchTag)
-	e.DynamicVariable = before.DynamicVariable.Append(e.DynamicVariable)
-	e.StandardInput = before.StandardInput
-	e.StandardOutput = before.StandardOutput
-	e.ErrorOutput = before.ErrorOutput
-	e.Handler = before.Handler
-
+	e := *before
+	e.BlockTag = before.BlockTag[:1:1]
+	e.TagbodyTag = before.TagbodyTag[:1:1]
+	e.Function.Frame = nil
+	e.Variable.Frame = nil
+	e.TailCall = false
 	return e
 }
@@ -0,0 +1,134 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package env
+
+import (
+	"github.com/islisp-dev/iris/runtime/ilos"
+)
+
+// Frame is a frame of lexical bindings over the frames around it. Analysed
+// code reaches a binding by how many frames out it is and its index, other
+// code by its name. A nil value is a binding which is not made yet.
+type Frame struct {
+	Names  []ilos.Instance
+	Values []ilos.Instance
+	Up     *Frame
+}
+
+// Out returns the frame n frames out from f.
+func (f *Frame) Out(n int) *Frame {
+	for ; n > 0; n-- {
+		f = f.Up
+	}
+	return f
+}
+
+// find returns the innermost frame binding name and the index of the
+// binding in it.
+func (f *Frame) find(name ilos.Instance) (*Frame, int) {
+	for ; f != nil; f = f.Up {
+		for i := len(f.Names) - 1; i >= 0; i-- {
+			if f.Names[i] == name && f.Values[i] != nil {
+				return f, i
+			}
+		}
+	}
+	return nil, 0
+}
+
+// Define binds name in f itself. It reports whether name was not bound in f
+// already.
+func (f *Frame) Define(name, value ilos.Instance) bool {
+	for i, n := range f.Names {
+		if n == name {
+			f.Values[i] = value
+			return false
+		}
+	}
+	f.Names = append(f.Names, name)
+	f.Values = append(f.Values, value)
+	return true
+}
+
+// namespace is a lexical namespace: frames of local bindings over a single
+// table of global ones, which every environment shares.
+type namespace struct {
+	Frame  *Frame
+	Global table
+}
+
+func newNamespace() namespace {
+	return namespace{Global: table{}}
+}
+
+func (n namespace) Get(key ilos.Instance) (ilos.Instance, bool) {
+	if f, i := n.Frame.find(key); f != nil {
+		return f.Values[i], true
+	}
+	return n.Global.Get(key)
+}
+
+func (n namespace) Set(key, value ilos.Instance) bool {
+	if f, i := n.Frame.find(key); f != nil {
+		f.Values[i] = value
+		return true
+	}
+	return n.Global.Set(key, value)
+}
+
+// Define makes a global binding of key.
+func (n namespace) Define(key, value ilos.Instance) bool {
+	return n.Global.Define(key, value)
+}
+
+// Push returns n with a frame binding names to values over its frames. The
+// frame keeps both slices.
+func (n namespace) Push(names, values []ilos.Instance) namespace {
+	n.Frame = &Frame{names, values, n.Frame}
+	return n
+}
+
+// Keys returns the keys bound globally or in any frame of n.
+func (n namespace) Keys() []ilos.Instance {
+	keys := n.Global.Keys()
+	for f := n.Frame; f != nil; f = f.Up {
+		keys = append(keys, f.Names...)
+	}
+	return keys
+}
+
+// table is a global namespace.
+type table map[ilos.Instance]ilos.Instance
+
+func (t table) Get(key ilos.Instance) (ilos.Instance, bool) {
+	v, ok := t[key]
+	return v, ok
+}
+
+func (t table) Set(key, value ilos.Instance) bool {
+	if _, ok := t[key]; !ok {
+		return false
+	}
+	t[key] = value
+	return true
+}
+
+func (t table) Define(key, value ilos.Instance) bool {
+	_, ok := t[key]
+	t[key] = value
+	return !ok
+}
+
+func (t table) Delete(key ilos.Instance) {
+	delete(t, key)
+}
+
+func (t table) Keys() []ilos.Instance {
+	keys := make([]ilos.Instance, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	return keys
+}
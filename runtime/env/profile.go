@@ -0,0 +1,81 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package env
+
+import (
+	"time"
+)
+
+// Profile counts the calls of functions, and of the methods of generic
+// functions, and the time spent in them while it is the profile of a Stack.
+// The calls are kept in a tree of the functions in progress, below Root, so
+// that each node is a function called by the function of its parent.
+type Profile struct {
+	// Name returns the name under which a call is counted.
+	Name   func(Call) string
+	Root   *ProfileNode
+	active []profileFrame
+}
+
+// ProfileNode is a function called by the function of its parent: how
+// often it was and the time spent in it, without that spent in the
+// functions it called.
+type ProfileNode struct {
+	Name     string
+	Calls    int
+	Self     time.Duration
+	Children map[string]*ProfileNode
+}
+
+// profileFrame is a call in progress. Its height is where it is in the
+// stack of calls, or -1 if it is a method, which is not on the stack.
+type profileFrame struct {
+	node     *ProfileNode
+	start    time.Time
+	children time.Duration
+	height   int
+}
+
+// NewProfile returns an empty profile counting calls under name.
+func NewProfile(name func(Call) string) *Profile {
+	return &Profile{Name: name, Root: &ProfileNode{Children: map[string]*ProfileNode{}}}
+}
+
+// Enter counts a call of the function called name, which is at height in
+// the stack of calls or -1 if it is a method.
+func (p *Profile) Enter(name string, height int) {
+	parent := p.Root
+	if len(p.active) > 0 {
+		parent = p.active[len(p.active)-1].node
+	}
+	node, ok := parent.Children[name]
+	if !ok {
+		node = &ProfileNode{Name: name, Children: map[string]*ProfileNode{}}
+		parent.Children[name] = node
+	}
+	node.Calls++
+	p.active = append(p.active, profileFrame{node, time.Now(), 0, height})
+}
+
+// Leave ends the calls at height or above in the stack of calls, or the
+// latest method if height is -1, and adds the time spent in them.
+func (p *Profile) Leave(height int) {
+	now := time.Now()
+	for len(p.active) > 0 {
+		f := p.active[len(p.active)-1]
+		if height >= 0 && f.height < height {
+			break
+		}
+		p.active = p.active[:len(p.active)-1]
+		elapsed := now.Sub(f.start)
+		f.node.Self += elapsed - f.children
+		if len(p.active) > 0 {
+			p.active[len(p.active)-1].children += elapsed
+		}
+		if height < 0 && f.height < 0 {
+			break
+		}
+	}
+}
@@ -46,9 +46,19 @@ func (s stack) Delete(key ilos.Instance) {
 	delete(s[len(s)-1], key)
 }
 
-func (s stack) Append(t stack) stack {
-	u := stack{}
-	u = append(u, s...)
-	u = append(u, t...)
-	return u
+// Push returns a new stack with frame on top of s. It leaves s as it was, so
+// a stack may be pushed again while environments made from it are in use.
+func (s stack) Push(frame map[ilos.Instance]ilos.Instance) stack {
+	return append(s[:len(s):len(s)], frame)
+}
+
+// Keys returns the keys defined in any frame of s.
+func (s stack) Keys() []ilos.Instance {
+	keys := []ilos.Instance{}
+	for _, m := range s {
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	return keys
 }
@@ -46,6 +46,12 @@ func Eq(e env.Environment, obj1, obj2 ilos.Instance) (ilos.Instance, ilos.Instan
 // same if there is no operation that could distinguish them (without modifying
 // them), and if modifying one would modify the other the same way.
 func Eql(e env.Environment, obj1, obj2 ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if ilos.InstanceOf(class.Integer, obj1) && ilos.InstanceOf(class.Integer, obj2) {
+		if bigInt(obj1).Cmp(bigInt(obj2)) == 0 {
+			return T, nil
+		}
+		return Nil, nil
+	}
 	t1, t2 := reflect.TypeOf(obj1), reflect.TypeOf(obj2)
 	if isComparable(t1) || isComparable(t2) {
 		if obj1 == obj2 {
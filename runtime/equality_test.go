@@ -141,6 +141,16 @@ func TestEq(t *testing.T) {
 
 func TestEql(t *testing.T) {
 	tests := []test{
+		{
+			exp:     `(eql (expt 2 70) (expt 2 70))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(eql (expt 2 70) (+ (expt 2 70) 1))`,
+			want:    `nil`,
+			wantErr: false,
+		},
 		{
 			exp:     `(eql () ())`,
 			want:    `T`,
@@ -274,6 +284,11 @@ func TestEql(t *testing.T) {
 
 func TestEqual(t *testing.T) {
 	tests := []test{
+		{
+			exp:     `(equal (list (expt 2 70)) (list 1180591620717411303424))`,
+			want:    `t`,
+			wantErr: false,
+		},
 		{
 			exp:     `(equal 'a 'a)`,
 			want:    `t`,
@@ -5,6 +5,9 @@
 package runtime
 
 import (
+	"context"
+	"time"
+
 	"github.com/islisp-dev/iris/runtime/env"
 	"github.com/islisp-dev/iris/runtime/ilos"
 	"github.com/islisp-dev/iris/runtime/ilos/class"
@@ -33,123 +36,163 @@ func evalArguments(e env.Environment, arguments ilos.Instance) (ilos.Instance, i
 
 }
 
-func evalLambda(e env.Environment, car, cdr ilos.Instance) (ilos.Instance, ilos.Instance, bool) {
-	// eval if lambda form
-	if ilos.InstanceOf(class.Cons, car) {
-		caar := car.(*instance.Cons).Car // Checked at the top of// This sentence
-		if caar == instance.NewSymbol("LAMBDA") {
-			fun, err := Eval(e, car)
-			if err != nil {
-				return nil, err, true
-			}
-
-			arguments, err := evalArguments(e, cdr)
-			if err != nil {
-				return nil, err, true
-			}
-			ret, err := fun.(instance.Applicable).Apply(e.NewDynamic(), arguments.(instance.List).Slice()...)
-			if err != nil {
-				return nil, err, true
-			}
-			return ret, nil, true
-		}
+func evalVariable(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if val, ok := e.Variable.Get(obj); ok {
+		return val, nil
+	}
+	if val, ok := e.Constant.Get(obj); ok {
+		return val, nil
 	}
-	return nil, nil, false
+	return SignalCondition(e, instance.NewUndefinedVariable(e, obj), Nil)
 }
 
-func evalSpecial(e env.Environment, car, cdr ilos.Instance) (ilos.Instance, ilos.Instance, bool) {
-	// get special instance has value of Function interface
-	var spl ilos.Instance
-	if s, ok := e.Special.Get(car); ok {
-		spl = s
-	}
-	if spl != nil {
-		ret, err := spl.(instance.Applicable).Apply(e.NewLexical(), cdr.(instance.List).Slice()...)
-		if err != nil {
-			return nil, err, true
-		}
-		return ret, nil, true
-	}
-	return nil, nil, false
+// tailCall is a call in tail position which has not been made yet. It is
+// only ever returned to trampoline. It keeps the lexical bindings where it
+// was made for the backtrace, though the code which made it is done.
+type tailCall struct {
+	function  ilos.Instance
+	arguments []ilos.Instance
+	form      ilos.Instance
+	variables *env.Frame
+	functions *env.Frame
 }
 
-func evalMacro(e env.Environment, car, cdr ilos.Instance) (ilos.Instance, ilos.Instance, bool) {
-	// get special instance has value of Function interface
-	var mac ilos.Instance
-	if m, ok := e.Macro.Get(car); ok {
-		mac = m
-	}
-	if mac != nil {
-		ret, err := mac.(instance.Applicable).Apply(e.NewDynamic(), cdr.(instance.List).Slice()...)
-		if err != nil {
-			return nil, err, true
+func (*tailCall) Class() ilos.Class {
+	return class.Object
+}
+
+func (t *tailCall) String() string {
+	return t.form.String()
+}
+
+// trampoline makes the pending tail calls in ret until it has a value, so
+// that a chain of tail calls runs in constant Go stack. Every form which
+// establishes a dynamic binding, a handler or an exit point ends tail
+// position, so each call is made in the dynamic environment e of the
+// function which started the chain.
+func trampoline(e env.Environment, ret, err ilos.Instance) (ilos.Instance, ilos.Instance) {
+	for err == nil {
+		t, ok := ret.(*tailCall)
+		if !ok {
+			break
+		}
+		if st := e.Stepper; st != nil && len(st.Breakpoints) > 0 {
+			if err = breakAt(e, t.form); err != nil {
+				break
+			}
+		}
+		ne := e.NewDynamic()
+		if f, ok := t.function.(instance.Function); ok && f.TailCalls() {
+			ne.TailCall = true
 		}
-		ret, err = Eval(e, ret)
+		n := e.Stack.Push(env.Call{Form: t.form, Function: t.function, Arguments: t.arguments, Variables: t.variables, Functions: t.functions})
+		ret, err = apply(ne, t.function, t.arguments)
 		if err != nil {
-			return nil, err, true
+			attachLocation(e, err, t.form)
+			attachBacktrace(e, err)
 		}
-		return ret, nil, true
+		e.Stack.Pop(n)
 	}
-	return nil, nil, false
+	return ret, err
 }
 
-func evalFunction(e env.Environment, car, cdr ilos.Instance) (ilos.Instance, ilos.Instance, bool) {
-	// get special instance has value of Function interface
-	var fun ilos.Instance
-	if f, ok := e.Function.Get(car); ok {
-		fun = f
+// applyFunction applies function to arguments for a builtin such as funcall
+// or mapcar. The call is not made by a form, but it is on the stack of calls
+// all the same.
+func applyFunction(e env.Environment, function ilos.Instance, arguments []ilos.Instance) (ilos.Instance, ilos.Instance) {
+	n := e.Stack.Push(env.Call{Function: function, Arguments: arguments})
+	ret, err := apply(e, function, arguments)
+	if err != nil {
+		attachBacktrace(e, err)
 	}
-	if fun != nil {
-		arguments, err := evalArguments(e, cdr)
-		if err != nil {
-			return nil, err, true
-		}
-		ret, err := fun.(instance.Applicable).Apply(e.NewDynamic(), arguments.(instance.List).Slice()...)
-		if err != nil {
-			return nil, err, true
+	e.Stack.Pop(n)
+	return ret, err
+}
+
+// apply applies function to arguments, counting the call as one level of
+// evaluation, as the virtual machine does, so that runaway recursion
+// signals <storage-exhausted> before it overflows the Go stack.
+func apply(e env.Environment, function ilos.Instance, arguments []ilos.Instance) (ilos.Instance, ilos.Instance) {
+	var ret, err ilos.Instance
+	depth := e.Depth
+	depth.Current++
+	if depth.Limit > 0 && (depth.Current == depth.Limit+1 || depth.Current > depth.Limit+depthReserve) {
+		ret, err = exhausted(e)
+	} else {
+		ret, err = function.(instance.Applicable).Apply(e, arguments...)
+	}
+	depth.Current--
+	return ret, err
+}
+
+// depthReserve is how much deeper than its limit evaluation may go while
+// the handler of <storage-exhausted> runs.
+const depthReserve = 1000
+
+// exhausted signals <storage-exhausted> when evaluation first goes deeper
+// than e.Depth.Limit. If the handler itself goes too deep, the condition is
+// returned without calling a handler again.
+func exhausted(e env.Environment) (ilos.Instance, ilos.Instance) {
+	condition := instance.Create(e, class.StorageExhausted)
+	if e.Depth.Current == e.Depth.Limit+1 {
+		return SignalCondition(e, condition, Nil)
+	}
+	return nil, condition
+}
+
+// interrupted returns an <interrupted> condition once the context of e is
+// done, or a <time-limit-exceeded> if that is because the deadline of the
+// budget of e has passed, and nil before. The condition is returned rather
+// than signalled, so no handler can resume the evaluation, and it unwinds
+// through the cleanup forms of unwind-protect to the caller of EvalContext.
+func interrupted(e env.Environment) ilos.Instance {
+	select {
+	case <-e.Context.Done():
+		if b := e.Budget; b != nil && !b.Deadline.IsZero() && !time.Now().Before(b.Deadline) {
+			return instance.Create(e, class.TimeLimitExceeded)
 		}
-		return ret, nil, true
+		return instance.Create(e, class.Interrupted)
+	default:
+		return nil
 	}
-	return nil, nil, false
 }
 
-func evalCons(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if err := ensure(e, class.Cons, obj); err != nil {
-		return nil, err
+// step counts a function call or an iteration of a loop against the budget
+// of e. It returns a <step-limit-exceeded> condition, which no handler can
+// resume either, once there are more steps than the budget allows, and
+// otherwise what interrupted returns.
+func step(e env.Environment) ilos.Instance {
+	if b := e.Budget; b != nil && b.StepLimit > 0 {
+		if b.Steps++; b.Steps > b.StepLimit {
+			return instance.Create(e, class.StepLimitExceeded)
+		}
 	}
-	car := obj.(*instance.Cons).Car // Checked at the top of// This function
-	cdr := obj.(*instance.Cons).Cdr // Checked at the top of// This function
+	return interrupted(e)
+}
 
-	// eval if lambda form
-	if a, b, c := evalLambda(e, car, cdr); c {
-		return a, b
-	}
-	// get special instance has value of Function interface
-	if a, b, c := evalSpecial(e, car, cdr); c {
-		return a, b
-	}
-	// get macro instance has value of Function interface
-	if a, b, c := evalMacro(e, car, cdr); c {
-		return a, b
-	}
-	// get function instance has value of Function interface
-	if a, b, c := evalFunction(e, car, cdr); c {
-		return a, b
-	}
-	return SignalCondition(e, instance.NewUndefinedFunction(e, car), Nil)
+// uninterrupted returns e with a context which is never done if err is an
+// <interrupted> condition, so that cleanup forms run after an interruption
+// are not interrupted themselves. Under a budget they are, and they stop at
+// their first step once the budget is spent, so that code can not escape its
+// limits in cleanup forms.
+func uninterrupted(e env.Environment, err ilos.Instance) env.Environment {
+	if err != nil && e.Budget == nil && ilos.InstanceOf(class.Interrupted, err) {
+		e.Context = context.Background()
+	}
+	return e
 }
 
-func evalVariable(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if val, ok := e.Variable.Get(obj); ok {
-		return val, nil
-	}
-	if val, ok := e.Constant.Get(obj); ok {
-		return val, nil
-	}
-	return SignalCondition(e, instance.NewUndefinedVariable(e, obj), Nil)
+// EvalContext evaluates obj as Eval does until ctx is done, when the
+// evaluation stops with an <interrupted> condition. It is checked at each
+// function call, at each iteration of while, for and tagbody, and while a
+// stream is waited on for input.
+func EvalContext(ctx context.Context, e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+	e.Context = ctx
+	return Eval(e, obj)
 }
 
-// Eval evaluates any classs
+// Eval evaluates obj in e. A form is analysed into code the first time it
+// is evaluated, and the code is kept for the next times.
 func Eval(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if obj == Nil {
 		return Nil, nil
@@ -162,11 +205,24 @@ func Eval(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
 		return ret, nil
 	}
 	if ilos.InstanceOf(class.Cons, obj) {
-		ret, err := evalCons(e, obj)
-		if err != nil {
-			return nil, err
+		c, ok := e.Compiled[obj]
+		if !ok {
+			c = compile(e, nil, obj, false)
+			if e.Compiled != nil {
+				if len(e.Compiled) == maxCompiled {
+					for form := range e.Compiled {
+						delete(e.Compiled, form)
+					}
+				}
+				e.Compiled[obj] = c
+			}
 		}
-		return ret, nil
+		return c(e)
 	}
 	return obj, nil
 }
+
+// maxCompiled is how many forms Eval keeps the code of. The forms read at
+// the top level are evaluated only once, so they are dropped with the rest
+// once there are more.
+const maxCompiled = 1 << 14
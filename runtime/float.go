@@ -6,6 +6,7 @@ package runtime
 
 import (
 	"math"
+	"math/big"
 
 	"github.com/islisp-dev/iris/runtime/env"
 	"github.com/islisp-dev/iris/runtime/ilos"
@@ -21,6 +22,19 @@ var (
 	MostNegativeFloat = instance.NewFloat(-math.MaxFloat64)
 )
 
+// integer returns the integer of the integral float f computed from x. An
+// error shall be signaled if f is infinite or NaN (error-id. domain-error).
+func integer(e env.Environment, x ilos.Instance, f float64) (ilos.Instance, ilos.Instance) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return SignalCondition(e, instance.NewDomainError(e, x, class.Float), Nil)
+	}
+	if float64(minInt) <= f && f < float64(maxInt) {
+		return instance.NewInteger(int(f)), nil
+	}
+	i, _ := big.NewFloat(f).Int(nil)
+	return instance.NewBigInteger(i), nil
+}
+
 // Floatp returns t if obj is a ﬂoat (instance of class float); otherwise,
 // returns nil. The obj may be any ISLISP object.
 func Floatp(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
@@ -45,42 +59,54 @@ func Float(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 // truncated towards negative infinity. An error shall be signaled if x is not a
 // number (error-id. domain-error).
 func Floor(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
-	f, _, err := convFloat64(e,x)
+	f, flt, err := convFloat64(e,x)
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewInteger(int(math.Floor(f))), nil
+	if !flt {
+		return x, nil
+	}
+	return integer(e, x, math.Floor(f))
 }
 
 // Ceiling Returns the smallest integer that is not smaller than x. That is, x
 // is truncated towards positive infinity. An error shall be signaled if x is
 // not a number (error-id. domain-error).
 func Ceiling(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
-	f, _, err := convFloat64(e,x)
+	f, flt, err := convFloat64(e,x)
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewInteger(int(math.Ceil(f))), nil
+	if !flt {
+		return x, nil
+	}
+	return integer(e, x, math.Ceil(f))
 }
 
 // Truncate returns the integer between 0 and x (inclusive) that is nearest to
 // x. That is, x is truncated towards zero. An error shall be signaled if x is
 // not a number (error-id. domain-error).
 func Truncate(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
-	f, _, err := convFloat64(e,x)
+	f, flt, err := convFloat64(e,x)
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewInteger(int(math.Trunc(f))), nil
+	if !flt {
+		return x, nil
+	}
+	return integer(e, x, math.Trunc(f))
 }
 
 // Round returns the integer nearest to x. If x is exactly halfway between two
 // integers, the even one is chosen. An error shall be signaled if x is not a
 // number (error-id. domain-error).
 func Round(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
-	f, _, err := convFloat64(e,x)
+	f, flt, err := convFloat64(e,x)
 	if err != nil {
 		return nil, err
 	}
-	return instance.NewInteger(int(math.Floor(f + .5))), nil
+	if !flt {
+		return x, nil
+	}
+	return integer(e, x, math.Floor(f + .5))
 }
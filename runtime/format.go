@@ -28,6 +28,10 @@ func FormatObject(e env.Environment, stream, object, escapep ilos.Instance) (ilo
 		fmt.Fprint(stream.(instance.Stream), string(object.(instance.Character)))
 		return Nil, nil
 	}
+	if symbol, ok := object.(instance.Symbol); ok {
+		fmt.Fprint(stream.(instance.Stream), string(symbol))
+		return Nil, nil
+	}
 	fmt.Fprint(stream.(instance.Stream), object)
 	return Nil, nil
 }
@@ -50,7 +54,7 @@ func FormatFloat(e env.Environment, stream, object ilos.Instance) (ilos.Instance
 	if ok, _ := Floatp(e, object); ok == Nil {
 		return SignalCondition(e, instance.NewDomainError(e, object, class.Float), Nil)
 	}
-	fmt.Fprint(stream.(instance.Stream), float64(object.(instance.Float)))
+	fmt.Fprint(stream.(instance.Stream), object)
 	return Nil, nil
 }
 
@@ -64,14 +68,16 @@ func FormatInteger(e env.Environment, stream, object, radix ilos.Instance) (ilos
 	if ok, _ := Integerp(e, radix); ok == Nil {
 		return SignalCondition(e, instance.NewDomainError(e, radix, class.Integer), Nil)
 	}
-	i := int(object.(instance.Integer))
-	r := int(radix.(instance.Integer))
-	fmt.Fprint(stream.(instance.Stream), strings.ToUpper(strconv.FormatInt(int64(i), r)))
+	r := fixnum(radix)
+	if r < 2 || 36 < r {
+		return SignalCondition(e, instance.NewDomainError(e, radix, class.Integer), Nil)
+	}
+	fmt.Fprint(stream.(instance.Stream), strings.ToUpper(bigInt(object).Text(r)))
 	return Nil, nil
 }
 
 func FormatTab(e env.Environment, stream, num ilos.Instance) (ilos.Instance, ilos.Instance) {
-	n := int(num.(instance.Integer))
+	n := fixnum(num)
 	if *stream.(instance.Stream).Column < n {
 		for i := *stream.(instance.Stream).Column; i < n; i++ {
 			if _, err := FormatChar(e, stream, instance.NewCharacter(' ')); err != nil {
@@ -26,7 +26,12 @@ func TestFormat(t *testing.T) {
 		},
 		{
 			exp:     `(progn (format str "The results are ~S and ~S" 1 #\a) (get-output-stream-string str))`,
-			want:    `"The results are 1 and #\a"`,
+			want:    `"The results are 1 and #\\a"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(progn (format str "~A ~S ~S ~S" '|a b| '|a b| "a\"b" #\tab) (get-output-stream-string str))`,
+			want:    `"a b |a b| \"a\\\"b\" #\\TAB"`,
 			wantErr: false,
 		},
 		{
@@ -55,5 +60,20 @@ func TestFormat(t *testing.T) {
 			want:    `"This is a tilde: ~"`,
 			wantErr: false,
 		},
+		{
+			exp:     `(progn (format-integer str (- (expt 2 70)) 16) (get-output-stream-string str))`,
+			want:    `"-400000000000000000"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(progn (format str "~D ~X" (expt 2 64) (expt 2 64)) (get-output-stream-string str))`,
+			want:    `"18446744073709551616 10000000000000000"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(progn (format str "~G ~S ~A ~G" 1.0 (- 0.0) 1.5e10 (quotient 1 3)) (get-output-stream-string str))`,
+			want:    `"1.0 -0.0 1.5E10 0.3333333333333333"`,
+			wantErr: false,
+		},
 	})
 }
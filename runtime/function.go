@@ -92,6 +92,7 @@ func Labels(e env.Environment, functions ilos.Instance, bodyForm ...ilos.Instanc
 	if err := ensure(e, class.List, functions); err != nil {
 		return nil, err
 	}
+	e.Function = e.Function.Push(nil, nil)
 	for _, function := range functions.(instance.List).Slice() {
 		if err := ensure(e, class.List, function); err != nil {
 			return nil, err
@@ -107,7 +108,7 @@ func Labels(e env.Environment, functions ilos.Instance, bodyForm ...ilos.Instanc
 		if err != nil {
 			return nil, err
 		}
-		if !e.Function.Define(functionName, fun) {
+		if !e.Function.Frame.Define(functionName, fun) {
 			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 		}
 	}
@@ -120,7 +121,8 @@ func Flet(e env.Environment, functions ilos.Instance, bodyForm ...ilos.Instance)
 	if err := ensure(e, class.List, functions); err != nil {
 		return nil, err
 	}
-	newEnv := e.NewLexical()
+	newEnv := e
+	newEnv.Function = e.Function.Push(nil, nil)
 	for _, function := range functions.(instance.List).Slice() {
 		if err := ensure(e, class.List, function); err != nil {
 			return nil, err
@@ -136,7 +138,7 @@ func Flet(e env.Environment, functions ilos.Instance, bodyForm ...ilos.Instance)
 		if err != nil {
 			return nil, err
 		}
-		if !newEnv.Function.Define(functionName, fun) {
+		if !newEnv.Function.Frame.Define(functionName, fun) {
 			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 		}
 	}
@@ -156,7 +158,24 @@ func Apply(e env.Environment, function ilos.Instance, obj ...ilos.Instance) (ilo
 		return nil, err
 	}
 	obj = append(obj[:len(obj)-1], obj[len(obj)-1].(instance.List).Slice()...)
-	return function.(instance.Applicable).Apply(e, obj...)
+	return applyFunction(e, function, obj)
+}
+
+// FunctionArity returns a list of the number of arguments function requires
+// and whether it takes more than those: t if it has a &rest or :rest
+// parameter and nil otherwise. An error shall be signaled if function is not
+// a function (error-id. domain-error). This is an extension of iris.
+func FunctionArity(e env.Environment, function ilos.Instance) (ilos.Instance, ilos.Instance) {
+	f, ok := function.(interface{ Arity() instance.Arity })
+	if !ok {
+		return SignalCondition(e, instance.NewDomainError(e, function, class.Function), Nil)
+	}
+	arity := f.Arity()
+	rest := Nil
+	if arity.Rest {
+		rest = T
+	}
+	return List(e, instance.NewInteger(arity.Required), rest)
 }
 
 // Funcall activates the specified function function and returns the value that
@@ -5,6 +5,7 @@
 package runtime
 
 import (
+	"runtime/debug"
 	"testing"
 )
 
@@ -143,3 +144,86 @@ func TestFuncall(t *testing.T) {
 	}
 	execTests(t, Funcall, tests)
 }
+
+func TestFunctionArity(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(function-arity #'car)`,
+			want:    `'(1 nil)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(function-arity #'+)`,
+			want:    `'(0 t)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(function-arity #'mapcar)`,
+			want:    `'(2 t)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(function-arity (lambda (x y :rest z) x))`,
+			want:    `'(2 t)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(function-arity #'create)`,
+			want:    `'(1 t)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(function-arity 'car)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, FunctionArity, tests)
+}
+
+func TestTailCall(t *testing.T) {
+	// Without proper tail calls these loops would overflow the stack.
+	defer debug.SetMaxStack(debug.SetMaxStack(8 << 20))
+	execTests(t, Defun, []test{
+		{
+			exp: `(defun tail-count (n acc)
+				(cond ((= n 0) acc)
+				      (t (let ((m (- n 1)))
+				           (progn (tail-count m (+ acc 1)))))))`,
+			want:    `'tail-count`,
+			wantErr: false,
+		},
+		{
+			exp:     `(tail-count 10000 0)`,
+			want:    `10000`,
+			wantErr: false,
+		},
+		{
+			exp: `(labels ((ping (n) (if (= n 0) 'done (pong (- n 1))))
+			           (pong (n) (and t (or nil (ping n)))))
+			    (ping 10000))`,
+			want:    `'done`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun tail-fact (n) (if (= n 0) 1 (* n (tail-fact (- n 1)))))`,
+			want:    `'tail-fact`,
+			wantErr: false,
+		},
+		{
+			exp:     `(tail-fact 20)`,
+			want:    `2432902008176640000`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'done (tail-count 10 (throw 'done 'thrown)))`,
+			want:    `'thrown`,
+			wantErr: false,
+		},
+		{
+			exp:     `(tail-count 10 'a)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
@@ -55,3 +55,8 @@ var CatchTag = instance.CatchTagClass
 var TagbodyTag = instance.TagbodyTagClass
 var BlockTag = instance.BlockTagClass
 var Continue = instance.ContinueClass
+var Readtable = instance.ReadtableClass
+var Interrupted = instance.InterruptedClass
+var StepLimitExceeded = instance.StepLimitExceededClass
+var AllocationLimitExceeded = instance.AllocationLimitExceededClass
+var TimeLimitExceeded = instance.TimeLimitExceededClass
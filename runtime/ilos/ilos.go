@@ -4,12 +4,12 @@
 
 package ilos
 
-import (
-	"reflect"
-)
-
+// Class is a class. Each class is made once and compared by identity, so two
+// classes defined alike are still distinct.
 type Class interface {
 	Supers() []Class
+	ClassPrecedenceList() []Class
+	Inherits(super Class) bool
 	Slots() []Instance
 	Initform(Instance) (Instance, bool)
 	Initarg(Instance) (Instance, bool)
@@ -23,29 +23,104 @@ type Instance interface {
 }
 
 func SubclassOf(super, sub Class) bool {
-	var subclassof func(p, c Class) bool
-	subclassof = func(p, c Class) bool {
-		if reflect.DeepEqual(c, p) {
-			return true
+	return sub.Inherits(super)
+}
+
+func InstanceOf(p Class, i Instance) bool {
+	c := i.Class()
+	return c == p || c.Inherits(p)
+}
+
+// Precedence holds the class precedence list of a class and the set of its
+// proper superclasses, which are computed once when the class is made.
+// Classes embed it to implement ClassPrecedenceList and Inherits.
+type Precedence struct {
+	list   []Class
+	supers map[Class]bool
+}
+
+// NewPrecedence computes the class precedence list of c from those of its
+// direct superclasses.
+func NewPrecedence(c Class) Precedence {
+	list := linearize(c)
+	supers := make(map[Class]bool, len(list)-1)
+	for _, s := range list[1:] {
+		supers[s] = true
+	}
+	return Precedence{list, supers}
+}
+
+// ClassPrecedenceList returns the class and its superclasses, each before
+// its own superclasses.
+func (p Precedence) ClassPrecedenceList() []Class {
+	return p.list
+}
+
+// Inherits reports whether super is a proper superclass of the class.
+func (p Precedence) Inherits(super Class) bool {
+	return p.supers[super]
+}
+
+// linearize merges the class precedence lists of the direct superclasses of
+// c so that every class precedes its superclasses, and the direct
+// superclasses keep the order in which they are given. If they cannot, the
+// superclasses are listed depth first from left to right.
+func linearize(c Class) []Class {
+	supers := c.Supers()
+	lists := make([][]Class, 0, len(supers)+1)
+	for _, s := range supers {
+		lists = append(lists, s.ClassPrecedenceList())
+	}
+	lists = append(lists, supers)
+	list := []Class{c}
+	for {
+		var next Class
+		empty := true
+		for _, l := range lists {
+			if len(l) == 0 {
+				continue
+			}
+			empty = false
+			if !inTail(l[0], lists) {
+				next = l[0]
+				break
+			}
 		}
-		for _, d := range c.Supers() {
-			if subclassof(p, d) {
-				return true
+		if empty {
+			return list
+		}
+		if next == nil {
+			return depthFirst(c, []Class{})
+		}
+		list = append(list, next)
+		for i, l := range lists {
+			if len(l) > 0 && l[0] == next {
+				lists[i] = l[1:]
 			}
 		}
-		return false
 	}
-	for _, d := range sub.Supers() {
-		if subclassof(super, d) {
-			return true
+}
+
+func inTail(c Class, lists [][]Class) bool {
+	for _, l := range lists {
+		for i := 1; i < len(l); i++ {
+			if l[i] == c {
+				return true
+			}
 		}
 	}
 	return false
 }
 
-func InstanceOf(p Class, i Instance) bool {
-	if reflect.DeepEqual(i.Class(), p) {
-		return true
+func depthFirst(c Class, list []Class) []Class {
+	for _, d := range list {
+		if d == c {
+			return list
+		}
+	}
+	list = append(list, c)
+	for _, s := range c.Supers() {
+		list = depthFirst(s, list)
 	}
-	return SubclassOf(p, i.Class())
+	return list
 }
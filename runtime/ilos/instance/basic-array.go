@@ -6,6 +6,7 @@ package instance
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/islisp-dev/iris/runtime/ilos"
 )
@@ -81,5 +82,6 @@ func (String) Class() ilos.Class {
 }
 
 func (i String) String() string {
-	return "\"" + string(i) + "\""
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return "\"" + r.Replace(string(i)) + "\""
 }
@@ -14,36 +14,43 @@ type BuiltInClass struct {
 	name   ilos.Instance
 	supers []ilos.Class
 	slots  []ilos.Instance
+	ilos.Precedence
 }
 
 func NewBuiltInClass(name string, super ilos.Class, slots ...string) ilos.Class {
+	return newBuiltInClass(name, []ilos.Class{super}, slots...)
+}
+
+func newBuiltInClass(name string, supers []ilos.Class, slots ...string) *BuiltInClass {
 	slotNames := []ilos.Instance{}
 	for _, slot := range slots {
 		slotNames = append(slotNames, NewSymbol(slot))
 	}
-	return BuiltInClass{NewSymbol(name), []ilos.Class{super}, slotNames}
+	c := &BuiltInClass{name: NewSymbol(name), supers: supers, slots: slotNames}
+	c.Precedence = ilos.NewPrecedence(c)
+	return c
 }
 
-func (p BuiltInClass) Supers() []ilos.Class {
+func (p *BuiltInClass) Supers() []ilos.Class {
 	return p.supers
 }
 
-func (p BuiltInClass) Slots() []ilos.Instance {
+func (p *BuiltInClass) Slots() []ilos.Instance {
 	return p.slots
 }
 
-func (p BuiltInClass) Initform(arg ilos.Instance) (ilos.Instance, bool) {
+func (p *BuiltInClass) Initform(arg ilos.Instance) (ilos.Instance, bool) {
 	return nil, false
 }
 
-func (p BuiltInClass) Initarg(arg ilos.Instance) (ilos.Instance, bool) {
+func (p *BuiltInClass) Initarg(arg ilos.Instance) (ilos.Instance, bool) {
 	return arg, true
 }
 
-func (BuiltInClass) Class() ilos.Class {
+func (*BuiltInClass) Class() ilos.Class {
 	return BuiltInClassClass
 }
 
-func (p BuiltInClass) String() string {
+func (p *BuiltInClass) String() string {
 	return fmt.Sprint(p.name)
 }
@@ -5,6 +5,12 @@
 package instance
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/islisp-dev/iris/runtime/ilos"
 )
 
@@ -20,13 +26,53 @@ func (Character) Class() ilos.Class {
 	return CharacterClass
 }
 
+// characterNames are the names printed for characters which are not graphic.
+var characterNames = map[rune]string{
+	' ':    "SPACE",
+	'\n':   "NEWLINE",
+	'\t':   "TAB",
+	'\r':   "RETURN",
+	'\f':   "PAGE",
+	'\b':   "BACKSPACE",
+	'\a':   "BELL",
+	0x1b:   "ESCAPE",
+	0x7f:   "RUBOUT",
+	0x0000: "NULL",
+}
+
+// characterAliases are names accepted by the reader besides characterNames.
+var characterAliases = map[string]rune{
+	"LINEFEED": '\n',
+	"DELETE":   0x7f,
+	"NUL":      0x0000,
+}
+
+// CharacterByName returns the character named name, ignoring case. The name
+// U+XXXX denotes the character of the hexadecimal code point XXXX.
+func CharacterByName(name string) (rune, bool) {
+	name = strings.ToUpper(name)
+	if r, ok := characterAliases[name]; ok {
+		return r, true
+	}
+	for r, n := range characterNames {
+		if n == name {
+			return r, true
+		}
+	}
+	if strings.HasPrefix(name, "U+") {
+		if n, err := strconv.ParseUint(name[2:], 16, 32); err == nil && utf8.ValidRune(rune(n)) {
+			return rune(n), true
+		}
+	}
+	return 0, false
+}
+
 func (i Character) String() string {
-	switch rune(i) {
-	case ' ':
-		return `#\SPACE`
-	case '\n':
-		return `#\NEWLINE`
-	default:
-		return `#\` + string(i)
+	if n, ok := characterNames[rune(i)]; ok {
+		return `#\` + n
+	}
+	if !unicode.IsGraphic(rune(i)) {
+		return fmt.Sprintf(`#\U+%04X`, rune(i))
 	}
+	return `#\` + string(i)
 }
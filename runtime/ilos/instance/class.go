@@ -8,7 +8,7 @@ import (
 	"github.com/islisp-dev/iris/runtime/ilos"
 )
 
-var ObjectClass = BuiltInClass{NewSymbol("<OBJECT>"), []ilos.Class{}, []ilos.Instance{}}
+var ObjectClass = newBuiltInClass("<OBJECT>", []ilos.Class{})
 var BuiltInClassClass = NewBuiltInClass("<BUILT-IN-CLASS>", ObjectClass)
 var StandardClassClass = NewBuiltInClass("<STANDARD-CLASS>", ObjectClass)
 var BasicArrayClass = NewBuiltInClass("<BASIC-ARRAY>", ObjectClass)
@@ -23,7 +23,7 @@ var GenericFunctionClass = NewBuiltInClass("<GENERIC-FUNCTION>", FunctionClass)
 var StandardGenericFunctionClass = NewBuiltInClass("<STANDARD-GENERIC-FUNCTION>", GenericFunctionClass)
 var ListClass = NewBuiltInClass("<LIST>", ObjectClass)
 var ConsClass = NewBuiltInClass("<CONS>", ListClass)
-var NullClass = BuiltInClass{NewSymbol("<NULL>"), []ilos.Class{ListClass, SymbolClass}, []ilos.Instance{}}
+var NullClass = newBuiltInClass("<NULL>", []ilos.Class{ListClass, SymbolClass})
 var SymbolClass = NewBuiltInClass("<SYMBOL>", ObjectClass)
 var NumberClass = NewBuiltInClass("<NUMBER>", ObjectClass)
 var IntegerClass = NewBuiltInClass("<INTEGER>", NumberClass)
@@ -55,3 +55,8 @@ var CatchTagClass = NewBuiltInClass("<THROW>", EscapeClass, "IRIS.OBJECT")
 var TagbodyTagClass = NewBuiltInClass("<TAGBODY-TAG>", EscapeClass)
 var BlockTagClass = NewBuiltInClass("<BLOCK-TAG>", EscapeClass, "IRIS.OBJECT")
 var ContinueClass = NewBuiltInClass("<CONTINUE>", EscapeClass, "IRIS.OBJECT")
+var ReadtableClass = NewBuiltInClass("<READTABLE>", ObjectClass)
+var InterruptedClass = NewBuiltInClass("<INTERRUPTED>", SeriousConditionClass)
+var StepLimitExceededClass = NewBuiltInClass("<STEP-LIMIT-EXCEEDED>", StorageExhaustedClass)
+var AllocationLimitExceededClass = NewBuiltInClass("<ALLOCATION-LIMIT-EXCEEDED>", StorageExhaustedClass)
+var TimeLimitExceededClass = NewBuiltInClass("<TIME-LIMIT-EXCEEDED>", StorageExhaustedClass)
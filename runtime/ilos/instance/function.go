@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 
 	"github.com/islisp-dev/iris/runtime/env"
 	"github.com/islisp-dev/iris/runtime/ilos"
@@ -17,13 +18,122 @@ type Applicable interface {
 	Apply(env.Environment, ...ilos.Instance) (ilos.Instance, ilos.Instance)
 }
 
+// Func0 to Func3 are builtins which take a fixed number of arguments, and
+// FuncN to Func3N ones which take any number of arguments after their
+// required ones. Function calls them without reflection.
+type (
+	Func0  func(env.Environment) (ilos.Instance, ilos.Instance)
+	Func1  func(env.Environment, ilos.Instance) (ilos.Instance, ilos.Instance)
+	Func2  func(env.Environment, ilos.Instance, ilos.Instance) (ilos.Instance, ilos.Instance)
+	Func3  func(env.Environment, ilos.Instance, ilos.Instance, ilos.Instance) (ilos.Instance, ilos.Instance)
+	FuncN  func(env.Environment, ...ilos.Instance) (ilos.Instance, ilos.Instance)
+	Func1N func(env.Environment, ilos.Instance, ...ilos.Instance) (ilos.Instance, ilos.Instance)
+	Func2N func(env.Environment, ilos.Instance, ilos.Instance, ...ilos.Instance) (ilos.Instance, ilos.Instance)
+	Func3N func(env.Environment, ilos.Instance, ilos.Instance, ilos.Instance, ...ilos.Instance) (ilos.Instance, ilos.Instance)
+)
+
+// Arity is the number of arguments a function takes: Required ones and, if
+// Rest is set, any number more.
+type Arity struct {
+	Required int
+	Rest     bool
+}
+
+// Accepts reports whether a function of arity a may be called with n
+// arguments.
+func (a Arity) Accepts(n int) bool {
+	return n == a.Required || (a.Rest && n > a.Required)
+}
+
 type Function struct {
 	name     ilos.Instance
 	function interface{}
+	arity    Arity
+	tail     bool
 }
 
+// NewFunction returns a function which calls function with the environment
+// and its arguments. A function of one of the signatures of Func0 to Func3N
+// is called directly; any other function is called by reflection.
 func NewFunction(name ilos.Instance, function interface{}) ilos.Instance {
-	return Function{name, function}
+	function, arity := typed(function)
+	return Function{name, function, arity, false}
+}
+
+// NewVariadicFunction returns a function which is reported to take arity
+// arguments but checks their number itself.
+func NewVariadicFunction(name ilos.Instance, arity Arity, function FuncN) ilos.Instance {
+	return Function{name, function, arity, false}
+}
+
+// NewTailFunction returns a function of the given arity which, when applied
+// in an environment whose TailCall is set, may return a pending tail call
+// for the caller to run instead of its value. The function checks the
+// number of its arguments itself.
+func NewTailFunction(name ilos.Instance, arity Arity, function FuncN) ilos.Instance {
+	return Function{name, function, arity, true}
+}
+
+// typed converts function to the type of its signature among Func0 to
+// Func3N, and returns its arity.
+func typed(function interface{}) (interface{}, Arity) {
+	switch f := function.(type) {
+	case func(env.Environment) (ilos.Instance, ilos.Instance):
+		return Func0(f), Arity{0, false}
+	case func(env.Environment, ilos.Instance) (ilos.Instance, ilos.Instance):
+		return Func1(f), Arity{1, false}
+	case func(env.Environment, ilos.Instance, ilos.Instance) (ilos.Instance, ilos.Instance):
+		return Func2(f), Arity{2, false}
+	case func(env.Environment, ilos.Instance, ilos.Instance, ilos.Instance) (ilos.Instance, ilos.Instance):
+		return Func3(f), Arity{3, false}
+	case func(env.Environment, ...ilos.Instance) (ilos.Instance, ilos.Instance):
+		return FuncN(f), Arity{0, true}
+	case func(env.Environment, ilos.Instance, ...ilos.Instance) (ilos.Instance, ilos.Instance):
+		return Func1N(f), Arity{1, true}
+	case func(env.Environment, ilos.Instance, ilos.Instance, ...ilos.Instance) (ilos.Instance, ilos.Instance):
+		return Func2N(f), Arity{2, true}
+	case func(env.Environment, ilos.Instance, ilos.Instance, ilos.Instance, ...ilos.Instance) (ilos.Instance, ilos.Instance):
+		return Func3N(f), Arity{3, true}
+	case Func0:
+		return f, Arity{0, false}
+	case Func1:
+		return f, Arity{1, false}
+	case Func2:
+		return f, Arity{2, false}
+	case Func3:
+		return f, Arity{3, false}
+	case FuncN:
+		return f, Arity{0, true}
+	case Func1N:
+		return f, Arity{1, true}
+	case Func2N:
+		return f, Arity{2, true}
+	case Func3N:
+		return f, Arity{3, true}
+	}
+	ft := reflect.TypeOf(function)
+	if ft == nil || ft.Kind() != reflect.Func || ft.NumIn() == 0 {
+		return function, Arity{}
+	}
+	if ft.IsVariadic() {
+		return function, Arity{ft.NumIn() - 2, true}
+	}
+	return function, Arity{ft.NumIn() - 1, false}
+}
+
+// Name returns the name f was made with.
+func (f Function) Name() ilos.Instance {
+	return f.name
+}
+
+// TailCalls reports whether f may return a pending tail call.
+func (f Function) TailCalls() bool {
+	return f.tail
+}
+
+// Arity returns the number of arguments f takes.
+func (f Function) Arity() Arity {
+	return f.arity
 }
 
 func (Function) Class() ilos.Class {
@@ -35,6 +145,46 @@ func (f Function) String() string {
 }
 
 func (f Function) Apply(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	switch fn := f.function.(type) {
+	case Func0:
+		if len(arguments) == 0 {
+			return fn(e)
+		}
+	case Func1:
+		if len(arguments) == 1 {
+			return fn(e, arguments[0])
+		}
+	case Func2:
+		if len(arguments) == 2 {
+			return fn(e, arguments[0], arguments[1])
+		}
+	case Func3:
+		if len(arguments) == 3 {
+			return fn(e, arguments[0], arguments[1], arguments[2])
+		}
+	case FuncN:
+		return fn(e, arguments...)
+	case Func1N:
+		if len(arguments) >= 1 {
+			return fn(e, arguments[0], arguments[1:]...)
+		}
+	case Func2N:
+		if len(arguments) >= 2 {
+			return fn(e, arguments[0], arguments[1], arguments[2:]...)
+		}
+	case Func3N:
+		if len(arguments) >= 3 {
+			return fn(e, arguments[0], arguments[1], arguments[2], arguments[3:]...)
+		}
+	default:
+		return f.reflect(e, arguments)
+	}
+	return nil, NewArityError(e)
+}
+
+// reflect calls a function of a signature other than those of Func0 to
+// Func3N.
+func (f Function) reflect(e env.Environment, arguments []ilos.Instance) (ilos.Instance, ilos.Instance) {
 	fv := reflect.ValueOf(f.function)
 	ft := reflect.TypeOf(f.function)
 	argv := []reflect.Value{reflect.ValueOf(e)}
@@ -81,7 +231,7 @@ func (f *GenericFunction) AddMethod(qualifier, lambdaList ilos.Instance, classLi
 		}
 	}
 	for i := range f.methods {
-		if f.methods[i].qualifier == qualifier && reflect.DeepEqual(f.methods[i].classList, classList) {
+		if f.methods[i].qualifier == qualifier && sameClasses(f.methods[i].classList, classList) {
 			f.methods[i].function = function.(Function)
 			return true
 		}
@@ -90,6 +240,38 @@ func (f *GenericFunction) AddMethod(qualifier, lambdaList ilos.Instance, classLi
 	return true
 }
 
+// sameClasses reports whether the lists name the same classes in order.
+func sameClasses(a, b []ilos.Class) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Arity returns the number of arguments taken by f, which is given by its
+// lambda list.
+func (f *GenericFunction) Arity() Arity {
+	arity := Arity{}
+	for _, param := range f.lambdaList.(List).Slice() {
+		if param == NewSymbol(":REST") || param == NewSymbol("&REST") {
+			arity.Rest = true
+			break
+		}
+		arity.Required++
+	}
+	return arity
+}
+
+// Name returns the function spec of f.
+func (f *GenericFunction) Name() ilos.Instance {
+	return f.funcSpec
+}
+
 func (f *GenericFunction) Class() ilos.Class {
 	return f.genericFunctionClass
 }
@@ -98,23 +280,33 @@ func (f *GenericFunction) String() string {
 	return fmt.Sprintf("#%v", f.Class())
 }
 
-func (f *GenericFunction) Apply(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	parameters := f.lambdaList.(List).Slice()
-	variadic := false
-	{
-		test := func(i int) bool { return parameters[i] == NewSymbol(":REST") || parameters[i] == NewSymbol("&REST") }
-		if sort.Search(len(parameters), test) < len(parameters) {
-			variadic = true
-		}
+// apply applies the method m of f, which the profile of the calls in e, if
+// there is one, counts as a call of its own.
+func (f *GenericFunction) apply(e env.Environment, m method, arguments []ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if e.Stack == nil || e.Stack.Profile == nil {
+		return m.function.Apply(e, arguments...)
 	}
-	if (variadic && len(parameters)-2 > len(arguments)) || (!variadic && len(parameters) != len(arguments)) {
-		return nil, NewArityError(e)
+	words := []string{}
+	if m.qualifier != nil {
+		words = append(words, m.qualifier.String())
+	}
+	for _, c := range m.classList {
+		words = append(words, c.String())
 	}
+	e.Stack.Profile.Enter(strings.Join(append([]string{f.funcSpec.String()}, words...), "/"), -1)
+	ret, err := m.function.Apply(e, arguments...)
+	e.Stack.Profile.Leave(-1)
+	return ret, err
+}
+
+// applicable returns the methods of f which apply to arguments, sorted by
+// the specificity of their classes and then by their qualifiers.
+func (f *GenericFunction) applicable(arguments []ilos.Instance) []method {
 	methods := []method{}
 	for _, method := range f.methods {
-		matched := true
+		matched := len(method.classList) <= len(arguments)
 		for i, c := range method.classList {
-			if !ilos.InstanceOf(c, arguments[i]) {
+			if !matched || !ilos.InstanceOf(c, arguments[i]) {
 				matched = false
 				break
 			}
@@ -138,7 +330,43 @@ func (f *GenericFunction) Apply(e env.Environment, arguments ...ilos.Instance) (
 		t := map[ilos.Instance]int{around: 4, before: 3, nil: 2, after: 1}
 		return t[methods[a].qualifier] > t[methods[b].qualifier]
 	})
+	return methods
+}
+
+// Methods returns the qualifiers and the classes of the methods of f which
+// apply to arguments, in the order Apply sorts them. A primary method has
+// the nil qualifier.
+func (f *GenericFunction) Methods(arguments ...ilos.Instance) ([]ilos.Instance, [][]ilos.Class) {
+	methods := f.applicable(arguments)
+	qualifiers := make([]ilos.Instance, len(methods))
+	classLists := make([][]ilos.Class, len(methods))
+	for i, method := range methods {
+		qualifiers[i], classLists[i] = method.qualifier, method.classList
+	}
+	return qualifiers, classLists
+}
+
+func (f *GenericFunction) Apply(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	parameters := f.lambdaList.(List).Slice()
+	variadic := false
+	{
+		test := func(i int) bool { return parameters[i] == NewSymbol(":REST") || parameters[i] == NewSymbol("&REST") }
+		if sort.Search(len(parameters), test) < len(parameters) {
+			variadic = true
+		}
+	}
+	if (variadic && len(parameters)-2 > len(arguments)) || (!variadic && len(parameters) != len(arguments)) {
+		return nil, NewArityError(e)
+	}
+	methods := f.applicable(arguments)
+	before := NewSymbol(":BEFORE")
+	around := NewSymbol(":AROUND")
+	after := NewSymbol(":AFTER")
 
+	// The methods find the next method functions in a frame of their own, and
+	// the index of the method being run in a dynamic binding.
+	e.Function = e.Function.Push(nil, nil)
+	e.DynamicVariable = e.DynamicVariable.Push(map[ilos.Instance]ilos.Instance{})
 	nextMethodPisNil := NewFunction(NewSymbol("NEXT-METHOD-P"), func(e env.Environment) (ilos.Instance, ilos.Instance) {
 		return Nil, nil
 	})
@@ -148,25 +376,27 @@ func (f *GenericFunction) Apply(e env.Environment, arguments ...ilos.Instance) (
 	if f.methodCombination == NewSymbol("NIL") {
 		var callNextMethod func(e env.Environment) (ilos.Instance, ilos.Instance) // To Recursive
 		callNextMethod = func(e env.Environment) (ilos.Instance, ilos.Instance) { // CALL-NEXT-METHOD
+			e.Function = e.Function.Push(nil, nil)
+			e.DynamicVariable = e.DynamicVariable.Push(map[ilos.Instance]ilos.Instance{})
 			depth, _ := e.DynamicVariable.Get(NewSymbol("IRIS/DEPTH"))           // Get previous depth
 			index := int(depth.(Integer)) + 1                                    // Get index of next method
 			e.DynamicVariable.Define(NewSymbol("IRIS/DEPTH"), NewInteger(index)) // Set current depth
 			// If Generic Function has no next-mehtods,  NEXT-METHOD-P e function returns nil
-			e.Function.Define(NewSymbol("NEXT-METHOD-P"), NewFunction(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil))
+			e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), NewFunction(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil))
 			if int(depth.(Integer))+1 < len(methods) { // If Generic Function has next method, set these functionss
-				e.Function.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
-				e.Function.Define(NewSymbol("NEXT-METHOD-P"), NewFunction(NewSymbol("NEXT-METHOD-P"), nextMethodPisT))
+				e.Function.Frame.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
+				e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), NewFunction(NewSymbol("NEXT-METHOD-P"), nextMethodPisT))
 			}
-			return methods[index].function.Apply(e, arguments...) // Call next method
+			return f.apply(e, methods[index], arguments) // Call next method
 		}
 		e.DynamicVariable.Define(NewSymbol("IRIS/DEPTH"), NewInteger(0)) // Set current depth
 		// If Generic Function has no next-mehtods,  NEXT-METHOD-P e function returns nil
-		e.Function.Define(NewSymbol("NEXT-METHOD-P"), NewFunction(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil))
+		e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), NewFunction(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil))
 		if 1 < len(methods) { // If Generic Function has next method, set these functionss
-			e.Function.Define(NewSymbol("NEXT-METHOD-P"), NewFunction(NewSymbol("NEXT-METHOD-P"), nextMethodPisT))
-			e.Function.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
+			e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), NewFunction(NewSymbol("NEXT-METHOD-P"), nextMethodPisT))
+			e.Function.Frame.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
 		}
-		return methods[0].function.Apply(e, arguments...) //Call first of method
+		return f.apply(e, methods[0], arguments) //Call first of method
 	}
 	// if f.methodCombination == NewSymbol("STANDARD")
 	{
@@ -176,28 +406,30 @@ func (f *GenericFunction) Apply(e env.Environment, arguments ...ilos.Instance) (
 			// This callNextMethod is called in :around methods
 			var callNextMethod func(e env.Environment) (ilos.Instance, ilos.Instance)
 			callNextMethod = func(e env.Environment) (ilos.Instance, ilos.Instance) {
+				e.Function = e.Function.Push(nil, nil)
+				e.DynamicVariable = e.DynamicVariable.Push(map[ilos.Instance]ilos.Instance{})
 				depth, _ := e.DynamicVariable.Get(NewSymbol("IRIS/DEPTH")) // Get previous depth
 				for index, method := range methods[:int(depth.(Integer))+1] {
 					if method.qualifier == around { // If have :around method
 						e.DynamicVariable.Define(NewSymbol("IRIS/DEPTH"), NewInteger(index)) // Set Current depth
 						// If Generic Function has no next-mehtods,  NEXT-METHOD-P e function returns nil
-						e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
+						e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
 						{ // If Generic Function has next method, set these functionss
 							width := len(methods) - index - 1
 							test := func(i int) bool { return methods[index+i+1].qualifier == nil || methods[index+i+1].qualifier == around }
 							if sort.Search(width, test) < width {
-								e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
-								e.Function.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
+								e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
+								e.Function.Frame.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
 							}
 						}
-						return methods[int(depth.(Integer))].function.Apply(e, arguments...) // Call next method
+						return f.apply(e, methods[int(depth.(Integer))], arguments) // Call next method
 					}
 				}
 				// If has no :around method then,
 				// Do All :before mehtods
 				for _, method := range methods {
 					if method.qualifier == before {
-						if _, err := method.function.Apply(e, arguments...); err != nil {
+						if _, err := f.apply(e, method, arguments); err != nil {
 							return nil, err
 						}
 					}
@@ -206,6 +438,8 @@ func (f *GenericFunction) Apply(e env.Environment, arguments ...ilos.Instance) (
 				// this callNextMethod is called in primary methods
 				var callNextMethod func(e env.Environment) (ilos.Instance, ilos.Instance)
 				callNextMethod = func(e env.Environment) (ilos.Instance, ilos.Instance) {
+					e.Function = e.Function.Push(nil, nil)
+					e.DynamicVariable = e.DynamicVariable.Push(map[ilos.Instance]ilos.Instance{})
 					depth, _ := e.DynamicVariable.Get(NewSymbol("IRIS/DEPTH")) // Get previous depth
 					index := int(depth.(Integer))                              // Convert depth to integer
 					{
@@ -215,16 +449,16 @@ func (f *GenericFunction) Apply(e env.Environment, arguments ...ilos.Instance) (
 						e.DynamicVariable.Define(NewSymbol("IRIS/DEPTH"), NewInteger(index)) // Set current depth
 					}
 					// If Generic Function has no next-mehtods,  NEXT-METHOD-P e function returns nil
-					e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
+					e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
 					{ // If Generic Function has next method, set these functionss
 						width := len(methods) - index - 1
 						test := func(i int) bool { return methods[index+i+1].qualifier == nil }
 						if sort.Search(width, test) < width {
-							e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
-							e.Function.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
+							e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
+							e.Function.Frame.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
 						}
 					}
-					return methods[index].function.Apply(e, arguments...) // Call next method
+					return f.apply(e, methods[index], arguments) // Call next method
 				} // callNextMethod ends here
 				index := 0 // index of the first primary method
 				{          // index != 0 is always true because this function has :around methods
@@ -234,24 +468,24 @@ func (f *GenericFunction) Apply(e env.Environment, arguments ...ilos.Instance) (
 					e.DynamicVariable.Define(NewSymbol("IRIS/DEPTH"), NewInteger(index))
 				}
 				// If Generic Function has no next-mehtods,  NEXT-METHOD-P e function returns nil
-				e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
+				e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
 				{ // If Generic Function has next method, set these functionss
 					test := func(i int) bool { return methods[index+i+1].qualifier == nil }
 					width := len(methods) - index - 1
 					if sort.Search(width, test) < width {
-						e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
-						e.Function.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
+						e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
+						e.Function.Frame.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
 					}
 				}
 				// Do primary methods
-				ret, err := methods[index].function.Apply(e, arguments...)
+				ret, err := f.apply(e, methods[index], arguments)
 				if err != nil {
 					return nil, err
 				}
 				// Do all :after methods
 				for i := len(methods) - 1; i >= 0; i-- {
 					if methods[i].qualifier == after {
-						if _, err := methods[i].function.Apply(e, arguments...); err != nil {
+						if _, err := f.apply(e, methods[i], arguments); err != nil {
 							return nil, err
 						}
 					}
@@ -260,78 +494,75 @@ func (f *GenericFunction) Apply(e env.Environment, arguments ...ilos.Instance) (
 			}
 			e.DynamicVariable.Define(NewSymbol("IRIS/DEPTH"), NewInteger(index)) // Set Current depth
 			// If Generic Function has no next-mehtods,  NEXT-METHOD-P e function returns nil
-			e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
+			e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
 			{ // If Generic Function has next method, set these functionss
 				test := func(i int) bool { return methods[index+i+1].qualifier == nil }
 				width := len(methods) - index - 1
 				if sort.Search(width, test) < width {
-					e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
-					e.Function.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
+					e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
+					e.Function.Frame.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
 				}
 			}
-			return methods[index].function.Apply(e, arguments...)
+			return f.apply(e, methods[index], arguments)
 		}
 	}
 	{ // Function has no :around methods
-		// This callNextMethod is called in primary methods
-		var callNextMethod func(e env.Environment) (ilos.Instance, ilos.Instance)
-		callNextMethod = func(e env.Environment) (ilos.Instance, ilos.Instance) {
-			depth, _ := e.DynamicVariable.Get(NewSymbol("IRIS/DEPTH")) // Get previous depth
-			index := int(depth.(Integer))                              // Convert depth to integer
-			{
-				test := func(i int) bool { return methods[index+i+1].qualifier == nil }
-				width := len(methods) - index - 1
-				index = sort.Search(width, test)
+		// primary returns the index of the first primary method from i on,
+		// or len(methods) if there is none.
+		primary := func(i int) int {
+			for ; i < len(methods); i++ {
+				if methods[i].qualifier == nil {
+					break
+				}
 			}
+			return i
+		}
+		// defineNext binds the next method functions of the primary method
+		// at index.
+		var callNextMethod func(e env.Environment) (ilos.Instance, ilos.Instance)
+		defineNext := func(e env.Environment, index int) {
 			e.DynamicVariable.Define(NewSymbol("IRIS/DEPTH"), NewInteger(index)) // Set Current depth
 			// If Generic Function has no next-mehtods,  NEXT-METHOD-P e function returns nil
-			e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
-			{ // If Generic Function has next method, set these functionss
-				test := func(i int) bool { return methods[index+i+1].qualifier == nil }
-				width := len(methods) - index - 1
-				if sort.Search(width, test) < width {
-					e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
-					e.Function.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
-				}
+			e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
+			if primary(index+1) < len(methods) { // If Generic Function has next method, set these functions
+				e.Function.Frame.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
+				e.Function.Frame.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
 			}
-			return methods[int(depth.(Integer))].function.Apply(e, arguments...)
+		}
+		// This callNextMethod is called in primary methods
+		callNextMethod = func(e env.Environment) (ilos.Instance, ilos.Instance) {
+			e.Function = e.Function.Push(nil, nil)
+			e.DynamicVariable = e.DynamicVariable.Push(map[ilos.Instance]ilos.Instance{})
+			depth, _ := e.DynamicVariable.Get(NewSymbol("IRIS/DEPTH")) // Get previous depth
+			index := primary(int(depth.(Integer)) + 1)                 // Get index of next method
+			defineNext(e, index)
+			return f.apply(e, methods[index], arguments)
 		} // callNextMethod ends here
+		index := primary(0) // index of the first primary method
+		if index == len(methods) {
+			return nil, NewUndefinedFunction(e, f.funcSpec)
+		}
 		// Do All :before mehtods
 		for _, method := range methods {
 			if method.qualifier == before {
-				if _, err := method.function.Apply(e, arguments...); err != nil {
+				if _, err := f.apply(e, method, arguments); err != nil {
 					return nil, err
 				}
 			}
 		}
-		index := 0 // index of the first primary method
-		{
-			test := func(i int) bool { return methods[i].qualifier == nil }
-			width := len(methods)
-			index := sort.Search(width, test)
-			e.DynamicVariable.Define(NewSymbol("IRIS/DEPTH"), NewInteger(index))
-			if index == len(methods) {
-				return nil, NewUndefinedFunction(e, f.funcSpec)
-			}
-		}
-		e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisNil)
-		{ // If Generic Function has next method, set these functions
-			test := func(i int) bool { return methods[index+i+1].qualifier == nil }
-			width := len(methods) - index - 1
-			if sort.Search(width, test) < width {
-				e.Function.Define(NewSymbol("NEXT-METHOD-P"), nextMethodPisT)
-				e.Function.Define(NewSymbol("CALL-NEXT-METHOD"), NewFunction(NewSymbol("CALL-NEXT-METHOD"), callNextMethod))
-			}
+		defineNext(e, index)
+		ret, err := f.apply(e, methods[index], arguments)
+		if err != nil {
+			return nil, err
 		}
-		ret, err := methods[index].function.Apply(e, arguments...)
 		// Do all :after methods
 		for i := len(methods) - 1; i >= 0; i-- {
 			if methods[i].qualifier == after {
-				if _, err := methods[i].function.Apply(e, arguments...); err != nil {
+				if _, err := f.apply(e, methods[i], arguments); err != nil {
 					return nil, err
 				}
 			}
 		}
-		return ret, err
+		return ret, nil
 	}
 }
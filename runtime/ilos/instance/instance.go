@@ -6,7 +6,6 @@ package instance
 
 import (
 	"fmt"
-	"reflect"
 
 	"github.com/islisp-dev/iris/runtime/env"
 	"github.com/islisp-dev/iris/runtime/ilos"
@@ -51,9 +50,15 @@ func InitializeObject(e env.Environment, object ilos.Instance, inits ...ilos.Ins
 
 type slots map[ilos.Instance]ilos.Instance
 
+var backtraceSlot = NewSymbol("IRIS.BACKTRACE")
+
 func (s slots) String() string {
 	str := "{"
 	for k, v := range s {
+		// A backtrace is too long to print with its condition.
+		if k == backtraceSlot {
+			continue
+		}
 		str += fmt.Sprintf(`%v: %v, `, k, v)
 	}
 	if len(str) == 1 {
@@ -73,7 +78,7 @@ func (i Instance) Class() ilos.Class {
 }
 
 func (i Instance) GetSlotValue(key ilos.Instance, class ilos.Class) (ilos.Instance, bool) {
-	if v, ok := i.slots[key]; ok && reflect.DeepEqual(i.class, class) {
+	if v, ok := i.slots[key]; ok && i.class == class {
 		return v, ok
 	}
 	for _, s := range i.supers {
@@ -85,7 +90,7 @@ func (i Instance) GetSlotValue(key ilos.Instance, class ilos.Class) (ilos.Instan
 }
 
 func (i Instance) SetSlotValue(key ilos.Instance, value ilos.Instance, class ilos.Class) bool {
-	if reflect.DeepEqual(i.class, class) {
+	if i.class == class {
 		i.slots[key] = value
 		return true
 	}
@@ -6,6 +6,10 @@ package instance
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
 
 	"github.com/islisp-dev/iris/runtime/ilos"
 )
@@ -25,6 +29,29 @@ func (i Integer) String() string {
 	return fmt.Sprint(int(i))
 }
 
+// BigInteger is an integer which does not fit in Integer.
+
+type BigInteger struct {
+	Int *big.Int
+}
+
+// NewBigInteger returns i as an Integer if it fits, or as a BigInteger
+// otherwise, so that every integer has only one representation.
+func NewBigInteger(i *big.Int) ilos.Instance {
+	if i.IsInt64() && int64(int(i.Int64())) == i.Int64() {
+		return Integer(i.Int64())
+	}
+	return BigInteger{i}
+}
+
+func (BigInteger) Class() ilos.Class {
+	return IntegerClass
+}
+
+func (i BigInteger) String() string {
+	return i.Int.String()
+}
+
 // Float
 
 type Float float64
@@ -37,6 +64,36 @@ func (Float) Class() ilos.Class {
 	return FloatClass
 }
 
+// String returns the shortest representation which reads as i again. It
+// always has a decimal point, so that it never reads as an integer. Numbers
+// outside of [1e-3, 1e7) are written with an exponent as in 1.5E10.
 func (i Float) String() string {
-	return fmt.Sprint(float64(i))
+	f := float64(i)
+	switch {
+	case math.IsNaN(f):
+		return "#<FLOAT NAN>"
+	case math.IsInf(f, 1):
+		return "#<FLOAT +INFINITY>"
+	case math.IsInf(f, -1):
+		return "#<FLOAT -INFINITY>"
+	}
+	if a := math.Abs(f); a == 0 || (1e-3 <= a && a < 1e7) {
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	}
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	j := strings.IndexByte(s, 'e')
+	mantissa, exponent := s[:j], strings.TrimPrefix(s[j+1:], "+")
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	if strings.HasPrefix(exponent, "-0") {
+		exponent = "-" + strings.TrimLeft(exponent[1:], "0")
+	} else {
+		exponent = strings.TrimLeft(exponent, "0")
+	}
+	return mantissa + "E" + exponent
 }
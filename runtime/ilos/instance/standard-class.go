@@ -18,34 +18,37 @@ type StandardClass struct {
 	initargs  map[ilos.Instance]ilos.Instance
 	metaclass ilos.Class
 	abstractp ilos.Instance
+	ilos.Precedence
 }
 
 func NewStandardClass(name ilos.Instance, supers []ilos.Class, slots []ilos.Instance, initforms, initargs map[ilos.Instance]ilos.Instance, metaclass ilos.Class, abstractp ilos.Instance) ilos.Class {
-	return StandardClass{name, supers, slots, initforms, initargs, metaclass, abstractp}
+	c := &StandardClass{name, supers, slots, initforms, initargs, metaclass, abstractp, ilos.Precedence{}}
+	c.Precedence = ilos.NewPrecedence(c)
+	return c
 }
 
-func (p StandardClass) Supers() []ilos.Class {
+func (p *StandardClass) Supers() []ilos.Class {
 	return p.supers
 }
 
-func (p StandardClass) Slots() []ilos.Instance {
+func (p *StandardClass) Slots() []ilos.Instance {
 	return p.slots
 }
 
-func (p StandardClass) Initform(arg ilos.Instance) (ilos.Instance, bool) {
+func (p *StandardClass) Initform(arg ilos.Instance) (ilos.Instance, bool) {
 	v, ok := p.initforms[arg]
 	return v, ok
 }
 
-func (p StandardClass) Initarg(arg ilos.Instance) (ilos.Instance, bool) {
+func (p *StandardClass) Initarg(arg ilos.Instance) (ilos.Instance, bool) {
 	v, ok := p.initargs[arg]
 	return v, ok
 }
 
-func (p StandardClass) Class() ilos.Class {
+func (p *StandardClass) Class() ilos.Class {
 	return p.metaclass
 }
 
-func (p StandardClass) String() string {
+func (p *StandardClass) String() string {
 	return fmt.Sprint(p.name)
 }
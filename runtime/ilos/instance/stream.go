@@ -27,10 +27,13 @@ type Stream struct {
 	ElementClass ilos.Instance
 	*tokenizer.Reader
 	*BufferedWriter
+	// Readtable is the readtable selected for the stream, or nil if it
+	// reads with the current one.
+	Readtable *ilos.Instance
 }
 
 func NewStream(r io.Reader, w io.Writer, e ilos.Instance) ilos.Instance {
-	return Stream{new(int), e, tokenizer.NewReader(r), NewBufferedWriter(w)}
+	return Stream{new(int), e, tokenizer.NewReader(r), NewBufferedWriter(w), new(ilos.Instance)}
 }
 
 func (Stream) Class() ilos.Class {
@@ -5,6 +5,9 @@
 package instance
 
 import (
+	"regexp"
+	"strings"
+
 	"github.com/islisp-dev/iris/runtime/ilos"
 )
 
@@ -20,8 +23,16 @@ func (Symbol) Class() ilos.Class {
 	return SymbolClass
 }
 
+// plainSymbol matches the names which the reader reads as the same symbol
+// without |...|.
+var plainSymbol = regexp.MustCompile(`^(?:[:&][A-Z]+|\+|-|1\+|1-|[A-Z<>/*=?_!$%[\]^{}~][-A-Z0-9+<>/*=?_!$%[\]^{}~.]*)$`)
+
 func (i Symbol) String() string {
-	return string(i)
+	if plainSymbol.MatchString(string(i)) {
+		return string(i)
+	}
+	r := strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	return "|" + r.Replace(string(i)) + "|"
 }
 
 var T = NewSymbol("T")
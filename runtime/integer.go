@@ -5,7 +5,7 @@
 package runtime
 
 import (
-	"math"
+	"math/big"
 
 	"github.com/islisp-dev/iris/runtime/env"
 	"github.com/islisp-dev/iris/runtime/ilos"
@@ -13,11 +13,49 @@ import (
 	"github.com/islisp-dev/iris/runtime/ilos/instance"
 )
 
-func convInt(e env.Environment, z ilos.Instance) (int, ilos.Instance) {
-	if err := ensure(e, class.Integer, z); err != nil {
+const (
+	maxInt = int(^uint(0) >> 1)
+	minInt = -maxInt - 1
+)
+
+// bigInt returns the integer z as a big.Int, which must not be modified.
+func bigInt(z ilos.Instance) *big.Int {
+	if b, ok := z.(instance.BigInteger); ok {
+		return b.Int
+	}
+	return big.NewInt(int64(z.(instance.Integer)))
+}
+
+// fixnum returns the integer z as an int. A bignum saturates to the
+// nearest int, so that it falls outside of every valid index and size.
+func fixnum(z ilos.Instance) int {
+	if b, ok := z.(instance.BigInteger); ok {
+		if b.Int.Sign() < 0 {
+			return minInt
+		}
+		return maxInt
+	}
+	return int(z.(instance.Integer))
+}
+
+// maxLength is the length of the longest string, vector or list which may
+// be made, and the most elements which an array may have. Longer ones signal
+// <storage-exhausted> rather than exhausting the memory of the process.
+const maxLength = 1<<31 - 1
+
+// sequenceLength returns i as the length of a sequence to be made. It signals
+// a domain-error if i is not a non-negative integer, and <storage-exhausted>
+// if i is longer than maxLength.
+func sequenceLength(e env.Environment, i ilos.Instance) (int, ilos.Instance) {
+	if !ilos.InstanceOf(class.Integer, i) || fixnum(i) < 0 {
+		_, err := SignalCondition(e, instance.NewDomainError(e, i, class.Integer), Nil)
 		return 0, err
 	}
-	return int(z.(instance.Integer)), nil
+	if fixnum(i) > maxLength {
+		_, err := SignalCondition(e, instance.Create(e, class.StorageExhausted), Nil)
+		return 0, err
+	}
+	return fixnum(i), nil
 }
 
 // Integerp returns t if obj is an integer (instance of class integer);
@@ -32,15 +70,10 @@ func Integerp(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instanc
 // Div returns the greatest integer less than or equal to the quotient of z1 and
 // z2. An error shall be signaled if z2 is zero (error-id. division-by-zero).
 func Div(e env.Environment, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	a, err := convInt(e, z1)
-	if err != nil {
-		return nil, err
-	}
-	b, err := convInt(e, z2)
-	if err != nil {
+	if err := ensure(e, class.Integer, z1, z2); err != nil {
 		return nil, err
 	}
-	if b == 0 {
+	if z2 == instance.NewInteger(0) {
 		operation := instance.NewSymbol("DIV")
 		operands, err := List(e, z1, z2)
 		if err != nil {
@@ -48,10 +81,20 @@ func Div(e env.Environment, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance)
 		}
 		return SignalCondition(e, instance.NewArithmeticError(e, operation, operands), Nil)
 	}
-	if a*b < 0 { // Issue #2
-		return instance.NewInteger(a/b - 1), nil
+	a, aok := z1.(instance.Integer)
+	b, bok := z2.(instance.Integer)
+	if aok && bok && !(a == instance.Integer(minInt) && b == -1) {
+		q := a / b
+		if a%b != 0 && (a < 0) != (b < 0) { // Issue #2
+			q--
+		}
+		return q, nil
+	}
+	q, m := new(big.Int).QuoRem(bigInt(z1), bigInt(z2), new(big.Int))
+	if m.Sign() != 0 && m.Sign() != bigInt(z2).Sign() {
+		q.Sub(q, big.NewInt(1))
 	}
-	return instance.NewInteger(a / b), nil
+	return instance.NewBigInteger(q), nil
 }
 
 // Mod returns the remainder of the integer division of z1 by z2. The sign of
@@ -77,54 +120,45 @@ func Mod(e env.Environment, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance)
 // error shall be signaled if either z1 or z2 is not an integer (error-id.
 // domain-error).
 func Gcd(e env.Environment, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	gcd := func(x, y int) int {
-		for y != 0 {
-			x, y = y, x%y
-		}
-		return x
-	}
-	a, err := convInt(e, z1)
-	if err != nil {
+	if err := ensure(e, class.Integer, z1, z2); err != nil {
 		return nil, err
 	}
-	b, err := convInt(e, z2)
-	if err != nil {
-		return nil, err
+	a := new(big.Int).Abs(bigInt(z1))
+	b := new(big.Int).Abs(bigInt(z2))
+	for b.Sign() != 0 {
+		a, b = b, a.Rem(a, b)
 	}
-	return instance.NewInteger(gcd(a, b)), nil
+	return instance.NewBigInteger(a), nil
 }
 
 // Lcm returns the least common multiple of its integer arguments. An error
 // shall be signaled if either z1 or z2 is not an integer (error-id.
 // domain-error).
 func Lcm(e env.Environment, z1, z2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	gcd := func(x, y int) int {
-		for y != 0 {
-			x, y = y, x%y
-		}
-		return x
-	}
-	a, err := convInt(e, z1)
+	gcd, err := Gcd(e, z1, z2)
 	if err != nil {
 		return nil, err
 	}
-	b, err := convInt(e, z2)
-	if err != nil {
+	if gcd == instance.NewInteger(0) {
+		return gcd, nil
+	}
+	if err := allocate(e, class.Integer, bigInt(z1).BitLen()+bigInt(z2).BitLen()); err != nil {
 		return nil, err
 	}
-	return instance.NewInteger(a * b / gcd(a, b)), nil
+	l := new(big.Int).Mul(bigInt(z1), bigInt(z2))
+	l.Abs(l).Quo(l, bigInt(gcd))
+	return instance.NewBigInteger(l), nil
 }
 
 // Isqrt Returns the greatest integer less than or equal to the exact positive
 // square root of z . An error shall be signaled if z is not a non-negative
 // integer (error-id. domain-error).
 func Isqrt(e env.Environment, z ilos.Instance) (ilos.Instance, ilos.Instance) {
-	a, err := convInt(e, z)
-	if err != nil {
+	if err := ensure(e, class.Integer, z); err != nil {
 		return nil, err
 	}
-	if a < 0 {
+	if bigInt(z).Sign() < 0 {
 		return SignalCondition(e, instance.NewDomainError(e, z, class.Number), Nil)
 	}
-	return instance.NewInteger(int(math.Sqrt(float64(a)))), nil
+	return instance.NewBigInteger(new(big.Int).Sqrt(bigInt(z))), nil
 }
@@ -26,6 +26,9 @@ func While(e env.Environment, testForm ilos.Instance, bodyForm ...ilos.Instance)
 		if err != nil {
 			return nil, err
 		}
+		if err := step(e); err != nil {
+			return nil, err
+		}
 		test, err = Eval(e, testForm)
 		if err != nil {
 			return nil, err
@@ -57,7 +60,8 @@ func For(e env.Environment, iterationSpecs, endTestAndResults ilos.Instance, for
 	if err := ensure(e, class.List, iterationSpecs); err != nil {
 		return nil, err
 	}
-	a := e.NewLexical()
+	a := e
+	a.Variable = e.Variable.Push(nil, nil)
 	for _, is := range iterationSpecs.(instance.List).Slice() {
 		if err := ensure(e, class.List, is); err != nil {
 			return nil, err
@@ -70,7 +74,7 @@ func For(e env.Environment, iterationSpecs, endTestAndResults ilos.Instance, for
 			if err != nil {
 				return nil, err
 			}
-			if !a.Variable.Define(var1, init) {
+			if !a.Variable.Frame.Define(var1, init) {
 				return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 			}
 		default:
@@ -95,7 +99,11 @@ func For(e env.Environment, iterationSpecs, endTestAndResults ilos.Instance, for
 		if err != nil {
 			return nil, err
 		}
-		b := a.NewLexical()
+		if err := step(e); err != nil {
+			return nil, err
+		}
+		b := a
+		b.Variable = a.Variable.Push(nil, nil)
 		for _, is := range iterationSpecs.(instance.List).Slice() {
 			if err := ensure(e, class.List, is); err != nil {
 				return nil, err
@@ -108,7 +116,7 @@ func For(e env.Environment, iterationSpecs, endTestAndResults ilos.Instance, for
 				if err != nil {
 					return nil, err
 				}
-				if !b.Variable.Define(var1, step) {
+				if !b.Variable.Frame.Define(var1, step) {
 					return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 				}
 			default:
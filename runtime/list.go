@@ -27,8 +27,9 @@ func Listp(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance)
 // shall be signaled if i is not a non-negative integer (error-id.
 // domain-error).initial-element may be any ISLISP object.
 func CreateList(e env.Environment, i ilos.Instance, initialElement ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if ok, _ := Integerp(e, i); ok == Nil {
-		return nil, instance.NewDomainError(e, i, class.Integer)
+	n, err := sequenceLength(e, i)
+	if err != nil {
+		return nil, err
 	}
 	if len(initialElement) > 1 {
 		return SignalCondition(e, instance.NewArityError(e), Nil)
@@ -37,8 +38,11 @@ func CreateList(e env.Environment, i ilos.Instance, initialElement ...ilos.Insta
 	if len(initialElement) == 1 {
 		elm = initialElement[0]
 	}
+	if err := allocate(e, class.Cons, n); err != nil {
+		return nil, err
+	}
 	cons := Nil
-	for j := 0; j < int(i.(instance.Integer)); j++ {
+	for j := 0; j < n; j++ {
 		cons = instance.NewCons(elm, cons)
 	}
 	return cons, nil
@@ -49,6 +53,9 @@ func CreateList(e env.Environment, i ilos.Instance, initialElement ...ilos.Insta
 // shall be signaled if the requested list cannot be allocated (error-id.
 // cannot-create-list). Each obj may be any ISLISP object.
 func List(e env.Environment, objs ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := allocate(e, class.Cons, len(objs)); err != nil {
+		return nil, err
+	}
 	cons := Nil
 	for i := len(objs) - 1; i >= 0; i-- {
 		cons = instance.NewCons(objs[i], cons)
@@ -65,8 +72,12 @@ func Reverse(e env.Environment, list ilos.Instance) (ilos.Instance, ilos.Instanc
 	if ok, _ := Listp(e, list); ok == Nil {
 		return nil, instance.NewDomainError(e, list, class.List)
 	}
+	elements := list.(instance.List).Slice()
+	if err := allocate(e, class.Cons, len(elements)); err != nil {
+		return nil, err
+	}
 	cons := Nil
-	for _, car := range list.(instance.List).Slice() {
+	for _, car := range elements {
 		cons = instance.NewCons(car, cons)
 	}
 	return cons, nil
@@ -82,8 +93,12 @@ func Nreverse(e env.Environment, list ilos.Instance) (ilos.Instance, ilos.Instan
 	if ok, _ := Listp(e, list); ok == Nil {
 		return nil, instance.NewDomainError(e, list, class.List)
 	}
+	elements := list.(instance.List).Slice()
+	if err := allocate(e, class.Cons, len(elements)); err != nil {
+		return nil, err
+	}
 	cons := Nil
-	for _, car := range list.(instance.List).Slice() {
+	for _, car := range elements {
 		cons = instance.NewCons(car, cons)
 	}
 	return cons, nil
@@ -129,7 +144,10 @@ func Member(e env.Environment, obj, list ilos.Instance) (ilos.Instance, ilos.Ins
 	if ok, _ := Listp(e, list); ok == Nil {
 		return nil, instance.NewDomainError(e, list, class.List)
 	}
-	if !ilos.InstanceOf(class.Cons, list) || list.(*instance.Cons).Car == obj {
+	if !ilos.InstanceOf(class.Cons, list) {
+		return list, nil
+	}
+	if ok, _ := Eql(e, list.(*instance.Cons).Car, obj); ok == T {
 		return list, nil
 	}
 	if !ilos.InstanceOf(class.Cons, list.(*instance.Cons).Cdr) {
@@ -162,7 +180,7 @@ func Mapcar(e env.Environment, function, list1 ilos.Instance, lists ...ilos.Inst
 		arguments = append(arguments, list.(*instance.Cons).Car)
 		rests = append(rests, list.(*instance.Cons).Cdr)
 	}
-	car, err := function.(instance.Applicable).Apply(e.NewDynamic(), arguments...)
+	car, err := applyFunction(e.NewDynamic(), function, arguments)
 	if err != nil {
 		return nil, err
 	}
@@ -225,7 +243,7 @@ func Maplist(e env.Environment, function, list1 ilos.Instance, lists ...ilos.Ins
 		arguments = append(arguments, list)
 		rests = append(rests, list.(*instance.Cons).Cdr)
 	}
-	car, err := function.(instance.Applicable).Apply(e.NewDynamic(), arguments...)
+	car, err := applyFunction(e.NewDynamic(), function, arguments)
 	if err != nil {
 		return nil, err
 	}
@@ -282,7 +300,7 @@ func Assoc(e env.Environment, obj, associationList ilos.Instance) (ilos.Instance
 	if ok, _ := Consp(e, car); ok == Nil {
 		return nil, instance.NewDomainError(e, car, class.Cons)
 	}
-	if car.(*instance.Cons).Car == obj { // eql
+	if ok, _ := Eql(e, car.(*instance.Cons).Car, obj); ok == T {
 		return car, nil
 	}
 	return Assoc(e, obj, cdr)
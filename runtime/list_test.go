@@ -78,6 +78,21 @@ func TestCreateList(t *testing.T) {
 			want:    `'(#\a #\a)`,
 			wantErr: false,
 		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (instancep c (class <storage-exhausted>)))) (create-list 100000000000000000000)))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (instancep c (class <storage-exhausted>)))) (create-list 4294967296)))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(create-list -1)`,
+			want:    `nil`,
+			wantErr: true,
+		},
 	})
 }
 
@@ -152,6 +167,11 @@ func TestMember(t *testing.T) {
 			want:    `'(c a b c)`,
 			wantErr: false,
 		},
+		{
+			exp:     `(member 100000000000000000000 '(1 100000000000000000000 2))`,
+			want:    `'(100000000000000000000 2)`,
+			wantErr: false,
+		},
 	})
 }
 
@@ -252,5 +272,10 @@ func TestAssoc(t *testing.T) {
 			want:    `nil`,
 			wantErr: false,
 		},
+		{
+			exp:     `(assoc 100000000000000000000 '((1 . a) (100000000000000000000 . b)))`,
+			want:    `'(100000000000000000000 . b)`,
+			wantErr: false,
+		},
 	})
 }
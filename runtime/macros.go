@@ -24,7 +24,8 @@ func Defmacro(e env.Environment, macroName, lambdaList ilos.Instance, forms ...i
 	if err != nil {
 		return nil, err
 	}
-	e.Macro[:1].Define(macroName, ret)
+	e.Macro.Define(macroName, ret)
+	*e.Macros++
 	return macroName, nil
 }
 
@@ -42,10 +43,12 @@ func Defmacro(e env.Environment, macroName, lambdaList ilos.Instance, forms ...i
 // by one inside each successive quasiquotation and decreases by one inside each
 // unquotation.
 func Quasiquote(e env.Environment, form ilos.Instance) (ilos.Instance, ilos.Instance) {
-	return expand(e, form, 0)
+	return expand(e, form, 0, Eval)
 }
 
-func expand(e env.Environment, form ilos.Instance, level int) (ilos.Instance, ilos.Instance) {
+// expand fills in the template form, evaluating the forms unquoted at level
+// zero with eval.
+func expand(e env.Environment, form ilos.Instance, level int, eval func(env.Environment, ilos.Instance) (ilos.Instance, ilos.Instance)) (ilos.Instance, ilos.Instance) {
 	if !ilos.InstanceOf(class.Cons, form) {
 		return form, nil
 	} // If form is a instance of <cons> then,
@@ -57,7 +60,7 @@ func expand(e env.Environment, form ilos.Instance, level int) (ilos.Instance, il
 		// To expand `((foo ,(- 10 3)) ,@(cdr '(c)) . ,(car '(cons)))
 		if cadr == instance.NewSymbol("UNQUOTE") && level == 0 {
 			caddr := cddr.(*instance.Cons).Car
-			elt, err := Eval(e, caddr)
+			elt, err := eval(e, caddr)
 			if err != nil {
 				return nil, err
 			}
@@ -79,7 +82,7 @@ func expand(e env.Environment, form ilos.Instance, level int) (ilos.Instance, il
 			cadadr := cdadr.(*instance.Cons).Car
 			var elt, err ilos.Instance
 			if level == 0 {
-				elt, err = Eval(e, cadadr)
+				elt, err = eval(e, cadadr)
 				if err != nil {
 					return nil, err
 				}
@@ -91,7 +94,7 @@ func expand(e env.Environment, form ilos.Instance, level int) (ilos.Instance, il
 				cdr = cdr.(*instance.Cons).Cdr
 				continue
 			} else {
-				elt, err = expand(e, cadadr, level-1)
+				elt, err = expand(e, cadadr, level-1, eval)
 				if err != nil {
 					return nil, err
 				}
@@ -111,7 +114,7 @@ func expand(e env.Environment, form ilos.Instance, level int) (ilos.Instance, il
 		if caadr == instance.NewSymbol("UNQUOTE-SPLICING") {
 			cadadr := cdadr.(*instance.Cons).Car
 			if level == 0 {
-				elt, err := Eval(e, cadadr)
+				elt, err := eval(e, cadadr)
 				if err != nil {
 					return nil, err
 				}
@@ -119,7 +122,7 @@ func expand(e env.Environment, form ilos.Instance, level int) (ilos.Instance, il
 				cdr = cdr.(*instance.Cons).Cdr
 				continue
 			} else {
-				elt, err := expand(e, cadadr, level-1)
+				elt, err := expand(e, cadadr, level-1, eval)
 				if err != nil {
 					return nil, err
 				}
@@ -138,7 +141,7 @@ func expand(e env.Environment, form ilos.Instance, level int) (ilos.Instance, il
 		}
 		if caadr == instance.NewSymbol("QUASIQUOTE") {
 			cadadr := cdadr.(*instance.Cons).Car
-			elt, err := expand(e, cadadr, level+1)
+			elt, err := expand(e, cadadr, level+1, eval)
 			if err != nil {
 				return nil, err
 			}
@@ -155,7 +158,7 @@ func expand(e env.Environment, form ilos.Instance, level int) (ilos.Instance, il
 			continue
 		}
 		// If the cadr is not special forms then,
-		elt, err := expand(e, cadr, level)
+		elt, err := expand(e, cadr, level, eval)
 		if err != nil {
 			return nil, err
 		}
@@ -34,37 +34,78 @@ func newNamedFunction(e env.Environment, functionName, lambdaList ilos.Instance,
 	if err := checkLambdaList(e, lambdaList); err != nil {
 		return nil, err
 	}
-	parameters := []ilos.Instance{}
-	variadic := false
-	for _, cadr := range lambdaList.(instance.List).Slice() {
-		if cadr == instance.NewSymbol(":REST") || cadr == instance.NewSymbol("&REST") {
-			variadic = true
-		}
-		parameters = append(parameters, cadr)
+	parameters, variables, variadic, _ := lambdaParameters(lambdaList)
+	body := compileBody(e, (*scope)(nil).with(variables...), forms, true)
+	return newClosure(lexical, functionName, parameters, variadic, body), nil
+}
+
+// newMethod makes the function of a method. Its body sees the next method
+// functions as well, which the generic function binds in the innermost frame
+// of the function namespace it applies the method in.
+func newMethod(e env.Environment, functionName, lambdaList ilos.Instance, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	lexical := e
+	if err := ensure(e, class.Symbol, functionName); err != nil {
+		return nil, err
+	}
+	if err := checkLambdaList(e, lambdaList); err != nil {
+		return nil, err
 	}
-	return instance.NewFunction(functionName.(instance.Symbol), func(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-		e.MergeLexical(lexical)
-		if (variadic && len(parameters)-2 > len(arguments)) || (!variadic && len(parameters) != len(arguments)) {
+	parameters, variables, variadic, _ := lambdaParameters(lambdaList)
+	body := compileBody(e, (*scope)(nil).with(variables...), forms, true)
+	arity := instance.Arity{Required: len(variables), Rest: variadic}
+	if variadic {
+		arity.Required--
+	}
+	return instance.NewTailFunction(functionName, arity, func(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+		next := e.Function.Frame
+		method := lexical
+		method.Function = lexical.Function.Push(next.Names, next.Values)
+		return newClosure(method, functionName, parameters, variadic, body).(instance.Applicable).Apply(e, arguments...)
+	}), nil
+}
+
+// newClosure makes a function which runs body with the parameters bound over
+// the lexical environment it was made in. The parameters are bound in one
+// frame, in the order of the variables returned by lambdaParameters.
+func newClosure(lexical env.Environment, functionName ilos.Instance, parameters []ilos.Instance, variadic bool, body code) ilos.Instance {
+	arity := instance.Arity{Required: len(parameters), Rest: variadic}
+	variables := parameters
+	if variadic {
+		arity.Required -= 2
+		variables = append(parameters[:arity.Required:arity.Required], parameters[arity.Required+1])
+	}
+	immutable := duplicate(variables)
+	return instance.NewTailFunction(functionName, arity, func(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+		tail := e.TailCall
+		e.TailCall = false
+		dynamic := e
+		e.BlockTag = lexical.BlockTag
+		e.TagbodyTag = lexical.TagbodyTag
+		e.Function = lexical.Function
+		e.Variable = lexical.Variable
+		if !arity.Accepts(len(arguments)) {
 			return SignalCondition(e, instance.NewArityError(e), Nil)
 		}
-		for idx := range parameters {
-			key := parameters[idx]
-			if key == instance.NewSymbol(":REST") || key == instance.NewSymbol("&REST") {
-				key := parameters[idx+1]
-				value, err := List(e, arguments[idx:]...)
-				if err != nil {
-					return nil, err
-				}
-				if !e.Variable.Define(key, value) {
-					return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
-				}
-				break
-			}
-			value := arguments[idx]
-			if !e.Variable.Define(key, value) {
-				return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
+		if immutable {
+			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
+		}
+		values := make([]ilos.Instance, len(variables))
+		copy(values, arguments[:arity.Required])
+		if variadic {
+			rest, err := List(e, arguments[arity.Required:]...)
+			if err != nil {
+				return nil, err
 			}
+			values[arity.Required] = rest
 		}
-		return Progn(e, forms...)
-	}), nil
+		e.Variable = e.Variable.Push(variables, values)
+		// The body runs calls in tail position through the trampoline, here
+		// or in the caller that asked for them.
+		e.TailCall = true
+		if tail {
+			return body(e)
+		}
+		ret, err := body(e)
+		return trampoline(dynamic, ret, err)
+	})
 }
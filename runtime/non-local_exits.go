@@ -43,6 +43,7 @@ func Block(e env.Environment, tag ilos.Instance, body ...ilos.Instance) (ilos.In
 	if ilos.InstanceOf(class.Number, tag) || ilos.InstanceOf(class.Character, tag) {
 		return SignalCondition(e, instance.NewDomainError(e, tag, class.Object), Nil)
 	}
+	e.BlockTag = e.BlockTag.Push(map[ilos.Instance]ilos.Instance{})
 	if !e.BlockTag.Define(tag, uid) {
 		return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 	}
@@ -93,6 +94,7 @@ func Catch(e env.Environment, tag ilos.Instance, body ...ilos.Instance) (ilos.In
 	if ilos.InstanceOf(class.Number, tag) || ilos.InstanceOf(class.Character, tag) {
 		return SignalCondition(e, instance.NewDomainError(e, tag, class.Object), Nil)
 	}
+	e.CatchTag = e.CatchTag.Push(map[ilos.Instance]ilos.Instance{})
 	if !e.CatchTag.Define(tag, uid) {
 		return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 	}
@@ -141,6 +143,7 @@ func Throw(e env.Environment, tag, object ilos.Instance) (ilos.Instance, ilos.In
 
 func Tagbody(e env.Environment, body ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	uid := instance.NewInteger(uniqueInt())
+	e.TagbodyTag = e.TagbodyTag.Push(map[ilos.Instance]ilos.Instance{})
 	for _, cadr := range body {
 		if !ilos.InstanceOf(class.Cons, cadr) {
 			if !e.TagbodyTag.Define(cadr, uid) { // ref cddr
@@ -164,6 +167,9 @@ func Tagbody(e env.Environment, body ...ilos.Instance) (ilos.Instance, ilos.Inst
 						}
 					}
 					if found {
+						if err := step(e); err != nil {
+							return nil, err
+						}
 						for _, form := range body[idx+1:] {
 							if ilos.InstanceOf(class.Cons, form) {
 								_, fail = Eval(e, form)
@@ -212,7 +218,7 @@ func Go(e env.Environment, tag ilos.Instance) (ilos.Instance, ilos.Instance) {
 // necessary and would respect these cleanup-forms.
 func UnwindProtect(e env.Environment, form ilos.Instance, cleanupForms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	ret1, err1 := Eval(e, form)
-	ret2, err2 := Progn(e, cleanupForms...)
+	ret2, err2 := Progn(uninterrupted(e, err1), cleanupForms...)
 	if err2 != nil {
 		if ilos.InstanceOf(class.Escape, err2) {
 			return SignalCondition(e, instance.NewControlError(e), Nil)
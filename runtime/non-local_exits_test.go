@@ -117,6 +117,42 @@ func TestCatch(t *testing.T) {
 	execTests(t, Catch, tests)
 }
 
+func TestTagbody(t *testing.T) {
+	tests := []test{
+		{
+			exp: `
+				(let ((n 0))
+				  (tagbody
+				    top
+				    (setq n (+ n 1))
+				    (if (< n 5) (go top)))
+				  n)
+			`,
+			want:    `5`,
+			wantErr: false,
+		},
+		{
+			exp: `
+				(let ((l nil))
+				  (tagbody
+				    (go b)
+				    a (setq l (cons 'a l)) (go c)
+				    b (setq l (cons 'b l)) (go a)
+				    c)
+				  l)
+			`,
+			want:    `'(a b)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(tagbody (go a))`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, Tagbody, tests)
+}
+
 func TestUnwindProtect(t *testing.T) {
 	tests := []test{
 		{
@@ -6,6 +6,7 @@ package runtime
 
 import (
 	"math"
+	"math/big"
 
 	"github.com/islisp-dev/iris/reader/parser"
 	"github.com/islisp-dev/iris/runtime/env"
@@ -39,6 +40,106 @@ func ParseNumber(e env.Environment, str ilos.Instance) (ilos.Instance, ilos.Inst
 	return ret, err
 }
 
+// float returns the number x as a float64.
+func float(x ilos.Instance) float64 {
+	switch x := x.(type) {
+	case instance.Integer:
+		return float64(x)
+	case instance.BigInteger:
+		f, _ := new(big.Float).SetInt(x.Int).Float64()
+		return f
+	}
+	return float64(x.(instance.Float))
+}
+
+// compare returns -1, 0 or 1 as the number x1 is less than, equal to or
+// greater than x2. It returns false if they are unordered, that is, one of
+// them is NaN.
+func compare(x1, x2 ilos.Instance) (int, bool) {
+	a, aok := x1.(instance.Integer)
+	b, bok := x2.(instance.Integer)
+	switch {
+	case aok && bok:
+		if a < b {
+			return -1, true
+		}
+		if a > b {
+			return 1, true
+		}
+		return 0, true
+	case ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2):
+		return bigInt(x1).Cmp(bigInt(x2)), true
+	}
+	f1, f2 := float(x1), float(x2)
+	if math.IsNaN(f1) || math.IsNaN(f2) {
+		return 0, false
+	}
+	if ilos.InstanceOf(class.Integer, x1) {
+		return new(big.Float).SetInt(bigInt(x1)).Cmp(big.NewFloat(f2)), true
+	}
+	if ilos.InstanceOf(class.Integer, x2) {
+		return big.NewFloat(f1).Cmp(new(big.Float).SetInt(bigInt(x2))), true
+	}
+	return big.NewFloat(f1).Cmp(big.NewFloat(f2)), true
+}
+
+// add returns the sum of the numbers x1 and x2. Integers overflowing int
+// become bignums.
+func add(x1, x2 ilos.Instance) ilos.Instance {
+	a, aok := x1.(instance.Integer)
+	b, bok := x2.(instance.Integer)
+	if aok && bok {
+		if c := a + b; (c > a) == (b > 0) {
+			return c
+		}
+	}
+	if ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2) {
+		return instance.NewBigInteger(new(big.Int).Add(bigInt(x1), bigInt(x2)))
+	}
+	return instance.NewFloat(float(x1) + float(x2))
+}
+
+// substruct returns the difference of the numbers x1 and x2.
+func substruct(x1, x2 ilos.Instance) ilos.Instance {
+	a, aok := x1.(instance.Integer)
+	b, bok := x2.(instance.Integer)
+	if aok && bok {
+		if c := a - b; (c < a) == (b > 0) {
+			return c
+		}
+	}
+	if ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2) {
+		return instance.NewBigInteger(new(big.Int).Sub(bigInt(x1), bigInt(x2)))
+	}
+	return instance.NewFloat(float(x1) - float(x2))
+}
+
+// multiply returns the product of the numbers x1 and x2.
+func multiply(x1, x2 ilos.Instance) ilos.Instance {
+	a, aok := x1.(instance.Integer)
+	b, bok := x2.(instance.Integer)
+	if aok && bok {
+		if a == 0 || b == 0 {
+			return instance.NewInteger(0)
+		}
+		if c := a * b; c/b == a && a != instance.Integer(minInt) && b != instance.Integer(minInt) {
+			return c
+		}
+	}
+	if ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2) {
+		return instance.NewBigInteger(new(big.Int).Mul(bigInt(x1), bigInt(x2)))
+	}
+	return instance.NewFloat(float(x1) * float(x2))
+}
+
+// isBigInteger returns true if the numbers x1 and x2 are integers and one of
+// them is a bignum.
+func isBigInteger(x1, x2 ilos.Instance) bool {
+	_, b1 := x1.(instance.BigInteger)
+	_, b2 := x2.(instance.BigInteger)
+	return (b1 || b2) && ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2)
+}
+
 // NumberEqual returns t if x1 has the same mathematical value as x2 ;
 // otherwise, returns nil. An error shall be signaled if either x1 or x2 is not
 // a number (error-id. domain-error). Note: = differs from eql because =
@@ -48,18 +149,7 @@ func NumberEqual(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance, ilos.I
 	if err := ensure(e, class.Number, x1, x2); err != nil {
 		return nil, err
 	}
-	ret := false
-	switch {
-	case ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2):
-		ret = x1 == x2
-	case ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Float, x2):
-		ret = float64(x1.(instance.Integer)) == float64(x2.(instance.Float))
-	case ilos.InstanceOf(class.Float, x1) && ilos.InstanceOf(class.Integer, x2):
-		ret = float64(x1.(instance.Float)) == float64(x2.(instance.Integer))
-	case ilos.InstanceOf(class.Float, x1) && ilos.InstanceOf(class.Float, x2):
-		ret = x1 == x2
-	}
-	if ret {
+	if c, ok := compare(x1, x2); ok && c == 0 {
 		return T, nil
 	}
 	return Nil, nil
@@ -81,18 +171,7 @@ func NumberGreaterThan(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance,
 	if err := ensure(e, class.Number, x1, x2); err != nil {
 		return nil, err
 	}
-	ret := false
-	switch {
-	case ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Integer, x2):
-		ret = float64(x1.(instance.Integer)) > float64(x2.(instance.Integer))
-	case ilos.InstanceOf(class.Integer, x1) && ilos.InstanceOf(class.Float, x2):
-		ret = float64(x1.(instance.Integer)) > float64(x2.(instance.Float))
-	case ilos.InstanceOf(class.Float, x1) && ilos.InstanceOf(class.Integer, x2):
-		ret = float64(x1.(instance.Float)) > float64(x2.(instance.Integer))
-	case ilos.InstanceOf(class.Float, x1) && ilos.InstanceOf(class.Float, x2):
-		ret = float64(x1.(instance.Float)) > float64(x2.(instance.Float))
-	}
-	if ret {
+	if c, ok := compare(x1, x2); ok && c > 0 {
 		return T, nil
 	}
 	return Nil, nil
@@ -100,36 +179,23 @@ func NumberGreaterThan(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance,
 
 // NumberGreaterThanOrEqual returns t if x1 is greater than or = x2
 func NumberGreaterThanOrEqual(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	gt, err := NumberGreaterThan(e, x1, x2)
-	if err != nil {
-		return nil, err
-	}
-	eq, err := NumberEqual(e, x1, x2)
-	if err != nil {
+	if err := ensure(e, class.Number, x1, x2); err != nil {
 		return nil, err
 	}
-	if gt == Nil && eq == Nil {
-		return Nil, nil
+	if c, ok := compare(x1, x2); ok && c >= 0 {
+		return T, nil
 	}
-	return T, nil
+	return Nil, nil
 }
 
 // NumberLessThan returns t if x1 is less than x2
 func NumberLessThan(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	ge, err := NumberGreaterThanOrEqual(e, x1, x2)
-	if err != nil {
-		return nil, err
-	}
-	return Not(e, ge)
+	return NumberGreaterThan(e, x2, x1)
 }
 
 // NumberLessThanOrEqual returns t if x1 is less than or = x2
 func NumberLessThanOrEqual(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance, ilos.Instance) {
-	gt, err := NumberGreaterThan(e, x1, x2)
-	if err != nil {
-		return nil, err
-	}
-	return Not(e, gt)
+	return NumberGreaterThanOrEqual(e, x2, x1)
 }
 
 // Add returns the sum, respectively, of their arguments. If all arguments are
@@ -137,20 +203,14 @@ func NumberLessThanOrEqual(e env.Environment, x1, x2 ilos.Instance) (ilos.Instan
 // a ﬂoat. When given no arguments, + returns 0. An error shall be signaled if
 // any x is not a number (error-id. domain-error).
 func Add(e env.Environment, x ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	flt := false
-	sum := 0.0
+	var sum ilos.Instance = instance.NewInteger(0)
 	for _, a := range x {
-		f, b, err := convFloat64(e, a)
-		if err != nil {
+		if err := ensure(e, class.Number, a); err != nil {
 			return nil, err
 		}
-		flt = flt || b
-		sum += f
-	}
-	if flt {
-		return instance.NewFloat(sum), nil
+		sum = add(sum, a)
 	}
-	return instance.NewInteger(int(sum)), nil
+	return sum, nil
 }
 
 // Multiply returns the product, respectively, of their arguments. If all
@@ -158,20 +218,19 @@ func Add(e env.Environment, x ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 // the result is a ﬂoat. When given no arguments, Multiply returns 1. An error
 // shall be signaled if any x is not a number (error-id. domain-error).
 func Multiply(e env.Environment, x ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	flt := false
-	pdt := 1.0
+	var pdt ilos.Instance = instance.NewInteger(1)
 	for _, a := range x {
-		f, b, err := convFloat64(e, a)
-		if err != nil {
+		if err := ensure(e, class.Number, a); err != nil {
 			return nil, err
 		}
-		pdt *= f
-		flt = flt || b
-	}
-	if flt {
-		return instance.NewFloat(pdt), nil
+		if isBigInteger(pdt, a) {
+			if err := allocate(e, class.Integer, bigInt(pdt).BitLen()+bigInt(a).BitLen()); err != nil {
+				return nil, err
+			}
+		}
+		pdt = multiply(pdt, a)
 	}
-	return instance.NewInteger(int(pdt)), nil
+	return pdt, nil
 }
 
 // Substruct returns its additive inverse. An error shall be signaled if x is
@@ -181,26 +240,23 @@ func Multiply(e env.Environment, x ...ilos.Instance) (ilos.Instance, ilos.Instan
 // argument, x1 … xn , - returns their successive differences, x1 −x2 − … −xn.
 // An error shall be signaled if any x is not a number (error-id. domain-error).
 func Substruct(e env.Environment, x ilos.Instance, xs ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if len(xs) == 0 {
-		ret, err := Substruct(e, instance.NewInteger(0), x)
-		return ret, err
-	}
-	sub, flt, err := convFloat64(e, x)
-	if err != nil {
+	if err := ensure(e, class.Number, x); err != nil {
 		return nil, err
 	}
+	if len(xs) == 0 {
+		if f, ok := x.(instance.Float); ok {
+			return instance.NewFloat(-float64(f)), nil
+		}
+		return substruct(instance.NewInteger(0), x), nil
+	}
+	sub := x
 	for _, a := range xs {
-		f, b, err := convFloat64(e, a)
-		if err != nil {
+		if err := ensure(e, class.Number, a); err != nil {
 			return nil, err
 		}
-		sub -= f
-		flt = flt || b
-	}
-	if flt {
-		return instance.NewFloat(sub), nil
+		sub = substruct(sub, a)
 	}
-	return instance.NewInteger(int(sub)), nil
+	return sub, nil
 }
 
 // Quotient returns the quotient of those numbers. The result is an integer if
@@ -214,31 +270,31 @@ func Substruct(e env.Environment, x ilos.Instance, xs ...ilos.Instance) (ilos.In
 // error shall be signaled if any divisor is zero (error-id. division-by-zero).
 func Quotient(e env.Environment, dividend, divisor1 ilos.Instance, divisor ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	divisor = append([]ilos.Instance{divisor1}, divisor...)
-	quotient, flt, err := convFloat64(e, dividend)
-	if err != nil {
+	if err := ensure(e, class.Number, dividend); err != nil {
 		return nil, err
 	}
+	quotient := dividend
 	for _, a := range divisor {
-		f, b, err := convFloat64(e, a)
-		if err != nil {
+		if err := ensure(e, class.Number, a); err != nil {
 			return nil, err
 		}
-		if f == 0.0 {
+		if c, ok := compare(a, instance.NewInteger(0)); ok && c == 0 {
 			arguments := Nil
 			for i := len(divisor) - 1; i >= 0; i-- {
 				arguments = instance.NewCons(divisor[i], arguments)
 			}
 			return SignalCondition(e, instance.NewArithmeticError(e, instance.NewSymbol("QUOTIENT"), arguments), Nil)
 		}
-		if !flt && !b && int(quotient)%int(f) != 0 {
-			flt = true
+		if ilos.InstanceOf(class.Integer, quotient) && ilos.InstanceOf(class.Integer, a) {
+			q, m := new(big.Int).QuoRem(bigInt(quotient), bigInt(a), new(big.Int))
+			if m.Sign() == 0 {
+				quotient = instance.NewBigInteger(q)
+				continue
+			}
 		}
-		quotient /= f
+		quotient = instance.NewFloat(float(quotient) / float(a))
 	}
-	if flt {
-		return instance.NewFloat(quotient), nil
-	}
-	return instance.NewInteger(int(quotient)), nil
+	return quotient, nil
 }
 
 // Reciprocal returns the reciprocal of its argument x ; that is, 1/x . An error
@@ -332,7 +388,15 @@ func Expt(e env.Environment, x1, x2 ilos.Instance) (ilos.Instance, ilos.Instance
 		return nil, err
 	}
 	if !af && !bf && b >= 0 {
-		return instance.NewInteger(int(math.Pow(a, b))), nil
+		if _, ok := x2.(instance.BigInteger); ok && bigInt(x1).CmpAbs(big.NewInt(1)) > 0 {
+			return SignalCondition(e, instance.Create(e, class.StorageExhausted), Nil)
+		}
+		if bits := b * math.Log2(math.Abs(a)); bits > 0 {
+			if err := allocate(e, class.Integer, int(math.Min(bits, float64(maxInt)))); err != nil {
+				return nil, err
+			}
+		}
+		return instance.NewBigInteger(new(big.Int).Exp(bigInt(x1), bigInt(x2), nil)), nil
 	}
 	if (a == 0 && b < 0) || (a == 0 && bf && b == 0) || (a < 0 && bf) {
 		operation := instance.NewSymbol("EXPT")
@@ -355,7 +419,13 @@ func Sqrt(e env.Environment, x ilos.Instance) (ilos.Instance, ilos.Instance) {
 	if a < 0.0 {
 		return SignalCondition(e, instance.NewDomainError(e, x, class.Number), Nil)
 	}
-	if math.Ceil(math.Sqrt(a)) == math.Sqrt(a) {
+	if ilos.InstanceOf(class.Integer, x) {
+		r := new(big.Int).Sqrt(bigInt(x))
+		if new(big.Int).Mul(r, r).Cmp(bigInt(x)) == 0 {
+			return instance.NewBigInteger(r), nil
+		}
+	}
+	if math.Ceil(math.Sqrt(a)) == math.Sqrt(a) && math.Sqrt(a) < float64(maxInt) {
 		return instance.NewInteger(int(math.Sqrt(a))), nil
 	}
 	return instance.NewFloat(math.Sqrt(a)), nil
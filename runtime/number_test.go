@@ -0,0 +1,178 @@
+package runtime
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	execTests(t, Add, []test{
+		{
+			exp:     `(+ 9223372036854775807 1)`,
+			want:    `9223372036854775808`,
+			wantErr: false,
+		},
+		{
+			exp:     `(+ 9223372036854775808 -1)`,
+			want:    `9223372036854775807`,
+			wantErr: false,
+		},
+		{
+			exp:     `(- -9223372036854775808 1)`,
+			want:    `-9223372036854775809`,
+			wantErr: false,
+		},
+		{
+			exp:     `(- -9223372036854775808)`,
+			want:    `9223372036854775808`,
+			wantErr: false,
+		},
+		{
+			exp:     `(+ 100000000000000000000 0.5)`,
+			want:    `1.0000000000000000E20`,
+			wantErr: false,
+		},
+	})
+}
+
+func TestMultiply(t *testing.T) {
+	execTests(t, Multiply, []test{
+		{
+			exp:     `(* 4294967296 4294967296)`,
+			want:    `18446744073709551616`,
+			wantErr: false,
+		},
+		{
+			exp:     `(* -1 -9223372036854775808)`,
+			want:    `9223372036854775808`,
+			wantErr: false,
+		},
+		{
+			exp:     `(quotient 18446744073709551616 4294967296)`,
+			want:    `4294967296`,
+			wantErr: false,
+		},
+		{
+			exp:     `(expt 2 100)`,
+			want:    `1267650600228229401496703205376`,
+			wantErr: false,
+		},
+		{
+			exp:     `(sqrt (expt 3 80))`,
+			want:    `(expt 3 40)`,
+			wantErr: false,
+		},
+	})
+}
+
+func TestNumberCompare(t *testing.T) {
+	execTests(t, NumberEqual, []test{
+		{
+			exp:     `(= (expt 2 64) 18446744073709551616)`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(< 9223372036854775807 9223372036854775808)`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(> (- (expt 2 64)) -1.0)`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `(= (expt 2 64) 18446744073709551616.0)`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(max 1 (expt 2 70) 3.0)`,
+			want:    `(expt 2 70)`,
+			wantErr: false,
+		},
+	})
+}
+
+func TestDiv(t *testing.T) {
+	execTests(t, Div, []test{
+		{
+			exp:     `(div 12 3)`,
+			want:    `4`,
+			wantErr: false,
+		},
+		{
+			exp:     `(div -12 3)`,
+			want:    `-4`,
+			wantErr: false,
+		},
+		{
+			exp:     `(div -7 2)`,
+			want:    `-4`,
+			wantErr: false,
+		},
+		{
+			exp:     `(div (expt 10 30) (- (expt 10 20)))`,
+			want:    `-10000000000`,
+			wantErr: false,
+		},
+		{
+			exp:     `(mod (- (expt 10 30) 1) (expt 10 20))`,
+			want:    `99999999999999999999`,
+			wantErr: false,
+		},
+		{
+			exp:     `(mod -7 2)`,
+			want:    `1`,
+			wantErr: false,
+		},
+		{
+			exp:     `(div (expt 10 30) 0)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
+
+func TestGcd(t *testing.T) {
+	execTests(t, Gcd, []test{
+		{
+			exp:     `(gcd (expt 2 80) (expt 6 40))`,
+			want:    `(expt 2 40)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(lcm (expt 2 80) (expt 6 40))`,
+			want:    `(* (expt 2 80) (expt 3 40))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(gcd -4 6)`,
+			want:    `2`,
+			wantErr: false,
+		},
+		{
+			exp:     `(isqrt (expt 10 41))`,
+			want:    `316227766016837933199`,
+			wantErr: false,
+		},
+	})
+}
+
+func TestFloor(t *testing.T) {
+	execTests(t, Floor, []test{
+		{
+			exp:     `(floor 1.0e20)`,
+			want:    `100000000000000000000`,
+			wantErr: false,
+		},
+		{
+			exp:     `(truncate -1.5e19)`,
+			want:    `-15000000000000000000`,
+			wantErr: false,
+		},
+		{
+			exp:     `(floor (+ (expt 2 70) 1))`,
+			want:    `1180591620717411303425`,
+			wantErr: false,
+		},
+	})
+}
@@ -0,0 +1,248 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// StartProfiling starts counting the calls made in e, and in every
+// environment made from it, in a new profile, which it returns. Calls are
+// counted by the name they are made by, and methods by the name of their
+// generic function and their qualifier and classes.
+func StartProfiling(e env.Environment) *env.Profile {
+	p := env.NewProfile(func(call env.Call) string {
+		return callName(call).String()
+	})
+	e.Stack.Profile = p
+	return p
+}
+
+// StopProfiling stops counting the calls made in e and returns the profile
+// they were counted in, or nil if they were not.
+func StopProfiling(e env.Environment) *env.Profile {
+	p := e.Stack.Profile
+	e.Stack.Profile = nil
+	return p
+}
+
+// profileEntry is what a profile counted for a function: its calls, the
+// time spent in them and the time spent in them but not in the functions
+// they called.
+type profileEntry struct {
+	name  string
+	calls int
+	total time.Duration
+	self  time.Duration
+}
+
+// profileEntries returns what p counted for each function, those which took
+// the most time by themselves first. The time of a recursive call is only
+// counted once in the total of the function.
+func profileEntries(p *env.Profile) []*profileEntry {
+	entries := map[string]*profileEntry{}
+	outer := map[string]int{}
+	var walk func(node *env.ProfileNode) time.Duration
+	walk = func(node *env.ProfileNode) time.Duration {
+		total := node.Self
+		outer[node.Name]++
+		for _, child := range node.Children {
+			total += walk(child)
+		}
+		outer[node.Name]--
+		entry, ok := entries[node.Name]
+		if !ok {
+			entry = &profileEntry{name: node.Name}
+			entries[node.Name] = entry
+		}
+		entry.calls += node.Calls
+		entry.self += node.Self
+		if outer[node.Name] == 0 {
+			entry.total += total
+		}
+		return total
+	}
+	for _, node := range p.Root.Children {
+		walk(node)
+	}
+	sorted := make([]*profileEntry, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].self != sorted[j].self {
+			return sorted[i].self > sorted[j].self
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	return sorted
+}
+
+// WriteProfile writes a report of p to w, a line for each function with
+// the number of its calls, the time spent in them in milliseconds and that
+// spent in them but not in the functions they called. The functions which
+// took the most time by themselves come first.
+func WriteProfile(w io.Writer, p *env.Profile) error {
+	if _, err := fmt.Fprintf(w, "%10v %12v %12v  %v\n", "calls", "total(ms)", "self(ms)", "function"); err != nil {
+		return err
+	}
+	for _, entry := range profileEntries(p) {
+		total := float64(entry.total) / float64(time.Millisecond)
+		self := float64(entry.self) / float64(time.Millisecond)
+		if _, err := fmt.Fprintf(w, "%10v %12.3f %12.3f  %v\n", entry.calls, total, self, entry.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pprofBrackets are the angle brackets around the name of a class, which
+// pprof would take for the arguments of a C++ template and drop.
+var pprofBrackets = regexp.MustCompile(`<([^<>]+)>`)
+
+// pprofName returns the name of a function as pprof shows it in full. The
+// parentheses in it, which pprof would take for the parameters of a Go
+// function, become brackets, and the names of classes lose their angle
+// brackets.
+func pprofName(name string) string {
+	name = strings.NewReplacer("(", "[", ")", "]").Replace(name)
+	return pprofBrackets.ReplaceAllString(name, "$1")
+}
+
+// WritePprof writes p to w as a gzipped profile.proto, which the pprof tool
+// reads, with the Lisp functions as frames. Each stack of calls has two
+// values: the number of calls and the nanoseconds spent in the innermost
+// one by itself.
+func WritePprof(w io.Writer, p *env.Profile) error {
+	var out protobuf
+	indices := map[string]int{"": 0}
+	table := []string{""}
+	index := func(s string) uint64 {
+		if i, ok := indices[s]; ok {
+			return uint64(i)
+		}
+		indices[s] = len(table)
+		table = append(table, s)
+		return uint64(len(table) - 1)
+	}
+	for _, t := range [][2]string{{"calls", "count"}, {"time", "nanoseconds"}} {
+		var valueType protobuf
+		valueType.uint64(1, index(t[0]))
+		valueType.uint64(2, index(t[1]))
+		out.message(1, &valueType) // sample_type
+	}
+	functions := map[string]uint64{}
+	var functionsAndLocations protobuf
+	var walk func(node *env.ProfileNode, stack []uint64)
+	walk = func(node *env.ProfileNode, stack []uint64) {
+		id, ok := functions[node.Name]
+		if !ok {
+			id = uint64(len(functions) + 1)
+			functions[node.Name] = id
+			var function protobuf
+			function.uint64(1, id)
+			function.uint64(2, index(pprofName(node.Name)))
+			function.uint64(3, index(pprofName(node.Name)))
+			functionsAndLocations.message(5, &function)
+			var line, location protobuf
+			line.uint64(1, id)
+			location.uint64(1, id)
+			location.message(4, &line)
+			functionsAndLocations.message(4, &location)
+		}
+		stack = append([]uint64{id}, stack...)
+		self := node.Self
+		if self < 0 {
+			self = 0
+		}
+		var sample protobuf
+		sample.packed(1, stack...)
+		sample.packed(2, uint64(node.Calls), uint64(self))
+		out.message(2, &sample)
+		for _, child := range node.Children {
+			walk(child, stack)
+		}
+	}
+	for _, node := range p.Root.Children {
+		walk(node, nil)
+	}
+	out.Write(functionsAndLocations.Bytes())
+	for _, s := range table {
+		out.bytes(6, []byte(s)) // string_table
+	}
+	z := gzip.NewWriter(w)
+	if _, err := z.Write(out.Bytes()); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// protobuf is a message in the protocol buffer encoding, to which fields are
+// appended.
+type protobuf struct {
+	bytes.Buffer
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.WriteByte(byte(x) | 0x80)
+		x >>= 7
+	}
+	b.WriteByte(byte(x))
+}
+
+func (b *protobuf) uint64(field int, x uint64) {
+	b.varint(uint64(field) << 3)
+	b.varint(x)
+}
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	b.Write(data)
+}
+
+func (b *protobuf) message(field int, m *protobuf) {
+	b.bytes(field, m.Bytes())
+}
+
+func (b *protobuf) packed(field int, xs ...uint64) {
+	var p protobuf
+	for _, x := range xs {
+		p.varint(x)
+	}
+	b.bytes(field, p.Bytes())
+}
+
+// WithProfiling evaluates forms counting the calls they make, as
+// StartProfiling does, and writes a report of them to the error output as
+// WriteProfile does. with-profiling returns the value of the last form.
+// This is an extension of iris.
+func WithProfiling(e env.Environment, forms ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	outer := e.Stack.Profile
+	p := StartProfiling(e)
+	ret, err := Progn(e, forms...)
+	e.Stack.Profile = outer
+	var report bytes.Buffer
+	WriteProfile(&report, p)
+	if _, err := Format(e, e.ErrorOutput, instance.NewString([]rune("~A")), instance.NewString([]rune(report.String()))); err != nil {
+		return nil, err
+	}
+	if _, err := FinishOutput(e, e.ErrorOutput); err != nil {
+		return nil, err
+	}
+	return ret, err
+}
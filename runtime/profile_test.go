@@ -0,0 +1,118 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	e := NewEnvironment(TopLevel.StandardInput, TopLevel.StandardOutput, TopLevel.ErrorOutput)
+	for _, exp := range []string{
+		`(defun profile-fib (n) (if (< n 2) n (+ (profile-fib (- n 1)) (profile-fib (- n 2)))))`,
+		`(defgeneric profile-area (s))`,
+		`(defmethod profile-area ((s <integer>)) (* s s))`,
+		`(defmethod profile-area ((s <string>)) (length s))`,
+	} {
+		obj, _ := readFromString(exp)
+		if _, err := Eval(e, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := StartProfiling(e)
+	obj, _ := readFromString(`(list (profile-fib 5) (profile-area 3) (profile-area "ab") (funcall #'profile-fib 1))`)
+	if _, err := Eval(e, obj); err != nil {
+		t.Fatal(err)
+	}
+	if StopProfiling(e) != p {
+		t.Fatal("StopProfiling() did not return the profile started")
+	}
+	calls := map[string]int{}
+	for _, entry := range profileEntries(p) {
+		calls[entry.name] = entry.calls
+		if entry.self > entry.total {
+			t.Errorf("%v: self %v > total %v", entry.name, entry.self, entry.total)
+		}
+	}
+	want := map[string]int{
+		"PROFILE-FIB":            16,
+		"PROFILE-AREA":           2,
+		"PROFILE-AREA/<INTEGER>": 1,
+		"PROFILE-AREA/<STRING>":  1,
+		"FUNCALL":                1,
+		"LIST":                   1,
+	}
+	for name, n := range want {
+		if calls[name] != n {
+			t.Errorf("calls of %v = %v, want %v", name, calls[name], n)
+		}
+	}
+	var pprof bytes.Buffer
+	if err := WritePprof(&pprof, p); err != nil {
+		t.Fatal(err)
+	}
+	z, err := gzip.NewReader(&pprof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := protobufFields(data)
+	names := map[string]bool{}
+	for _, function := range fields[5] {
+		name := protobufFields(function)[2][0]
+		names[string(fields[6][protobufVarint(name)])] = true
+	}
+	for _, name := range []string{"PROFILE-FIB", "PROFILE-AREA", "PROFILE-AREA/INTEGER", "PROFILE-AREA/STRING", "<", "FUNCALL"} {
+		if !names[name] {
+			t.Errorf("pprof profile has no frame %v, has %v", name, names)
+		}
+	}
+}
+
+// protobufFields decodes the fields of a message in the protocol buffer
+// encoding by number, keeping varints encoded.
+func protobufFields(data []byte) map[int][][]byte {
+	fields := map[int][][]byte{}
+	for len(data) > 0 {
+		key, n := protobufVarintLen(data)
+		data = data[n:]
+		switch key & 7 {
+		case 0:
+			_, n = protobufVarintLen(data)
+			fields[int(key>>3)] = append(fields[int(key>>3)], data[:n])
+			data = data[n:]
+		case 2:
+			length, n := protobufVarintLen(data)
+			data = data[n:]
+			fields[int(key>>3)] = append(fields[int(key>>3)], data[:length])
+			data = data[length:]
+		default:
+			panic("unexpected wire type")
+		}
+	}
+	return fields
+}
+
+func protobufVarint(data []byte) uint64 {
+	x, _ := protobufVarintLen(data)
+	return x
+}
+
+func protobufVarintLen(data []byte) (uint64, int) {
+	var x uint64
+	for i, b := range data {
+		x |= uint64(b&0x7f) << (7 * uint(i))
+		if b < 0x80 {
+			return x, i + 1
+		}
+	}
+	return x, len(data)
+}
@@ -0,0 +1,169 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/islisp-dev/iris/reader/parser"
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// readtableOption returns the readtable given as the i-th optional
+// argument, or the current readtable if it is omitted.
+func readtableOption(e env.Environment, options []ilos.Instance, i int) (*parser.Readtable, ilos.Instance) {
+	if len(options) <= i {
+		return parser.CurrentReadtable(e, nil), nil
+	}
+	if err := ensure(e, class.Readtable, options[i]); err != nil {
+		return nil, err
+	}
+	return options[i].(*parser.Readtable), nil
+}
+
+func Readtablep(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if ilos.InstanceOf(class.Readtable, obj) {
+		return T, nil
+	}
+	return Nil, nil
+}
+
+// CopyReadtable returns a copy of readtable, which defaults to the current
+// readtable. If it is NIL, the standard readtable is copied.
+func CopyReadtable(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if len(options) == 1 && options[0] == Nil {
+		return parser.NewReadtable(), nil
+	}
+	r, err := readtableOption(e, options, 0)
+	if err != nil {
+		return nil, err
+	}
+	return r.Copy(), nil
+}
+
+// SetMacroCharacter makes char a macro character of readtable. When the
+// reader meets char, function is called with the stream and char and the
+// result is the form read.
+func SetMacroCharacter(e env.Environment, char, function ilos.Instance, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 2 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if err := ensure(e, class.Character, char); err != nil {
+		return nil, err
+	}
+	if err := ensure(e, class.Function, function); err != nil {
+		return nil, err
+	}
+	r, err := readtableOption(e, options, 1)
+	if err != nil {
+		return nil, err
+	}
+	r.SetMacro(rune(char.(instance.Character)), function, len(options) > 0 && options[0] != Nil)
+	return T, nil
+}
+
+func GetMacroCharacter(e env.Environment, char ilos.Instance, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if err := ensure(e, class.Character, char); err != nil {
+		return nil, err
+	}
+	r, err := readtableOption(e, options, 0)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := r.Macro(rune(char.(instance.Character))); ok {
+		return f, nil
+	}
+	return Nil, nil
+}
+
+func MakeDispatchMacroCharacter(e env.Environment, char ilos.Instance, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 2 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if err := ensure(e, class.Character, char); err != nil {
+		return nil, err
+	}
+	r, err := readtableOption(e, options, 1)
+	if err != nil {
+		return nil, err
+	}
+	r.MakeDispatch(rune(char.(instance.Character)), len(options) > 0 && options[0] != Nil)
+	return T, nil
+}
+
+// SetDispatchMacroCharacter sets the function of sub under the dispatching
+// macro character disp. The function is called with the stream, sub and
+// the decimal argument written between them, or NIL if there is none.
+func SetDispatchMacroCharacter(e env.Environment, disp, sub, function ilos.Instance, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if err := ensure(e, class.Character, disp, sub); err != nil {
+		return nil, err
+	}
+	if err := ensure(e, class.Function, function); err != nil {
+		return nil, err
+	}
+	r, err := readtableOption(e, options, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !r.SetDispatch(rune(disp.(instance.Character)), rune(sub.(instance.Character)), function) {
+		return SignalCondition(e, instance.NewDomainError(e, disp, class.Character), Nil)
+	}
+	return T, nil
+}
+
+func GetDispatchMacroCharacter(e env.Environment, disp, sub ilos.Instance, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(options) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if err := ensure(e, class.Character, disp, sub); err != nil {
+		return nil, err
+	}
+	r, err := readtableOption(e, options, 0)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := r.Dispatch(rune(disp.(instance.Character)), rune(sub.(instance.Character))); ok {
+		return f, nil
+	}
+	return Nil, nil
+}
+
+// StreamReadtable returns the readtable selected for stream, or NIL if it
+// reads with the value of *READTABLE*.
+func StreamReadtable(e env.Environment, stream ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if b, _ := InputStreamP(e, stream); b == Nil {
+		return SignalCondition(e, instance.NewDomainError(e, stream, class.Stream), Nil)
+	}
+	if r := *stream.(instance.Stream).Readtable; r != nil {
+		return r, nil
+	}
+	return Nil, nil
+}
+
+// SetStreamReadtable selects readtable for stream. NIL deselects it.
+func SetStreamReadtable(e env.Environment, readtable, stream ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if b, _ := InputStreamP(e, stream); b == Nil {
+		return SignalCondition(e, instance.NewDomainError(e, stream, class.Stream), Nil)
+	}
+	if readtable == Nil {
+		*stream.(instance.Stream).Readtable = nil
+		return readtable, nil
+	}
+	if err := ensure(e, class.Readtable, readtable); err != nil {
+		return nil, err
+	}
+	*stream.(instance.Stream).Readtable = readtable
+	return readtable, nil
+}
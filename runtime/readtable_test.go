@@ -0,0 +1,98 @@
+package runtime
+
+import "testing"
+
+func TestSetMacroCharacter(t *testing.T) {
+	execTests(t, SetMacroCharacter, []test{
+		{
+			exp:     `(defglobal bang-readtable (copy-readtable))`,
+			want:    `'bang-readtable`,
+			wantErr: false,
+		},
+		{
+			exp: `(set-macro-character #\!
+			        (lambda (stream char) (list 'not (read stream)))
+			        nil bang-readtable)`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp: `(dynamic-let ((*readtable* bang-readtable))
+			        (read (create-string-input-stream "(a!b !c)")))`,
+			want:    `'(a (not b) (not c))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(read (create-string-input-stream "!c"))`,
+			want:    `'!c`,
+			wantErr: false,
+		},
+		{
+			exp: `(let ((str (create-string-input-stream "!'c")))
+			        (set-stream-readtable bang-readtable str)
+			        (read str))`,
+			want:    `'(not (quote c))`,
+			wantErr: false,
+		},
+		{
+			exp:     `(functionp (get-macro-character #\! bang-readtable))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(get-macro-character #\! (copy-readtable nil))`,
+			want:    `nil`,
+			wantErr: false,
+		},
+		{
+			exp:     `(set-macro-character #\! 1 nil bang-readtable)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	})
+}
+
+func TestSetDispatchMacroCharacter(t *testing.T) {
+	execTests(t, SetDispatchMacroCharacter, []test{
+		{
+			exp:     `(defglobal dollar-readtable (copy-readtable))`,
+			want:    `'dollar-readtable`,
+			wantErr: false,
+		},
+		{
+			exp:     `(make-dispatch-macro-character #\$ nil dollar-readtable)`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp: `(set-dispatch-macro-character #\$ #\r
+			        (lambda (stream char arg) (create-list arg (read stream)))
+			        dollar-readtable)`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp: `(set-dispatch-macro-character #\# #\!
+			        (lambda (stream char arg) (list 'ignored (read stream)))
+			        dollar-readtable)`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp: `(dynamic-let ((*readtable* dollar-readtable))
+			        (read (create-string-input-stream "($3Rx #!y #(1 2) #2a((1)) #'car #\\a)")))`,
+			want:    `'((x x x) (ignored y) #(1 2) #2a((1)) (function car) #\a)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(set-dispatch-macro-character #\% #\r #'list dollar-readtable)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(readtablep (dynamic *readtable*))`,
+			want:    `t`,
+			wantErr: false,
+		},
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/islisp-dev/iris/reader/parser"
 	"github.com/islisp-dev/iris/runtime/env"
 	"github.com/islisp-dev/iris/runtime/ilos"
 	"github.com/islisp-dev/iris/runtime/ilos/class"
@@ -21,305 +22,347 @@ func TopLevelHander(e env.Environment, c ilos.Instance) (ilos.Instance, ilos.Ins
 	return nil, c
 }
 
-var TopLevel = env.NewEnvironment(
+// NewEnvironment returns a top level environment reading from stdin and
+// writing to stdout and stderr. It has its own bindings of the builtins, so
+// definitions made in one environment are not seen by another.
+func NewEnvironment(stdin, stdout, stderr ilos.Instance) env.Environment {
+	e := env.NewEnvironment(stdin, stdout, stderr, instance.NewFunction(instance.NewSymbol("TOP-LEVEL-HANDLER"), TopLevelHander))
+	install(e)
+	return e
+}
+
+// TopLevel is the environment shared by the command and the tests.
+var TopLevel = NewEnvironment(
 	instance.NewStream(os.Stdin, nil, class.Character),
 	instance.NewStream(nil, os.Stdout, class.Character),
 	instance.NewStream(nil, os.Stderr, class.Character),
-	instance.NewFunction(instance.NewSymbol("TOP-LEVEL-HANDLER"), TopLevelHander),
 )
 
-func defclass(name string, class ilos.Class) {
+func defclass(e env.Environment, name string, class ilos.Class) {
 	symbol := instance.NewSymbol(name)
-	TopLevel.Class.Define(symbol, class)
+	e.Class.Define(symbol, class)
 }
 
-func defspecial(name string, function interface{}) {
+func defspecial(e env.Environment, name string, function interface{}) {
 	symbol := instance.NewSymbol(name)
-	TopLevel.Special.Define(symbol, instance.NewFunction(func2symbol(function), function))
+	e.Special.Define(symbol, instance.NewFunction(func2symbol(function), function))
 }
 
-func defun(name string, function interface{}) {
+func defun(e env.Environment, name string, function interface{}) {
 	symbol := instance.NewSymbol(name)
-	TopLevel.Function.Define(symbol, instance.NewFunction(symbol, function))
+	e.Function.Define(symbol, instance.NewFunction(symbol, function))
 }
 
-func defgeneric(name string, function interface{}) {
+func defgeneric(e env.Environment, name string, function interface{}) {
 	symbol := instance.NewSymbol(name)
-	lambdaList, _ := List(TopLevel, instance.NewSymbol("FIRST"), instance.NewSymbol("&REST"), instance.NewSymbol("REST"))
+	lambdaList, _ := List(e, instance.NewSymbol("FIRST"), instance.NewSymbol("&REST"), instance.NewSymbol("REST"))
 	generic := instance.NewGenericFunction(symbol, lambdaList, T, class.GenericFunction)
 	generic.(*instance.GenericFunction).AddMethod(nil, lambdaList, []ilos.Class{class.StandardClass}, instance.NewFunction(symbol, function))
-	TopLevel.Function.Define(symbol, generic)
+	e.Function.Define(symbol, generic)
 }
 
-func defglobal(name string, value ilos.Instance) {
+func defglobal(e env.Environment, name string, value ilos.Instance) {
 	symbol := instance.NewSymbol(name)
-	TopLevel.Variable.Define(symbol, value)
+	e.Variable.Define(symbol, value)
 }
 
-func init() {
-	defglobal("*PI*", instance.Float(math.Pi))
-	defglobal("*MOST-POSITIVE-FLOAT*", MostPositiveFloat)
-	defglobal("*MOST-NEGATIVE-FLOAT*", MostNegativeFloat)
-	defun("-", Substruct)
-	defun("+", Add)
-	defun("*", Multiply)
-	defun("<", NumberLessThan)
-	defun("<=", NumberLessThanOrEqual)
-	defun("=", NumberEqual)
-	defun(">", NumberGreaterThan)
-	defun(">=", NumberGreaterThanOrEqual)
-	defspecial("QUASIQUOTE", Quasiquote)
-	defun("ABS", Abs)
-	defspecial("AND", And)
-	defun("APPEND", Append)
-	defun("APPLY", Apply)
-	defun("ARRAY-DIMENSIONS", ArrayDimensions)
-	defun("AREF", Aref)
-	defun("ASSOC", Assoc)
+func defdynamic(e env.Environment, name string, value ilos.Instance) {
+	symbol := instance.NewSymbol(name)
+	e.DynamicVariable.Define(symbol, value)
+}
+
+func install(e env.Environment) {
+	defglobal(e, "*PI*", instance.Float(math.Pi))
+	defglobal(e, "*MOST-POSITIVE-FLOAT*", MostPositiveFloat)
+	defglobal(e, "*MOST-NEGATIVE-FLOAT*", MostNegativeFloat)
+	defdynamic(e, "*READTABLE*", parser.NewReadtable())
+	defun(e, "-", Substruct)
+	defun(e, "+", Add)
+	defun(e, "*", Multiply)
+	defun(e, "<", NumberLessThan)
+	defun(e, "<=", NumberLessThanOrEqual)
+	defun(e, "=", NumberEqual)
+	defun(e, ">", NumberGreaterThan)
+	defun(e, ">=", NumberGreaterThanOrEqual)
+	defspecial(e, "QUASIQUOTE", Quasiquote)
+	defun(e, "ABS", Abs)
+	defspecial(e, "AND", And)
+	defun(e, "APPEND", Append)
+	defun(e, "APPLY", Apply)
+	defun(e, "ARRAY-DIMENSIONS", ArrayDimensions)
+	defun(e, "AREF", Aref)
+	defun(e, "ASSOC", Assoc)
 	// TODO: defspecial2("ASSURE", Assure)
-	defun("ATAN", Atan)
-	defun("ATAN2", Atan2)
-	defun("ATANH", Atanh)
-	defun("BASIC-ARRAY*-P", BasicArrayStarP)
-	defun("BASIC-ARRAY-P", BasicArrayP)
-	defun("BASIC-VECTOR-P", BasicVectorP)
-	defspecial("BLOCK", Block)
-	defun("CAR", Car)
-	defspecial("CASE", Case)
-	defspecial("CASE-USING", CaseUsing)
-	defspecial("CATCH", Catch)
-	defun("CDR", Cdr)
-	defun("CEILING", Ceiling)
-	defun("CERROR", Cerror)
-	defun("CHAR-INDEX", CharIndex)
-	defun("CHAR/=", CharNotEqual)
-	defun("CHAR<", CharLessThan)
-	defun("CHAR<=", CharLessThanOrEqual)
-	defun("CHAR=", CharEqual)
-	defun("CHAR>", CharGreaterThan)
-	defun("CHAR>=", CharGreaterThanOrEqual)
-	defun("CHARACTERP", Characterp)
-	defspecial("CLASS", Class)
-	defun("CLASS-OF", ClassOf)
-	defun("CLOSE", Close)
+	defun(e, "ATAN", Atan)
+	defun(e, "ATAN2", Atan2)
+	defun(e, "ATANH", Atanh)
+	defun(e, "BASIC-ARRAY*-P", BasicArrayStarP)
+	defun(e, "BASIC-ARRAY-P", BasicArrayP)
+	defun(e, "BASIC-VECTOR-P", BasicVectorP)
+	defspecial(e, "BLOCK", Block)
+	defun(e, "BREAK", Break)
+	defun(e, "CAR", Car)
+	defspecial(e, "CASE", Case)
+	defspecial(e, "CASE-USING", CaseUsing)
+	defspecial(e, "CATCH", Catch)
+	defun(e, "CDR", Cdr)
+	defun(e, "CEILING", Ceiling)
+	defun(e, "CERROR", Cerror)
+	defun(e, "CHAR-INDEX", CharIndex)
+	defun(e, "CHAR/=", CharNotEqual)
+	defun(e, "CHAR<", CharLessThan)
+	defun(e, "CHAR<=", CharLessThanOrEqual)
+	defun(e, "CHAR=", CharEqual)
+	defun(e, "CHAR>", CharGreaterThan)
+	defun(e, "CHAR>=", CharGreaterThanOrEqual)
+	defun(e, "CHARACTERP", Characterp)
+	defspecial(e, "CLASS", Class)
+	defun(e, "CLASS-OF", ClassOf)
+	defun(e, "CLOSE", Close)
 	// SKIP defun2("COERCION", Coercion)
-	defspecial("COND", Cond)
-	defun("CONDITION-CONTINUABLE", ConditionContinuable)
-	defun("CONS", Cons)
-	defun("CONSP", Consp)
-	defun("CONTINUE-CONDITION", ContinueCondition)
-	defspecial("CONVERT", Convert)
-	defun("COS", Cos)
-	defun("COSH", Cosh)
-	defgeneric("CREATE", Create) //TODO Change to generic function
-	defun("CREATE-ARRAY", CreateArray)
-	defun("CREATE-LIST", CreateList)
-	defun("CREATE-STRING", CreateString)
-	defun("CREATE-STRING-INPUT-STREAM", CreateStringInputStream)
-	defun("CREATE-STRING-OUTPUT-STREAM", CreateStringOutputStream)
-	defun("CREATE-VECTOR", CreateVector)
-	defspecial("DEFCLASS", Defclass)
-	defspecial("DEFCONSTANT", Defconstant)
-	defspecial("DEFDYNAMIC", Defdynamic)
-	defspecial("DEFGENERIC", Defgeneric)
-	defspecial("DEFMETHOD", Defmethod)
-	defspecial("DEFGLOBAL", Defglobal)
-	defspecial("DEFMACRO", Defmacro)
-	defspecial("DEFUN", Defun)
-	defun("DIV", Div)
-	defspecial("DYNAMIC", Dynamic)
-	defspecial("DYNAMIC-LET", DynamicLet)
-	defun("ELT", Elt)
-	defun("EQ", Eq)
-	defun("EQL", Eql)
-	defun("EQUAL", Equal)
-	defun("ERROR", Error)
-	defun("ERROR-OUTPUT", ErrorOutput)
-	defun("EXP", Exp)
-	defun("EXPT", Expt)
+	defspecial(e, "COND", Cond)
+	defun(e, "CONDITION-BACKTRACE", ConditionBacktrace)
+	defun(e, "CONDITION-CONTINUABLE", ConditionContinuable)
+	defun(e, "CONS", Cons)
+	defun(e, "CONSP", Consp)
+	defun(e, "CONTINUE-CONDITION", ContinueCondition)
+	defspecial(e, "CONVERT", Convert)
+	defun(e, "COPY-READTABLE", CopyReadtable)
+	defun(e, "COS", Cos)
+	defun(e, "COSH", Cosh)
+	defgeneric(e, "CREATE", Create) //TODO Change to generic function
+	defun(e, "CREATE-ARRAY", CreateArray)
+	defun(e, "CREATE-LIST", CreateList)
+	defun(e, "CREATE-STRING", CreateString)
+	defun(e, "CREATE-STRING-INPUT-STREAM", CreateStringInputStream)
+	defun(e, "CREATE-STRING-OUTPUT-STREAM", CreateStringOutputStream)
+	defun(e, "CREATE-VECTOR", CreateVector)
+	defspecial(e, "DEFCLASS", Defclass)
+	defspecial(e, "DEFCONSTANT", Defconstant)
+	defspecial(e, "DEFDYNAMIC", Defdynamic)
+	defspecial(e, "DEFGENERIC", Defgeneric)
+	defspecial(e, "DEFMETHOD", Defmethod)
+	defspecial(e, "DEFGLOBAL", Defglobal)
+	defspecial(e, "DEFMACRO", Defmacro)
+	defspecial(e, "DEFUN", Defun)
+	defun(e, "DIV", Div)
+	defspecial(e, "DYNAMIC", Dynamic)
+	defspecial(e, "DYNAMIC-LET", DynamicLet)
+	defun(e, "ELT", Elt)
+	defun(e, "EQ", Eq)
+	defun(e, "EQL", Eql)
+	defun(e, "EQUAL", Equal)
+	defun(e, "ERROR", Error)
+	defun(e, "ERROR-OUTPUT", ErrorOutput)
+	defun(e, "EXP", Exp)
+	defun(e, "EXPT", Expt)
 	// TODO defun2("FILE-LENGTH", FileLength)
 	// TODO defun2("FILE-POSITION", FilePosition)
-	defun("FINISH-OUTPUT", FinishOutput)
-	defspecial("FLET", Flet)
-	defun("FLOAT", Float)
-	defun("FLOATP", Floatp)
-	defun("FLOOR", Floor)
-	defspecial("FOR", For)
-	defun("FORMAT", Format)
-	defun("FORMAT-CHAR", FormatChar)
-	defun("FORMAT-FLOAT", FormatFloat)
-	defun("FORMAT-FRESH-LINE", FormatFreshLine)
-	defun("FORMAT-INTEGER", FormatInteger)
-	defun("FORMAT-OBJECT", FormatObject)
-	defun("FORMAT-TAB", FormatTab)
-	defun("FUNCALL", Funcall)
-	defspecial("FUNCTION", Function)
-	defun("FUNCTIONP", Functionp)
-	defun("GAREF", Garef)
-	defun("GCD", Gcd)
-	defun("GENERAL-ARRAY*-P", GeneralArrayStarP)
-	defun("GENERAL-VECTOR-P", GeneralVectorP)
-	defun("GENERIC-FUNCTION-P", GenericFunctionP)
-	defun("GENSYM", Gensym)
-	defun("GET-INTERNAL-REAL-TIME", GetInternalRealTime)
-	defun("GET-INTERNAL-RUN-TIME", GetInternalRunTime)
-	defun("GET-OUTPUT-STREAM-STRING", GetOutputStreamString)
-	defun("GET-UNIVERSAL-TIME", GetUniversalTime)
-	defspecial("GO", Go)
-	defun("IDENTITY", Identity)
-	defspecial("IF", If)
+	defun(e, "FINISH-OUTPUT", FinishOutput)
+	defspecial(e, "FLET", Flet)
+	defun(e, "FLOAT", Float)
+	defun(e, "FLOATP", Floatp)
+	defun(e, "FLOOR", Floor)
+	defspecial(e, "FOR", For)
+	defun(e, "FORMAT", Format)
+	defun(e, "FORMAT-CHAR", FormatChar)
+	defun(e, "FORMAT-FLOAT", FormatFloat)
+	defun(e, "FORMAT-FRESH-LINE", FormatFreshLine)
+	defun(e, "FORMAT-INTEGER", FormatInteger)
+	defun(e, "FORMAT-OBJECT", FormatObject)
+	defun(e, "FORMAT-TAB", FormatTab)
+	defun(e, "FUNCALL", Funcall)
+	defspecial(e, "FUNCTION", Function)
+	defun(e, "FUNCTION-ARITY", FunctionArity)
+	defun(e, "FUNCTIONP", Functionp)
+	defun(e, "GAREF", Garef)
+	defun(e, "GCD", Gcd)
+	defun(e, "GENERAL-ARRAY*-P", GeneralArrayStarP)
+	defun(e, "GENERAL-VECTOR-P", GeneralVectorP)
+	defun(e, "GENERIC-FUNCTION-P", GenericFunctionP)
+	defun(e, "GENSYM", Gensym)
+	defun(e, "GET-DISPATCH-MACRO-CHARACTER", GetDispatchMacroCharacter)
+	defun(e, "GET-MACRO-CHARACTER", GetMacroCharacter)
+	defun(e, "GET-INTERNAL-REAL-TIME", GetInternalRealTime)
+	defun(e, "GET-INTERNAL-RUN-TIME", GetInternalRunTime)
+	defun(e, "GET-OUTPUT-STREAM-STRING", GetOutputStreamString)
+	defun(e, "GET-UNIVERSAL-TIME", GetUniversalTime)
+	defspecial(e, "GO", Go)
+	defun(e, "IDENTITY", Identity)
+	defspecial(e, "IF", If)
 	// TODO defspecial2("IGNORE-ERRORS", IgnoreErrors)
-	defgeneric("INITIALIZE-OBJECT", InitializeObject) // TODO change generic function
-	defun("INPUT-STREAM-P", InputStreamP)
-	defun("INSTANCEP", Instancep)
-	defun("INTEGERP", Integerp)
-	defun("INTERNAL-TIME-UNITS-PER-SECOND", InternalTimeUnitsPerSecond)
-	defun("ISQRT", Isqrt)
-	defspecial("LABELS", Labels)
-	defspecial("LAMBDA", Lambda)
-	defun("LCM", Lcm)
-	defun("LENGTH", Length)
-	defspecial("LET", Let)
-	defspecial("LET*", LetStar)
-	defun("LIST", List)
-	defun("LISTP", Listp)
-	defun("LOG", Log)
-	defun("MAP-INTO", MapInto)
-	defun("MAPC", Mapc)
-	defun("MAPCAN", Mapcan)
-	defun("MAPCAR", Mapcar)
-	defun("MAPCON", Mapcon)
-	defun("MAPL", Mapl)
-	defun("MAPLIST", Maplist)
-	defun("MAX", Max)
-	defun("MEMBER", Member)
-	defun("MIN", Min)
-	defun("MOD", Mod)
-	defglobal("NI-L", Nil)
-	defun("NOT", Not)
-	defun("NREVERSE", Nreverse)
-	defun("NULL", Null)
-	defun("NUMBERP", Numberp)
-	defun("OPEN-INPUT-FILE", OpenInputFile)
-	defun("OPEN-IO-FILE", OpenIoFile)
-	defun("OPEN-OUTPUT-FILE", OpenOutputFile)
-	defun("OPEN-STREAM-P", OpenStreamP)
-	defspecial("OR", Or)
+	defgeneric(e, "INITIALIZE-OBJECT", InitializeObject) // TODO change generic function
+	defun(e, "INPUT-STREAM-P", InputStreamP)
+	defun(e, "INSTANCEP", Instancep)
+	defun(e, "INTEGERP", Integerp)
+	defun(e, "INTERNAL-TIME-UNITS-PER-SECOND", InternalTimeUnitsPerSecond)
+	defun(e, "ISQRT", Isqrt)
+	defspecial(e, "LABELS", Labels)
+	defspecial(e, "LAMBDA", Lambda)
+	defun(e, "LCM", Lcm)
+	defun(e, "LENGTH", Length)
+	defspecial(e, "LET", Let)
+	defspecial(e, "LET*", LetStar)
+	defun(e, "LIST", List)
+	defun(e, "LISTP", Listp)
+	defun(e, "LOG", Log)
+	defun(e, "MAKE-DISPATCH-MACRO-CHARACTER", MakeDispatchMacroCharacter)
+	defun(e, "MAP-INTO", MapInto)
+	defun(e, "MAPC", Mapc)
+	defun(e, "MAPCAN", Mapcan)
+	defun(e, "MAPCAR", Mapcar)
+	defun(e, "MAPCON", Mapcon)
+	defun(e, "MAPL", Mapl)
+	defun(e, "MAPLIST", Maplist)
+	defun(e, "MAX", Max)
+	defun(e, "MEMBER", Member)
+	defun(e, "MIN", Min)
+	defun(e, "MOD", Mod)
+	defglobal(e, "NI-L", Nil)
+	defun(e, "NOT", Not)
+	defun(e, "NREVERSE", Nreverse)
+	defun(e, "NULL", Null)
+	defun(e, "NUMBERP", Numberp)
+	defun(e, "OPEN-INPUT-FILE", OpenInputFile)
+	defun(e, "OPEN-IO-FILE", OpenIoFile)
+	defun(e, "OPEN-OUTPUT-FILE", OpenOutputFile)
+	defun(e, "OPEN-STREAM-P", OpenStreamP)
+	defspecial(e, "OR", Or)
 	// defun("FLUSH-OUTPUT", FlushOutput)
-	defun("OUTPUT-STREAM-P", OutputStreamP)
-	defun("PARSE-NUMBER", ParseNumber)
-	defun("PREVIEW-CHAR", PreviewChar)
-	defun("PROBE-FILE", ProbeFile)
-	defspecial("PROGN", Progn)
-	defun("PROPERTY", Property)
-	defspecial("QUASIQUOTE", Quasiquote)
-	defspecial("QUOTE", Quote)
-	defun("QUOTIENT", Quotient)
-	defun("READ", Read)
-	defun("READ-BYTE", ReadByte)
-	defun("READ-CHAR", ReadChar)
-	defun("READ-LINE", ReadLine)
-	defun("REMOVE-PROPERTY", RemoveProperty)
-	defun("REPORT-CONDITION", ReportCondition)
-	defspecial("RETURN-FROM", ReturnFrom)
-	defun("REVERSE", Reverse)
-	defun("ROUND", Round)
-	defun("SET-AREF", SetAref)
-	defun("(SETF AREF)", SetAref)
-	defun("SET-CAR", SetCar)
-	defun("(SETF CAR)", SetCar)
-	defun("SET-CDR", SetCdr)
-	defun("(SETF CDR)", SetCdr)
-	defun("SET-DYNAMIC", SetDynamic)
-	defun("(SETF DYNAMIC)", SetDynamic)
-	defun("SET-ELT", SetElt)
-	defun("(SETF ELT)", SetElt)
+	defun(e, "OUTPUT-STREAM-P", OutputStreamP)
+	defun(e, "PARSE-NUMBER", ParseNumber)
+	defun(e, "PREVIEW-CHAR", PreviewChar)
+	defun(e, "PROBE-FILE", ProbeFile)
+	defspecial(e, "PROGN", Progn)
+	defun(e, "PROPERTY", Property)
+	defspecial(e, "QUASIQUOTE", Quasiquote)
+	defspecial(e, "QUOTE", Quote)
+	defun(e, "QUOTIENT", Quotient)
+	defun(e, "READ", Read)
+	defun(e, "READ-BYTE", ReadByte)
+	defun(e, "READ-CHAR", ReadChar)
+	defun(e, "READ-LINE", ReadLine)
+	defun(e, "READTABLEP", Readtablep)
+	defun(e, "REMOVE-BREAKPOINT", RemoveBreakpoint)
+	defun(e, "REMOVE-PROPERTY", RemoveProperty)
+	defun(e, "REPORT-CONDITION", ReportCondition)
+	defspecial(e, "RETURN-FROM", ReturnFrom)
+	defun(e, "REVERSE", Reverse)
+	defun(e, "ROUND", Round)
+	defun(e, "SET-AREF", SetAref)
+	defun(e, "(SETF AREF)", SetAref)
+	defun(e, "SET-BREAKPOINT", SetBreakpoint)
+	defun(e, "SET-CAR", SetCar)
+	defun(e, "(SETF CAR)", SetCar)
+	defun(e, "SET-CDR", SetCdr)
+	defun(e, "(SETF CDR)", SetCdr)
+	defun(e, "SET-DYNAMIC", SetDynamic)
+	defun(e, "(SETF DYNAMIC)", SetDynamic)
+	defun(e, "SET-DISPATCH-MACRO-CHARACTER", SetDispatchMacroCharacter)
+	defun(e, "SET-ELT", SetElt)
+	defun(e, "(SETF ELT)", SetElt)
 	// TODO defun2("SET-FILE-POSITION", SetFilePosition)
-	defun("SET-GAREF", SetGaref)
-	defun("(SETF GAREF)", SetGaref)
-	defun("SET-PROPERTY", SetProperty)
-	defun("(SETF PROPERTY)", SetProperty)
-	defspecial("SETF", Setf)
-	defspecial("SETQ", Setq)
-	defun("SIGNAL-CONDITION", SignalCondition)
+	defun(e, "SET-GAREF", SetGaref)
+	defun(e, "(SETF GAREF)", SetGaref)
+	defun(e, "SET-MACRO-CHARACTER", SetMacroCharacter)
+	defun(e, "SET-PROPERTY", SetProperty)
+	defun(e, "(SETF PROPERTY)", SetProperty)
+	defun(e, "SET-STREAM-READTABLE", SetStreamReadtable)
+	defun(e, "(SETF STREAM-READTABLE)", SetStreamReadtable)
+	defspecial(e, "SETF", Setf)
+	defspecial(e, "SETQ", Setq)
+	defun(e, "SIGNAL-CONDITION", SignalCondition)
 	// TODO defun2("SIMPLE-ERROR-FORMAT-ARGUMENTS", SimpleErrorFormatArguments)
 	// TODO defun2("SIMPLE-ERROR-FORMAT-STRING", SimpleErrorFormatString)
-	defun("SIN", Sin)
-	defun("SINH", Sinh)
-	defun("SQRT", Sqrt)
-	defun("STANDARD-INPUT", StandardInput)
-	defun("STANDARD-OUTPUT", StandardOutput)
-	defun("STREAM-READY-P", StreamReadyP)
-	defun("STREAMP", Streamp)
-	defun("STRING-APPEND", StringAppend)
-	defun("STRING-INDEX", StringIndex)
-	defun("STRING/=", StringNotEqual)
-	defun("STRING>", StringGreaterThan)
-	defun("STRING>=", StringGreaterThanOrEqual)
-	defun("STRING=", StringEqual)
-	defun("STRING<", StringLessThan)
-	defun("STRING<=", StringLessThanOrEqual)
-	defun("STRINGP", Stringp)
-	defun("SUBCLASSP", Subclassp)
-	defun("SUBSEQ", Subseq)
-	defun("SYMBOLP", Symbolp)
-	defglobal("T", T)
-	defspecial("TAGBODY", Tagbody)
-	defspecial("TAN", Tan)
-	defspecial("TANH", Tanh)
+	defun(e, "SIN", Sin)
+	defun(e, "SINH", Sinh)
+	defun(e, "SQRT", Sqrt)
+	defun(e, "STANDARD-INPUT", StandardInput)
+	defun(e, "STANDARD-OUTPUT", StandardOutput)
+	defspecial(e, "STEP", Step)
+	defun(e, "STREAM-READY-P", StreamReadyP)
+	defun(e, "STREAM-READTABLE", StreamReadtable)
+	defun(e, "STREAMP", Streamp)
+	defun(e, "STRING-APPEND", StringAppend)
+	defun(e, "STRING-INDEX", StringIndex)
+	defun(e, "STRING/=", StringNotEqual)
+	defun(e, "STRING>", StringGreaterThan)
+	defun(e, "STRING>=", StringGreaterThanOrEqual)
+	defun(e, "STRING=", StringEqual)
+	defun(e, "STRING<", StringLessThan)
+	defun(e, "STRING<=", StringLessThanOrEqual)
+	defun(e, "STRINGP", Stringp)
+	defun(e, "SUBCLASSP", Subclassp)
+	defun(e, "SUBSEQ", Subseq)
+	defun(e, "SYMBOLP", Symbolp)
+	defglobal(e, "T", T)
+	defspecial(e, "TAGBODY", Tagbody)
+	defspecial(e, "TAN", Tan)
+	defspecial(e, "TANH", Tanh)
 	// TODO defspecial2("THE", The)
-	defspecial("THROW", Throw)
-	defun("TRUNCATE", Truncate)
+	defspecial(e, "THROW", Throw)
+	defspecial(e, "TRACE", Trace)
+	defun(e, "TRUNCATE", Truncate)
 	// TODO defun1("UNDEFINED-ENTITY-NAME", UndefinedEntityName)
 	// TODO defun2("UNDEFINED-ENTITY-NAMESPACE", UndefinedEntityNamespace)
-	defspecial("UNWIND-PROTECT", UnwindProtect)
-	defun("VECTOR", Vector)
-	defspecial("WHILE", While)
-	defspecial("WITH-ERROR-OUTPUT", WithErrorOutput)
-	defspecial("WITH-HANDLER", WithHandler)
-	defspecial("WITH-OPEN-INPUT-FILE", WithOpenInputFile)
-	defspecial("WITH-OPEN-OUTPUT-FILE", WithOpenOutputFile)
-	defspecial("WITH-STANDARD-INPUT", WithStandardInput)
-	defspecial("WITH-STANDARD-OUTPUT", WithStandardOutput)
-	defun("WRITE-BYTE", WriteByte)
-	defclass("<OBJECT>", class.Object)
-	defclass("<BUILT-IN-CLASS>", class.BuiltInClass)
-	defclass("<STANDARD-CLASS>", class.StandardClass)
-	defclass("<BASIC-ARRAY>", class.BasicArray)
-	defclass("<BASIC-ARRAY-STAR>", class.BasicArrayStar)
-	defclass("<GENERAL-ARRAY-STAR>", class.GeneralArrayStar)
-	defclass("<BASIC-VECTOR>", class.BasicVector)
-	defclass("<GENERAL-VECTOR>", class.GeneralVector)
-	defclass("<STRING>", class.String)
-	defclass("<CHARACTER>", class.Character)
-	defclass("<FUNCTION>", class.Function)
-	defclass("<GENERIC-FUNCTION>", class.GenericFunction)
-	defclass("<STANDARD-GENERIC-FUNCTION>", class.StandardGenericFunction)
-	defclass("<LIST>", class.List)
-	defclass("<CONS>", class.Cons)
-	defclass("<NULL>", class.Null)
-	defclass("<SYMBOL>", class.Symbol)
-	defclass("<NUMBER>", class.Number)
-	defclass("<INTEGER>", class.Integer)
-	defclass("<FLOAT>", class.Float)
-	defclass("<SERIOUS-CONDITION>", class.SeriousCondition)
-	defclass("<ERROR>", class.Error)
-	defclass("<ARITHMETIC-ERROR>", class.ArithmeticError)
-	defclass("<DIVISION-BY-ZERO>", class.DivisionByZero)
-	defclass("<FLOATING-POINT-ONDERFLOW>", class.FloatingPointOnderflow)
-	defclass("<FLOATING-POINT-UNDERFLOW>", class.FloatingPointUnderflow)
-	defclass("<CONTROL-ERROR>", class.ControlError)
-	defclass("<PARSE-ERROR>", class.ParseError)
-	defclass("<PROGRAM-ERROR>", class.ProgramError)
-	defclass("<DOMAIN-ERROR>", class.DomainError)
-	defclass("<UNDEFINED-ENTITY>", class.UndefinedEntity)
-	defclass("<UNDEFINED-VARIABLE>", class.UndefinedVariable)
-	defclass("<UNDEFINED-FUNCTION>", class.UndefinedFunction)
-	defclass("<SIMPLE-ERROR>", class.SimpleError)
-	defclass("<STREAM-ERROR>", class.StreamError)
-	defclass("<END-OF-STREAM>", class.EndOfStream)
-	defclass("<STORAGE-EXHAUSTED>", class.StorageExhausted)
-	defclass("<STANDARD-OBJECT>", class.StandardObject)
-	defclass("<STREAM>", class.Stream)
+	defspecial(e, "UNTRACE", Untrace)
+	defspecial(e, "UNWIND-PROTECT", UnwindProtect)
+	defun(e, "VECTOR", Vector)
+	defspecial(e, "WHILE", While)
+	defspecial(e, "WITH-ERROR-OUTPUT", WithErrorOutput)
+	defspecial(e, "WITH-HANDLER", WithHandler)
+	defspecial(e, "WITH-OPEN-INPUT-FILE", WithOpenInputFile)
+	defspecial(e, "WITH-OPEN-OUTPUT-FILE", WithOpenOutputFile)
+	defspecial(e, "WITH-PROFILING", WithProfiling)
+	defspecial(e, "WITH-STANDARD-INPUT", WithStandardInput)
+	defspecial(e, "WITH-STANDARD-OUTPUT", WithStandardOutput)
+	defun(e, "WRITE-BYTE", WriteByte)
+	defclass(e, "<OBJECT>", class.Object)
+	defclass(e, "<BUILT-IN-CLASS>", class.BuiltInClass)
+	defclass(e, "<STANDARD-CLASS>", class.StandardClass)
+	defclass(e, "<BASIC-ARRAY>", class.BasicArray)
+	defclass(e, "<BASIC-ARRAY-STAR>", class.BasicArrayStar)
+	defclass(e, "<GENERAL-ARRAY-STAR>", class.GeneralArrayStar)
+	defclass(e, "<BASIC-VECTOR>", class.BasicVector)
+	defclass(e, "<GENERAL-VECTOR>", class.GeneralVector)
+	defclass(e, "<STRING>", class.String)
+	defclass(e, "<CHARACTER>", class.Character)
+	defclass(e, "<FUNCTION>", class.Function)
+	defclass(e, "<GENERIC-FUNCTION>", class.GenericFunction)
+	defclass(e, "<STANDARD-GENERIC-FUNCTION>", class.StandardGenericFunction)
+	defclass(e, "<LIST>", class.List)
+	defclass(e, "<CONS>", class.Cons)
+	defclass(e, "<NULL>", class.Null)
+	defclass(e, "<SYMBOL>", class.Symbol)
+	defclass(e, "<NUMBER>", class.Number)
+	defclass(e, "<INTEGER>", class.Integer)
+	defclass(e, "<FLOAT>", class.Float)
+	defclass(e, "<SERIOUS-CONDITION>", class.SeriousCondition)
+	defclass(e, "<ERROR>", class.Error)
+	defclass(e, "<ARITHMETIC-ERROR>", class.ArithmeticError)
+	defclass(e, "<DIVISION-BY-ZERO>", class.DivisionByZero)
+	defclass(e, "<FLOATING-POINT-ONDERFLOW>", class.FloatingPointOnderflow)
+	defclass(e, "<FLOATING-POINT-UNDERFLOW>", class.FloatingPointUnderflow)
+	defclass(e, "<CONTROL-ERROR>", class.ControlError)
+	defclass(e, "<PARSE-ERROR>", class.ParseError)
+	defclass(e, "<PROGRAM-ERROR>", class.ProgramError)
+	defclass(e, "<DOMAIN-ERROR>", class.DomainError)
+	defclass(e, "<UNDEFINED-ENTITY>", class.UndefinedEntity)
+	defclass(e, "<UNDEFINED-VARIABLE>", class.UndefinedVariable)
+	defclass(e, "<UNDEFINED-FUNCTION>", class.UndefinedFunction)
+	defclass(e, "<SIMPLE-ERROR>", class.SimpleError)
+	defclass(e, "<STREAM-ERROR>", class.StreamError)
+	defclass(e, "<END-OF-STREAM>", class.EndOfStream)
+	defclass(e, "<STORAGE-EXHAUSTED>", class.StorageExhausted)
+	defclass(e, "<STANDARD-OBJECT>", class.StandardObject)
+	defclass(e, "<STREAM>", class.Stream)
+	defclass(e, "<READTABLE>", class.Readtable)
+	defclass(e, "<INTERRUPTED>", class.Interrupted)
+	defclass(e, "<STEP-LIMIT-EXCEEDED>", class.StepLimitExceeded)
+	defclass(e, "<ALLOCATION-LIMIT-EXCEEDED>", class.AllocationLimitExceeded)
+	defclass(e, "<TIME-LIMIT-EXCEEDED>", class.TimeLimitExceeded)
+}
+
+func init() {
 	Time = time.Now()
 }
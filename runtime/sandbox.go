@@ -0,0 +1,65 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// privileged are the builtins which reach outside the interpreter, to files,
+// processes or the network. Any new builtin of that kind belongs here, so
+// that Confine removes it.
+var privileged = []string{
+	"OPEN-INPUT-FILE",
+	"OPEN-OUTPUT-FILE",
+	"OPEN-IO-FILE",
+	"PROBE-FILE",
+	"WITH-OPEN-INPUT-FILE",
+	"WITH-OPEN-OUTPUT-FILE",
+}
+
+// Confine removes the privileged builtins from e, and from every
+// environment made from it, so that code evaluated there can only reach
+// the outside through the standard streams and the functions defined by Go.
+func Confine(e env.Environment) {
+	for _, name := range privileged {
+		symbol := instance.NewSymbol(name)
+		e.Function.Global.Delete(symbol)
+		e.Special.Delete(symbol)
+	}
+}
+
+// allocate counts n conses, vector elements, characters of a string or bits
+// of an integer, as c is <cons>, <general-vector>, <string> or <integer>,
+// against the budget of e before they are allocated. It returns an <allocation-limit-exceeded> condition,
+// which no handler can resume, if they do not fit.
+func allocate(e env.Environment, c ilos.Class, n int) ilos.Instance {
+	b := e.Budget
+	if b == nil {
+		return nil
+	}
+	switch c {
+	case class.Cons:
+		if b.Conses += n; b.ConsLimit > 0 && b.Conses > b.ConsLimit {
+			return instance.Create(e, class.AllocationLimitExceeded)
+		}
+	case class.GeneralVector:
+		if b.Elements += n; b.ElementLimit > 0 && b.Elements > b.ElementLimit {
+			return instance.Create(e, class.AllocationLimitExceeded)
+		}
+	case class.String:
+		if b.StringLimit > 0 && n > b.StringLimit {
+			return instance.Create(e, class.AllocationLimitExceeded)
+		}
+	case class.Integer:
+		if b.IntegerLimit > 0 && n > b.IntegerLimit {
+			return instance.Create(e, class.AllocationLimitExceeded)
+		}
+	}
+	return nil
+}
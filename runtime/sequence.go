@@ -45,21 +45,21 @@ func Elt(e env.Environment, sequence, z ilos.Instance) (ilos.Instance, ilos.Inst
 	switch {
 	case ilos.InstanceOf(class.String, sequence):
 		seq := sequence.(instance.String)
-		idx := int(z.(instance.Integer))
+		idx := fixnum(z)
 		if idx > 0 && len(seq) <= idx {
 			return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 		}
 		return instance.NewCharacter(seq[idx]), nil
 	case ilos.InstanceOf(class.GeneralVector, sequence):
 		seq := sequence.(instance.GeneralVector)
-		idx := int(z.(instance.Integer))
+		idx := fixnum(z)
 		if idx > 0 && len(seq) <= idx {
 			return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 		}
 		return seq[idx], nil
 	case ilos.InstanceOf(class.List, sequence):
 		seq := sequence.(instance.List).Slice()
-		idx := int(z.(instance.Integer))
+		idx := fixnum(z)
 		if idx > 0 && len(seq) <= idx {
 			return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 		}
@@ -81,7 +81,7 @@ func SetElt(e env.Environment, obj, sequence, z ilos.Instance) (ilos.Instance, i
 	switch {
 	case ilos.InstanceOf(class.String, sequence):
 		seq := sequence.(instance.String)
-		idx := int(z.(instance.Integer))
+		idx := fixnum(z)
 		if idx > 0 && len(seq) <= idx {
 			return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 		}
@@ -92,7 +92,7 @@ func SetElt(e env.Environment, obj, sequence, z ilos.Instance) (ilos.Instance, i
 		return obj, nil
 	case ilos.InstanceOf(class.GeneralVector, sequence):
 		seq := sequence.(instance.GeneralVector)
-		idx := int(z.(instance.Integer))
+		idx := fixnum(z)
 		if idx > 0 && len(seq) <= idx {
 			return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 		}
@@ -100,7 +100,7 @@ func SetElt(e env.Environment, obj, sequence, z ilos.Instance) (ilos.Instance, i
 		return obj, nil
 	case ilos.InstanceOf(class.List, sequence):
 		seq := sequence.(instance.List).Slice()
-		idx := int(z.(instance.Integer))
+		idx := fixnum(z)
 		if idx > 0 && len(seq) <= idx {
 			return SignalCondition(e, instance.NewIndexOutOfRange(e), Nil)
 		}
@@ -127,8 +127,8 @@ func Subseq(e env.Environment, sequence, z1, z2 ilos.Instance) (ilos.Instance, i
 	if err := ensure(e, class.Integer, z1, z2); err != nil {
 		return nil, err
 	}
-	start := int(z1.(instance.Integer))
-	end := int(z2.(instance.Integer))
+	start := fixnum(z1)
+	end := fixnum(z2)
 	switch {
 	case ilos.InstanceOf(class.String, sequence):
 		seq := sequence.(instance.String)
@@ -185,7 +185,7 @@ func MapInto(e env.Environment, destination, function ilos.Instance, sequences .
 			return nil, err
 		}
 	}
-	for i := 0; i < int(min.(instance.Integer)); i++ {
+	for i := 0; i < fixnum(min); i++ {
 		arguments := make([]ilos.Instance, len(sequences))
 		for j, seq := range sequences {
 			var err ilos.Instance
@@ -194,7 +194,7 @@ func MapInto(e env.Environment, destination, function ilos.Instance, sequences .
 				return nil, err
 			}
 		}
-		ret, err := function.(instance.Applicable).Apply(e.NewDynamic(), arguments...)
+		ret, err := applyFunction(e.NewDynamic(), function, arguments)
 		if err != nil {
 			return nil, err
 		}
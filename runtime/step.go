@@ -0,0 +1,120 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"math"
+
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// pause pauses evaluation before form, which is about to be evaluated in e,
+// and sets where the stepper of e pauses next by what Pause returns.
+func pause(e env.Environment, form ilos.Instance) ilos.Instance {
+	st := e.Stepper
+	if st.Pause == nil || st.Paused {
+		return nil
+	}
+	st.Paused = true
+	action, err := st.Pause(e, form)
+	st.Paused = false
+	if err != nil {
+		return err
+	}
+	switch depth := e.Depth.Forms; action {
+	case env.StepInto:
+		st.Stepping, st.Until = true, math.MaxInt32
+	case env.StepOver:
+		st.Stepping, st.Until = true, depth
+	case env.StepOut:
+		st.Stepping, st.Until = true, depth-1
+	default:
+		st.Stepping = false
+	}
+	return nil
+}
+
+// stepTo pauses before form while stepping, if it is no deeper than the
+// stepper of e pauses at. It is called with the depth of form counted.
+func stepTo(e env.Environment, form ilos.Instance) ilos.Instance {
+	if e.Depth.Forms > e.Stepper.Until {
+		return nil
+	}
+	return pause(e, form)
+}
+
+// breakAt pauses before the call made by form if a breakpoint is set on
+// the function called, which is named by the form.
+func breakAt(e env.Environment, form ilos.Instance) ilos.Instance {
+	if cons, ok := form.(*instance.Cons); ok && e.Stepper.Breakpoints[cons.Car] {
+		return pause(e, form)
+	}
+	return nil
+}
+
+// Step evaluates form pausing before each form evaluated on the way, so
+// that it can be stepped through. step returns the value of form. It only
+// pauses if the host has set the Pause function of the stepper, as the REPL
+// does. This is an extension of iris.
+func Step(e env.Environment, form ilos.Instance) (ilos.Instance, ilos.Instance) {
+	st := e.Stepper
+	stepping, until := st.Stepping, st.Until
+	st.Stepping, st.Until = true, math.MaxInt32
+	ret, err := Eval(e, form)
+	st.Stepping, st.Until = stepping, until
+	return ret, err
+}
+
+// Break pauses evaluation where it is called, as a breakpoint does, and
+// returns nil. This is an extension of iris.
+func Break(e env.Environment) (ilos.Instance, ilos.Instance) {
+	// The pause is in the bindings of the caller.
+	var form ilos.Instance = Nil
+	if calls := e.Stack.Calls; len(calls) > 0 {
+		call := calls[len(calls)-1]
+		if call.Form != nil {
+			form = call.Form
+		}
+		e.Variable.Frame, e.Function.Frame = call.Variables, call.Functions
+	}
+	if err := pause(e, form); err != nil {
+		return nil, err
+	}
+	return Nil, nil
+}
+
+// SetBreakpoint sets a breakpoint on the function named function-name, so
+// that evaluation pauses before each call of it by name, and returns
+// function-name. The breakpoint lasts over redefinitions of the function.
+// An error shall be signaled if function-name is not a symbol (error-id.
+// domain-error). This is an extension of iris.
+func SetBreakpoint(e env.Environment, functionName ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := ensure(e, class.Symbol, functionName); err != nil {
+		return nil, err
+	}
+	e.Stepper.Breakpoints[functionName] = true
+	return functionName, nil
+}
+
+// RemoveBreakpoint removes the breakpoint on the function named
+// function-name, or every breakpoint if none is named, and returns nil.
+// This is an extension of iris.
+func RemoveBreakpoint(e env.Environment, functionName ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(functionName) > 1 {
+		return SignalCondition(e, instance.NewArityError(e), Nil)
+	}
+	if len(functionName) == 0 {
+		e.Stepper.Breakpoints = map[ilos.Instance]bool{}
+		return Nil, nil
+	}
+	if err := ensure(e, class.Symbol, functionName[0]); err != nil {
+		return nil, err
+	}
+	delete(e.Stepper.Breakpoints, functionName[0])
+	return Nil, nil
+}
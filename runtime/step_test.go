@@ -0,0 +1,107 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+func TestStep(t *testing.T) {
+	tests := []struct {
+		name        string
+		exp         string
+		action      env.StepAction
+		breakpoints []string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			name:   "into",
+			exp:    `(step (step-f (* 2 3)))`,
+			action: env.StepInto,
+			want:   []string{"(STEP-F (* 2 3))", "(* 2 3)", "(+ X 1)"},
+		},
+		{
+			name:   "over",
+			exp:    `(step (progn (step-f 1) (step-f 2)))`,
+			action: env.StepOver,
+			want:   []string{"(PROGN (STEP-F 1) (STEP-F 2))"},
+		},
+		{
+			name:   "out",
+			exp:    `(step (list (step-f 1) (step-f 2)))`,
+			action: env.StepOut,
+			want:   []string{"(LIST (STEP-F 1) (STEP-F 2))"},
+		},
+		{
+			name:   "continue",
+			exp:    `(step (step-f 1))`,
+			action: env.Continue,
+			want:   []string{"(STEP-F 1)"},
+		},
+		{
+			name:        "breakpoint",
+			exp:         `(list (step-f 1) (car '(2)) (step-f 3))`,
+			action:      env.Continue,
+			breakpoints: []string{"STEP-F"},
+			want:        []string{"(STEP-F 1)", "(STEP-F 3)"},
+		},
+		{
+			name:   "break",
+			exp:    `(progn (break) (step-f 1))`,
+			action: env.StepInto,
+			want:   []string{"(BREAK)", "(STEP-F 1)", "(+ X 1)"},
+		},
+		{
+			name:    "stop",
+			exp:     `(step (step-f 1))`,
+			action:  -1,
+			want:    []string{"(STEP-F 1)"},
+			wantErr: true,
+		},
+		{
+			name: "off",
+			exp:  `(step-f 1)`,
+			want: nil,
+		},
+	}
+	e := NewEnvironment(TopLevel.StandardInput, TopLevel.StandardOutput, TopLevel.ErrorOutput)
+	def, _ := readFromString(`(defun step-f (x) (+ x 1))`)
+	if _, err := Eval(e, def); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			e.Stepper.Pause = func(e env.Environment, form ilos.Instance) (env.StepAction, ilos.Instance) {
+				got = append(got, form.String())
+				if tt.action < 0 {
+					return env.Continue, instance.Create(e, class.Interrupted)
+				}
+				return tt.action, nil
+			}
+			for _, name := range tt.breakpoints {
+				SetBreakpoint(e, instance.NewSymbol(name))
+			}
+			defer RemoveBreakpoint(e)
+			obj, _ := readFromString(tt.exp)
+			if _, err := Eval(e, obj); (err != nil) != tt.wantErr {
+				t.Errorf("Eval() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paused at %v, want %v", got, tt.want)
+			}
+			if e.Stepper.Stepping {
+				t.Errorf("Stepping = true after the evaluation")
+			}
+		})
+	}
+}
@@ -164,9 +164,9 @@ func WithOpenInputFile(e env.Environment, fileSpec ilos.Instance, forms ...ilos.
 	if err != nil {
 		return nil, err
 	}
-	e.Variable.Define(car, s)
-	r, err := Progn(e, forms...)
-	e.Variable.Delete(car)
+	ne := e
+	ne.Variable = e.Variable.Push([]ilos.Instance{car}, []ilos.Instance{s})
+	r, err := Progn(ne, forms...)
 	if _, err := Close(e, s); err != nil {
 		return nil, err
 	}
@@ -193,9 +193,9 @@ func WithOpenOutputFile(e env.Environment, fileSpec ilos.Instance, forms ...ilos
 	if err != nil {
 		return nil, err
 	}
-	e.Variable.Define(car, s)
-	r, err := Progn(e, forms...)
-	e.Variable.Delete(car)
+	ne := e
+	ne.Variable = e.Variable.Push([]ilos.Instance{car}, []ilos.Instance{s})
+	r, err := Progn(ne, forms...)
 	if _, err := Close(e, s); err != nil {
 		return nil, err
 	}
@@ -222,9 +222,9 @@ func WithOpenIoFile(e env.Environment, fileSpec ilos.Instance, forms ...ilos.Ins
 	if err != nil {
 		return nil, err
 	}
-	e.Variable.Define(car, cadr)
-	r, err := Progn(e, forms...)
-	e.Variable.Delete(car)
+	ne := e
+	ne.Variable = e.Variable.Push([]ilos.Instance{car}, []ilos.Instance{cadr})
+	r, err := Progn(ne, forms...)
 	if _, err := Close(e, s); err != nil {
 		return nil, err
 	}
@@ -284,10 +284,24 @@ func GetOutputStreamString(e env.Environment, stream ilos.Instance) (ilos.Instan
 	stream.(instance.Stream).Flush()
 	out := instance.NewString([]rune(stream.(instance.Stream).BufferedWriter.Raw.(*bytes.Buffer).String()))
 	stream.(instance.Stream).BufferedWriter.Raw.(*bytes.Buffer).Reset()
+	if err := allocate(e, class.String, len(out.(instance.String))); err != nil {
+		return nil, err
+	}
 	return out, nil
 }
 
 func Read(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return read(e, parser.Read, options...)
+}
+
+// ReadForm reads a form to be evaluated as Read does, and records where each
+// of its conses was read from, for the locations of the conditions it
+// signals, coverage and stepping.
+func ReadForm(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return read(e, parser.ReadForm, options...)
+}
+
+func read(e env.Environment, parse func(env.Environment, ilos.Instance) (ilos.Instance, ilos.Instance), options ...ilos.Instance) (ilos.Instance, ilos.Instance) {
 	s := e.StandardInput
 	if len(options) > 0 {
 		s = options[0]
@@ -307,13 +321,19 @@ func Read(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.Inst
 			eosValue = options[2]
 		}
 	}
-	v, err := parser.Parse(s.(instance.Stream).Reader)
+	if err := waitInput(e, s); err != nil {
+		return nil, err
+	}
+	v, err := parse(e, s)
 	if err != nil && ilos.InstanceOf(class.EndOfStream, err) {
 		if eosErrorP {
 			return nil, err
 		}
 		return eosValue, nil
 	}
+	if err != nil {
+		return SignalCondition(e, err, Nil)
+	}
 	return v, nil
 }
 
@@ -337,6 +357,9 @@ func ReadChar(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.
 			eosValue = options[2]
 		}
 	}
+	if err := waitInput(e, s); err != nil {
+		return nil, err
+	}
 	//v, _, err := bufio.NewReader(s.(instance.Stream).Reader).ReadRune()
 	v, _, err := s.(instance.Stream).ReadRune()
 	if err != nil {
@@ -382,6 +405,9 @@ func PreviewChar(e env.Environment, options ...ilos.Instance) (ilos.Instance, il
 			eosValue = options[2]
 		}
 	}
+	if err := waitInput(e, s); err != nil {
+		return nil, err
+	}
 	//v, _, err := bufio.NewReader(s.(instance.Stream).Reader).ReadRune()
 	bytes, err := s.(instance.Stream).Peek(1)
 	if err != nil {
@@ -413,6 +439,9 @@ func ReadLine(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.
 			eosValue = options[2]
 		}
 	}
+	if err := waitInput(e, s); err != nil {
+		return nil, err
+	}
 	v, _, err := s.(instance.Stream).ReadLine()
 	if err != nil {
 		if eosErrorP {
@@ -423,6 +452,14 @@ func ReadLine(e env.Environment, options ...ilos.Instance) (ilos.Instance, ilos.
 	return instance.NewString([]rune(string(v))), nil
 }
 
+// waitInput waits until the input stream s has input. It returns an
+// <interrupted> condition if the context of e is done first, or while s
+// was waited on.
+func waitInput(e env.Environment, s ilos.Instance) ilos.Instance {
+	s.(instance.Stream).Wait(e.Context.Done())
+	return interrupted(e)
+}
+
 func StreamReadyP(e env.Environment, inputStream ilos.Instance) (ilos.Instance, ilos.Instance) {
 	// TODO: stream-ready-p
 	return T, nil
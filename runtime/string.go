@@ -29,13 +29,16 @@ func Stringp(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance
 // cannot-create-string). An error shall be signaled if i is not a non-negative
 // integer or if initial-character is not a character (error-id. domain-error).
 func CreateString(e env.Environment, i ilos.Instance, initialElement ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if !ilos.InstanceOf(class.Integer, i) || int(i.(instance.Integer)) < 0 {
-		return SignalCondition(e, instance.NewDomainError(e, i, class.Object), Nil)
+	n, err := sequenceLength(e, i)
+	if err != nil {
+		return nil, err
 	}
 	if len(initialElement) > 1 {
 		return SignalCondition(e, instance.NewArityError(e), Nil)
 	}
-	n := int(i.(instance.Integer))
+	if err := allocate(e, class.String, n); err != nil {
+		return nil, err
+	}
 	v := make([]rune, n)
 	for i := 0; i < n; i++ {
 		if len(initialElement) == 0 {
@@ -137,7 +140,7 @@ func CharIndex(e env.Environment, char, str ilos.Instance, startPosition ...ilos
 		if err := ensure(e, class.Integer, startPosition[0]); err != nil {
 			return nil, err
 		}
-		n = int(startPosition[0].(instance.Integer))
+		n = fixnum(startPosition[0])
 	}
 	s := string(str.(instance.String)[n:])
 	c := rune(char.(instance.Character))
@@ -171,7 +174,7 @@ func StringIndex(e env.Environment, sub, str ilos.Instance, startPosition ...ilo
 		if err := ensure(e, class.Integer, startPosition[0]); err != nil {
 			return nil, err
 		}
-		n = int(startPosition[0].(instance.Integer))
+		n = fixnum(startPosition[0])
 	}
 	s := string(str.(instance.String)[n:])
 	c := string(sub.(instance.String))
@@ -197,5 +200,9 @@ func StringAppend(e env.Environment, str ...ilos.Instance) (ilos.Instance, ilos.
 		}
 		ret += string(s.(instance.String))
 	}
-	return instance.NewString([]rune(ret)), nil
+	runes := []rune(ret)
+	if err := allocate(e, class.String, len(runes)); err != nil {
+		return nil, err
+	}
+	return instance.NewString(runes), nil
 }
@@ -29,6 +29,21 @@ func TestCreateString(t *testing.T) {
 			want:    `""`,
 			wantErr: false,
 		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (instancep c (class <storage-exhausted>)))) (create-string 100000000000000000000 #\a)))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (instancep c (class <storage-exhausted>)))) (create-string 4294967296 #\a)))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(create-string -1 #\a)`,
+			want:    `nil`,
+			wantErr: true,
+		},
 	})
 }
 
@@ -0,0 +1,186 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// traced is a global function which is traced. It is bound in place of the
+// function, and prints each call of it and what the call returns to the
+// error output, indented by the number of traced calls in progress.
+type traced struct {
+	name     ilos.Instance
+	function ilos.Instance
+}
+
+func (t traced) Class() ilos.Class {
+	return t.function.Class()
+}
+
+func (t traced) String() string {
+	return t.function.String()
+}
+
+// Name returns the name the function is traced under.
+func (t traced) Name() ilos.Instance {
+	return t.name
+}
+
+// Arity returns the number of arguments the traced function takes.
+func (t traced) Arity() instance.Arity {
+	if f, ok := t.function.(interface{ Arity() instance.Arity }); ok {
+		return f.Arity()
+	}
+	return instance.Arity{Rest: true}
+}
+
+// traceDepth is the dynamic variable counting the traced calls in progress.
+var traceDepth = instance.NewSymbol("IRIS/TRACE-DEPTH")
+
+func (t traced) Apply(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	depth := 0
+	if d, ok := e.DynamicVariable.Get(traceDepth); ok {
+		depth = int(d.(instance.Integer))
+	}
+	e.DynamicVariable = e.DynamicVariable.Push(map[ilos.Instance]ilos.Instance{traceDepth: instance.NewInteger(depth + 1)})
+	// The traced function runs its tail calls itself, so that it has
+	// returned when its value is printed.
+	e.TailCall = false
+	line := describeCall(nil, env.Call{Function: t.name, Arguments: arguments})
+	if g, ok := t.function.(*instance.GenericFunction); ok {
+		qualifiers, classLists := g.Methods(arguments...)
+		methods := []string{}
+		for i, classList := range classLists {
+			words := []string{}
+			if qualifiers[i] != nil {
+				words = append(words, qualifiers[i].String())
+			}
+			for _, c := range classList {
+				words = append(words, c.String())
+			}
+			methods = append(methods, "("+strings.Join(words, " ")+")")
+		}
+		line += " using " + strings.Join(methods, " ")
+	}
+	if err := traceLine(e, depth, line); err != nil {
+		return nil, err
+	}
+	ret, err := t.function.(instance.Applicable).Apply(e, arguments...)
+	switch {
+	case err == nil:
+		line = fmt.Sprintf("%v returned %v", t.name, ret)
+	case ilos.InstanceOf(class.SeriousCondition, err):
+		line = fmt.Sprintf("%v signalled %v", t.name, err)
+	default:
+		line = fmt.Sprintf("%v exited", t.name)
+	}
+	if err := traceLine(e, depth, line); err != nil {
+		return nil, err
+	}
+	return ret, err
+}
+
+// traceLine prints line to the error output of e, indented to depth.
+func traceLine(e env.Environment, depth int, line string) ilos.Instance {
+	line = fmt.Sprintf("%v%v: %v", strings.Repeat("  ", depth), depth, line)
+	if _, err := Format(e, e.ErrorOutput, instance.NewString([]rune("~A~%")), instance.NewString([]rune(line))); err != nil {
+		return err
+	}
+	_, err := FinishOutput(e, e.ErrorOutput)
+	return err
+}
+
+// retraced returns function traced if the global function named name, which
+// function is about to replace, is traced, so that tracing a function lasts
+// over its redefinitions.
+func retraced(e env.Environment, name, function ilos.Instance) ilos.Instance {
+	if old, ok := e.Function.Global.Get(name); ok {
+		if _, ok := old.(traced); ok {
+			return traced{name, function}
+		}
+	}
+	return function
+}
+
+// untraced returns the function which function traces, or function itself
+// if it is not traced.
+func untraced(function ilos.Instance) ilos.Instance {
+	if t, ok := function.(traced); ok {
+		return t.function
+	}
+	return function
+}
+
+// Trace traces the global functions or generic functions named by
+// function-names: each call of them is printed with its arguments to the
+// error output, then what it returns, indented by the number of traced calls
+// in progress. The call of a generic function is printed with the methods
+// which apply to its arguments. A function stays traced when it is defined
+// again. trace returns the list of function-names, or of every traced
+// function if none is given. An error shall be signaled if a function-name
+// is not the name of a global function (error-id. undefined-function). This
+// is an extension of iris.
+func Trace(e env.Environment, functionNames ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(functionNames) == 0 {
+		return List(e, tracedNames(e)...)
+	}
+	for _, name := range functionNames {
+		if err := ensure(e, class.Symbol, name); err != nil {
+			return nil, err
+		}
+		function, ok := e.Function.Global.Get(name)
+		if !ok {
+			return SignalCondition(e, instance.NewUndefinedFunction(e, name), Nil)
+		}
+		if _, ok := function.(traced); !ok {
+			e.Function.Global.Set(name, traced{name, function})
+		}
+	}
+	return List(e, functionNames...)
+}
+
+// Untrace stops tracing the functions named by function-names, or every
+// traced function if none is given, and returns the list of the functions
+// which it stopped tracing. This is an extension of iris.
+func Untrace(e env.Environment, functionNames ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if len(functionNames) == 0 {
+		functionNames = tracedNames(e)
+	}
+	names := []ilos.Instance{}
+	for _, name := range functionNames {
+		if err := ensure(e, class.Symbol, name); err != nil {
+			return nil, err
+		}
+		if function, ok := e.Function.Global.Get(name); ok {
+			if t, ok := function.(traced); ok {
+				e.Function.Global.Set(name, t.function)
+				names = append(names, name)
+			}
+		}
+	}
+	return List(e, names...)
+}
+
+// tracedNames returns the names of the traced functions.
+func tracedNames(e env.Environment) []ilos.Instance {
+	names := []ilos.Instance{}
+	for _, name := range e.Function.Global.Keys() {
+		if function, _ := e.Function.Global.Get(name); function != nil {
+			if _, ok := function.(traced); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].String() < names[j].String() })
+	return names
+}
@@ -0,0 +1,112 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import "testing"
+
+func TestTrace(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(defun trace-fact (n) (if (= n 0) 1 (* n (trace-fact (- n 1)))))`,
+			want:    `'trace-fact`,
+			wantErr: false,
+		},
+		{
+			exp:     `(trace trace-fact)`,
+			want:    `'(trace-fact)`,
+			wantErr: false,
+		},
+		{
+			exp: `
+				(let ((s (create-string-output-stream)))
+					(with-error-output s (trace-fact 1))
+					(get-output-stream-string s))
+				`,
+			want: `"0: (TRACE-FACT 1)
+  1: (TRACE-FACT 0)
+  1: TRACE-FACT returned 1
+0: TRACE-FACT returned 1
+"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defun trace-fact (n) n)`,
+			want:    `'trace-fact`,
+			wantErr: false,
+		},
+		{
+			exp: `
+				(let ((s (create-string-output-stream)))
+					(with-error-output s (trace-fact 2))
+					(get-output-stream-string s))
+				`,
+			want: `"0: (TRACE-FACT 2)
+0: TRACE-FACT returned 2
+"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defgeneric trace-generic (x))`,
+			want:    `'trace-generic`,
+			wantErr: false,
+		},
+		{
+			exp:     `(trace trace-generic)`,
+			want:    `'(trace-generic)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defmethod trace-generic ((x <integer>)) (list x))`,
+			want:    `'trace-generic`,
+			wantErr: false,
+		},
+		{
+			exp:     `(defmethod trace-generic ((x <number>)) x)`,
+			want:    `'trace-generic`,
+			wantErr: false,
+		},
+		{
+			exp: `
+				(let ((s (create-string-output-stream)))
+					(with-error-output s (trace-generic 1))
+					(get-output-stream-string s))
+				`,
+			want: `"0: (TRACE-GENERIC 1) using (<INTEGER>) (<NUMBER>)
+0: TRACE-GENERIC returned (1)
+"`,
+			wantErr: false,
+		},
+		{
+			exp:     `(trace)`,
+			want:    `'(trace-fact trace-generic)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(untrace trace-fact)`,
+			want:    `'(trace-fact)`,
+			wantErr: false,
+		},
+		{
+			exp: `
+				(let ((s (create-string-output-stream)))
+					(with-error-output s (trace-fact 3))
+					(get-output-stream-string s))
+				`,
+			want:    `""`,
+			wantErr: false,
+		},
+		{
+			exp:     `(untrace)`,
+			want:    `'(trace-generic)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(trace trace-undefined)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+	}
+	execTests(t, Trace, tests)
+}
@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
+	"sync/atomic"
 
 	"github.com/islisp-dev/iris/reader/parser"
 	"github.com/islisp-dev/iris/reader/tokenizer"
@@ -31,9 +32,9 @@ func isProperList(i ilos.Instance) bool {
 func convFloat64(e env.Environment, x ilos.Instance) (float64, bool, ilos.Instance) {
 	switch {
 	case ilos.InstanceOf(class.Integer, x):
-		return float64(x.(instance.Integer)), false, nil
+		return float(x), false, nil
 	case ilos.InstanceOf(class.Float, x):
-		return float64(x.(instance.Float)), true, nil
+		return float(x), true, nil
 	default:
 		_, err := SignalCondition(e, instance.NewDomainError(e, x, class.Number), Nil)
 		return 0.0, false, err
@@ -54,12 +55,12 @@ func ensure(e env.Environment, c ilos.Class, i ...ilos.Instance) ilos.Instance {
 	return nil
 }
 
-var unique = 0
+// unique is the last number returned by uniqueInt. It is shared by all the
+// interpreters, which may run in goroutines of their own.
+var unique int64 = -1
 
 func uniqueInt() int {
-	i := unique
-	unique++
-	return i
+	return int(atomic.AddInt64(&unique, 1))
 }
 
 func func2symbol(function interface{}) ilos.Instance {
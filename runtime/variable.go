@@ -58,7 +58,7 @@ func Setf(e env.Environment, var1, form ilos.Instance) (ilos.Instance, ilos.Inst
 // body (or nil if there is none). No var may appear more than once in let
 // variable list.
 func Let(e env.Environment, varForm ilos.Instance, bodyForm ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	vfs := map[ilos.Instance]ilos.Instance{}
+	names, values := []ilos.Instance{}, []ilos.Instance{}
 	if err := ensure(e, class.List, varForm); err != nil {
 		return nil, err
 	}
@@ -73,13 +73,10 @@ func Let(e env.Environment, varForm ilos.Instance, bodyForm ...ilos.Instance) (i
 		if err != nil {
 			return nil, err
 		}
-		vfs[cadr.(instance.List).Nth(0)] = f
-	}
-	for v, f := range vfs {
-		if !e.Variable.Define(v, f) {
-			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
-		}
+		names = append(names, cadr.(instance.List).Nth(0))
+		values = append(values, f)
 	}
+	e.Variable = e.Variable.Push(names, values)
 	return Progn(e, bodyForm...)
 }
 
@@ -100,6 +97,7 @@ func LetStar(e env.Environment, varForm ilos.Instance, bodyForm ...ilos.Instance
 	if err := ensure(e, class.List, varForm); err != nil {
 		return nil, err
 	}
+	e.Variable = e.Variable.Push(nil, nil)
 	for _, cadr := range varForm.(instance.List).Slice() {
 		if err := ensure(e, class.List, cadr); err != nil {
 			return nil, err
@@ -111,7 +109,7 @@ func LetStar(e env.Environment, varForm ilos.Instance, bodyForm ...ilos.Instance
 		if err != nil {
 			return nil, err
 		}
-		if !e.Variable.Define(cadr.(instance.List).Nth(0), f) {
+		if !e.Variable.Frame.Define(cadr.(instance.List).Nth(0), f) {
 			return SignalCondition(e, instance.NewImmutableBinding(e), Nil)
 		}
 	}
@@ -16,3 +16,29 @@ func TestSetf(t *testing.T) {
 	}
 	execTests(t, Setf, tests)
 }
+
+func TestLet(t *testing.T) {
+	tests := []test{
+		{
+			exp:     `(let ((x 1)) (let ((x 2) (y x)) (list x y)))`,
+			want:    `'(2 1)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let ((x 1)) (let* ((y (+ x 1)) (x (+ y 1))) (setq y 5) (list x y)))`,
+			want:    `'(3 5)`,
+			wantErr: false,
+		},
+		{
+			exp:     `(let* ((x 1) (x 2)) x)`,
+			want:    `nil`,
+			wantErr: true,
+		},
+		{
+			exp:     `(let ((x 1)) (with-standard-input (create-string-input-stream "a") (let ((y x)) (setq x (+ y 1)))) x)`,
+			want:    `2`,
+			wantErr: false,
+		},
+	}
+	execTests(t, Let, tests)
+}
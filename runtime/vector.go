@@ -36,13 +36,16 @@ func GeneralVectorP(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.I
 // cannot-create-vector). An error shall be signaled if i is not a non-negative
 // integer (error-id. domain-error). initial-element may be any ISLISP object.
 func CreateVector(e env.Environment, i ilos.Instance, initialElement ...ilos.Instance) (ilos.Instance, ilos.Instance) {
-	if !ilos.InstanceOf(class.Integer, i) || int(i.(instance.Integer)) < 0 {
-		return SignalCondition(e, instance.NewDomainError(e, i, class.Integer), Nil)
+	n, err := sequenceLength(e, i)
+	if err != nil {
+		return nil, err
 	}
 	if len(initialElement) > 1 {
 		return SignalCondition(e, instance.NewArityError(e), Nil)
 	}
-	n := int(i.(instance.Integer))
+	if err := allocate(e, class.GeneralVector, n); err != nil {
+		return nil, err
+	}
 	v := make([]ilos.Instance, n)
 	for i := 0; i < n; i++ {
 		if len(initialElement) == 0 {
@@ -60,5 +63,8 @@ func CreateVector(e env.Environment, i ilos.Instance, initialElement ...ilos.Ins
 // dimension−1. An error shall be signaled if the requested vector cannot be
 // allocated (error-id. cannot-create-vector). Each obj may be any ISLISP object.
 func Vector(e env.Environment, obj ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if err := allocate(e, class.GeneralVector, len(obj)); err != nil {
+		return nil, err
+	}
 	return instance.GeneralVector(obj), nil
 }
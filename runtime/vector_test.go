@@ -33,6 +33,21 @@ func TestCreateVector(t *testing.T) {
 			want:    `#(#\a #\a)`,
 			wantErr: false,
 		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (instancep c (class <storage-exhausted>)))) (create-vector 100000000000000000000)))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(catch 'c (with-handler (lambda (c) (throw 'c (instancep c (class <storage-exhausted>)))) (create-vector 4294967296)))`,
+			want:    `t`,
+			wantErr: false,
+		},
+		{
+			exp:     `(create-vector -1)`,
+			want:    `nil`,
+			wantErr: true,
+		},
 	})
 }
 
@@ -0,0 +1,586 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/islisp-dev/iris/runtime/env"
+	"github.com/islisp-dev/iris/runtime/ilos"
+	"github.com/islisp-dev/iris/runtime/ilos/class"
+	"github.com/islisp-dev/iris/runtime/ilos/instance"
+)
+
+// vmFrame holds the variables, local functions and the uids of blocks and
+// tagbodies bound by a function or a binding form run by the machine.
+type vmFrame struct {
+	slots []ilos.Instance
+	up    *vmFrame
+}
+
+func (f *vmFrame) outer(depth int32) *vmFrame {
+	for ; depth > 0; depth-- {
+		f = f.up
+	}
+	return f
+}
+
+// Closure is a function compiled to bytecode, with the frame it was made in.
+// It may be called as any other function.
+type Closure struct {
+	proto *proto
+	frame *vmFrame
+}
+
+func (*Closure) Class() ilos.Class {
+	return class.Function
+}
+
+func (c *Closure) String() string {
+	return fmt.Sprintf("#%v", c.Class())
+}
+
+// Name returns the name of the function c was made from.
+func (c *Closure) Name() ilos.Instance {
+	return c.proto.name
+}
+
+// Arity returns the number of arguments c takes.
+func (c *Closure) Arity() instance.Arity {
+	return instance.Arity{Required: c.proto.parameters, Rest: c.proto.variadic}
+}
+
+func (c *Closure) Apply(e env.Environment, arguments ...ilos.Instance) (ilos.Instance, ilos.Instance) {
+	return execute(e, c, arguments)
+}
+
+// Execute evaluates obj as Eval does, by compiling it to bytecode and running
+// it on the virtual machine. Special forms the machine does not know, and
+// functions defined by them, are left to Eval.
+func Execute(e env.Environment, obj ilos.Instance) (ilos.Instance, ilos.Instance) {
+	if _, ok := obj.(*instance.Cons); !ok {
+		return Eval(e, obj)
+	}
+	return execute(e, &Closure{proto: compileProto(e, obj)}, nil)
+}
+
+// handler is a block, catch, tagbody, unwind-protect, dynamic-let or
+// with-handler established by the running function. An exit to it restores
+// the stack, the frame and the environment it was established with.
+type handler struct {
+	op     opcode
+	uid    ilos.Instance
+	tags   map[ilos.Instance]ilos.Instance // the catch tags it pushed
+	table  map[ilos.Instance]int           // the targets of the tags of a tagbody
+	index  int32                           // the slot of the uid in the frame
+	target int
+	sp     int
+	frame  *vmFrame
+	env    env.Environment
+}
+
+// disestablish makes the exit point of h unusable.
+func (h *handler) disestablish() {
+	switch h.op {
+	case opPushBlock, opPushTagbody:
+		h.frame.slots[h.index] = nil
+	case opPushCatch:
+		for tag := range h.tags {
+			delete(h.tags, tag)
+		}
+	}
+}
+
+// execute calls closure, counting the call as one level of evaluation.
+func execute(e env.Environment, closure *Closure, arguments []ilos.Instance) (ilos.Instance, ilos.Instance) {
+	var ret, err ilos.Instance
+	e.TailCall = false
+	depth := e.Depth
+	depth.Current++
+	if depth.Limit > 0 && (depth.Current == depth.Limit+1 || depth.Current > depth.Limit+depthReserve) {
+		ret, err = exhausted(e)
+	} else {
+		base := len(e.Stack.Calls)
+		ret, err = run(e, closure, arguments)
+		e.Stack.Pop(base)
+	}
+	depth.Current--
+	return ret, err
+}
+
+// bind makes the frame of a call to closure. If the arguments do not match
+// its parameters it returns the result of signalling that instead.
+func bind(e env.Environment, closure *Closure, arguments []ilos.Instance) (*vmFrame, ilos.Instance, ilos.Instance) {
+	p := closure.proto
+	if len(arguments) < p.parameters || (!p.variadic && len(arguments) != p.parameters) {
+		ret, err := SignalCondition(e, instance.NewArityError(e), Nil)
+		return nil, ret, err
+	}
+	slots := make([]ilos.Instance, p.size)
+	copy(slots, arguments[:p.parameters])
+	if p.variadic {
+		slots[p.parameters], _ = List(e, arguments[p.parameters:]...)
+	}
+	return &vmFrame{slots, closure.frame}, nil, nil
+}
+
+// invoke calls function with arguments. A closure runs in the same
+// environment, and any other function in a new dynamic one as Eval calls it.
+func invoke(e env.Environment, function ilos.Instance, arguments []ilos.Instance) (ilos.Instance, ilos.Instance) {
+	switch f := function.(type) {
+	case *Closure:
+		return execute(e, f, arguments)
+	case instance.Function:
+		// Only a function with a rest parameter may keep its arguments.
+		if !f.Arity().Rest {
+			return f.Apply(e.NewDynamic(), arguments...)
+		}
+		return f.Apply(e.NewDynamic(), append([]ilos.Instance(nil), arguments...)...)
+	case instance.Applicable:
+		return f.Apply(e.NewDynamic(), append([]ilos.Instance(nil), arguments...)...)
+	}
+	return SignalCondition(e, instance.NewDomainError(e, function, class.Function), Nil)
+}
+
+// run runs the code of closure until it returns. A condition signalled by an
+// instruction goes to the innermost handler which takes it, or out of the
+// function if there is none.
+func run(e env.Environment, closure *Closure, arguments []ilos.Instance) (ilos.Instance, ilos.Instance) {
+	fr, ret, err := bind(e, closure, arguments)
+	if fr == nil {
+		return ret, err
+	}
+	p := closure.proto
+	code := p.code
+	base := len(e.Stack.Calls)
+	stack := make([]ilos.Instance, 0, 16)
+	handlers := []handler{}
+	pc := 0
+	for {
+		at := pc
+		op := opcode(code[pc])
+		pc += 1 + operands[op]
+		var v ilos.Instance
+		switch op {
+		case opConst:
+			stack = append(stack, p.constants[code[at+1]])
+		case opLocal:
+			stack = append(stack, fr.outer(code[at+1]).slots[code[at+2]])
+		case opSetLocal:
+			fr.outer(code[at+1]).slots[code[at+2]] = stack[len(stack)-1]
+		case opGlobal:
+			if v, err = evalVariable(e, p.constants[code[at+1]]); err == nil {
+				stack = append(stack, v)
+			}
+		case opSetGlobal:
+			if !e.Variable.Set(p.constants[code[at+1]], stack[len(stack)-1]) {
+				if v, err = SignalCondition(e, instance.NewUndefinedVariable(e, p.constants[code[at+1]]), Nil); err == nil {
+					stack[len(stack)-1] = v
+				}
+			}
+		case opDynamic:
+			name := p.constants[code[at+1]]
+			var ok bool
+			if v, ok = e.DynamicVariable.Get(name); !ok {
+				v, err = SignalCondition(e, instance.NewUndefinedVariable(e, name), Nil)
+			}
+			if err == nil {
+				stack = append(stack, v)
+			}
+		case opFunction:
+			name := p.constants[code[at+1]]
+			var ok bool
+			if v, ok = e.Function.Get(name); !ok {
+				v, err = SignalCondition(e, instance.NewUndefinedFunction(e, name), Nil)
+			}
+			if err == nil {
+				stack = append(stack, v)
+			}
+		case opCallee:
+			name := p.constants[code[at+1]]
+			if f, ok := e.Function.Get(name); ok {
+				stack = append(stack, f)
+				break
+			}
+			if _, ok := e.Macro.Get(name); ok {
+				v, err = fallbackTo(e, fr, p.fallbacks[code[at+2]])
+			} else {
+				v, err = SignalCondition(e, instance.NewUndefinedFunction(e, name), Nil)
+			}
+			if err == nil {
+				stack = append(stack, v)
+				pc = int(code[at+3])
+			}
+		case opExpanded:
+			if int32(*e.Macros) != code[at+1] {
+				if v, err = fallbackTo(e, fr, p.fallbacks[code[at+2]]); err == nil {
+					stack = append(stack, v)
+					pc = int(code[at+3])
+				}
+			}
+		case opClosure:
+			stack = append(stack, &Closure{p.protos[code[at+1]], fr})
+		case opCall:
+			if err = step(e); err != nil {
+				break
+			}
+			n := len(stack) - int(code[at+1])
+			height := e.Stack.Push(env.Call{Form: p.forms[at], Function: stack[n-1], Arguments: stack[n:]})
+			if v, err = invoke(e, stack[n-1], stack[n:]); err != nil {
+				attachBacktrace(e, err)
+			}
+			e.Stack.Pop(height)
+			stack = stack[:n-1]
+			if err == nil {
+				stack = append(stack, v)
+			}
+		case opTailCall:
+			if err = step(e); err != nil {
+				break
+			}
+			n := len(stack) - int(code[at+1])
+			c, ok := stack[n-1].(*Closure)
+			if !ok {
+				height := e.Stack.Push(env.Call{Form: p.forms[at], Function: stack[n-1], Arguments: stack[n:]})
+				if v, err = invoke(e, stack[n-1], stack[n:]); err != nil {
+					attachBacktrace(e, err)
+				}
+				e.Stack.Pop(height)
+				if err == nil {
+					return v, nil
+				}
+				break
+			}
+			var f *vmFrame
+			if f, v, err = bind(e, c, stack[n:]); f == nil {
+				if err == nil {
+					return v, nil
+				}
+				break
+			}
+			// The call goes on the stack of calls in place of any earlier
+			// tail call, with its parameters as its arguments.
+			parameters := c.proto.parameters
+			if c.proto.variadic {
+				parameters++
+			}
+			e.Stack.Pop(base)
+			e.Stack.Push(env.Call{Form: p.forms[at], Function: c, Arguments: f.slots[:parameters]})
+			p, code, fr, pc = c.proto, c.proto.code, f, 0
+			stack = stack[:0]
+		case opReturn:
+			return stack[len(stack)-1], nil
+		case opPop:
+			stack = stack[:len(stack)-1]
+		case opJump:
+			pc = int(code[at+1])
+			if pc < at {
+				// the back edge of a loop
+				err = step(e)
+			}
+		case opJumpIfNil:
+			if stack[len(stack)-1] == Nil {
+				pc = int(code[at+1])
+			}
+			stack = stack[:len(stack)-1]
+		case opJumpUnlessNil:
+			if stack[len(stack)-1] != Nil {
+				pc = int(code[at+1])
+			}
+			stack = stack[:len(stack)-1]
+		case opJumpUnlessT:
+			if stack[len(stack)-1] != T {
+				pc = int(code[at+1])
+			}
+			stack = stack[:len(stack)-1]
+		case opAnd:
+			if stack[len(stack)-1] == Nil {
+				pc = int(code[at+1])
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+		case opOr:
+			if stack[len(stack)-1] != Nil {
+				pc = int(code[at+1])
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+		case opCase:
+			key := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		clauses:
+			for _, clause := range p.cases[code[at+1]] {
+				if clause.fallback {
+					pc = clause.target
+					break
+				}
+				for _, k := range clause.keys {
+					if ok, _ := Eql(e, k, key); ok == T {
+						pc = clause.target
+						break clauses
+					}
+				}
+			}
+		case opCaseUsing:
+			predicate, key := stack[len(stack)-1], stack[len(stack)-2]
+			stack = stack[:len(stack)-2]
+			if err = ensure(e, class.Function, predicate); err != nil {
+				break
+			}
+		usingClauses:
+			for _, clause := range p.cases[code[at+1]] {
+				if clause.fallback {
+					pc = clause.target
+					break
+				}
+				for _, k := range clause.keys {
+					if v, err = predicate.(instance.Applicable).Apply(e.NewDynamic(), k, key); err != nil {
+						break usingClauses
+					}
+					if v != Nil {
+						pc = clause.target
+						break usingClauses
+					}
+				}
+			}
+		case opEnter:
+			fr = &vmFrame{make([]ilos.Instance, code[at+1]), fr}
+		case opLeave:
+			fr = fr.up
+		case opBind:
+			fr.slots[code[at+1]] = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		case opPushBlock:
+			uid := instance.NewInteger(uniqueInt())
+			fr.slots[code[at+1]] = uid
+			handlers = append(handlers, handler{op: op, uid: uid, index: code[at+1], target: int(code[at+2]), sp: len(stack), frame: fr, env: e})
+		case opPushCatch:
+			tag := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			uid := instance.NewInteger(uniqueInt())
+			tags := map[ilos.Instance]ilos.Instance{tag: uid}
+			handlers = append(handlers, handler{op: op, uid: uid, tags: tags, target: int(code[at+1]), sp: len(stack), frame: fr, env: e})
+			e.CatchTag = e.CatchTag.Push(tags)
+		case opPushTagbody:
+			uid := instance.NewInteger(uniqueInt())
+			fr.slots[code[at+1]] = uid
+			handlers = append(handlers, handler{op: op, uid: uid, index: code[at+1], table: p.tables[code[at+2]], sp: len(stack), frame: fr, env: e})
+		case opPushUnwind, opPushGuard:
+			handlers = append(handlers, handler{op: op, target: int(code[at+1]), sp: len(stack), frame: fr, env: e})
+		case opPopHandler:
+			h := &handlers[len(handlers)-1]
+			h.disestablish()
+			e = h.env
+			handlers = handlers[:len(handlers)-1]
+		case opEndUnwind:
+			u := stack[len(stack)-1].(*unwinding)
+			stack = stack[:len(stack)-1]
+			err = u.err
+		case opLocalExit, opLocalGo:
+			i := int(code[at+1])
+			keep := i
+			if op == opLocalGo {
+				keep++
+			}
+			if op == opLocalExit {
+				v = stack[len(stack)-1]
+			} else if err = step(e); err != nil {
+				break
+			}
+			for j := len(handlers) - 1; j >= keep; j-- {
+				handlers[j].disestablish()
+			}
+			h := handlers[i]
+			handlers = handlers[:keep]
+			stack, fr, e = stack[:h.sp], h.frame, h.env
+			if op == opLocalExit {
+				stack = append(stack, v)
+			}
+			pc = int(code[at+2])
+		case opReturnFrom:
+			obj := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if uid := fr.outer(code[at+1]).slots[code[at+2]]; uid != nil {
+				err = instance.NewBlockTag(p.constants[code[at+3]], uid, obj)
+			} else if v, err = SignalCondition(e, instance.NewControlError(e), Nil); err == nil {
+				stack = append(stack, v)
+			}
+		case opGo:
+			if uid := fr.outer(code[at+1]).slots[code[at+2]]; uid != nil {
+				err = instance.NewTagbodyTag(p.constants[code[at+3]], uid)
+			} else if v, err = SignalCondition(e, instance.NewControlError(e), Nil); err == nil {
+				stack = append(stack, v)
+			}
+		case opCheckTag:
+			tag := stack[len(stack)-1]
+			if ilos.InstanceOf(class.Number, tag) || ilos.InstanceOf(class.Character, tag) {
+				if v, err = SignalCondition(e, instance.NewDomainError(e, tag, class.Object), Nil); err == nil {
+					stack[len(stack)-1] = v
+					pc = int(code[at+1])
+				}
+			}
+		case opThrow:
+			tag, obj := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-2]
+			if uid, ok := e.CatchTag.Get(tag); ok {
+				err = instance.NewCatchTag(tag, uid, obj)
+			} else if v, err = SignalCondition(e, instance.NewControlError(e), Nil); err == nil {
+				stack = append(stack, v)
+			}
+		case opDynamicLet:
+			variables := p.lists[code[at+1]]
+			n := len(stack) - len(variables)
+			bindings := make(map[ilos.Instance]ilos.Instance, len(variables))
+			for i, variable := range variables {
+				bindings[variable] = stack[n+i]
+			}
+			stack = stack[:n]
+			handlers = append(handlers, handler{op: op, sp: len(stack), frame: fr, env: e})
+			e.DynamicVariable = e.DynamicVariable.Push(bindings)
+		case opWithHandler:
+			function := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			handlers = append(handlers, handler{op: op, sp: len(stack), frame: fr, env: e})
+			e.Handler = function
+		case opFallback:
+			if v, err = fallbackTo(e, fr, p.fallbacks[code[at+1]]); err == nil {
+				stack = append(stack, v)
+			}
+		case opRaise:
+			err = p.constants[code[at+1]]
+		case opCheckConstant:
+			if _, ok := e.Constant.Get(p.constants[code[at+1]]); ok {
+				if v, err = SignalCondition(e, instance.NewImmutableBinding(e), Nil); err == nil {
+					stack = append(stack, v)
+					pc = int(code[at+2])
+				}
+			}
+		case opDefun, opDefglobal, opDefdynamic, opDefconstant:
+			name := p.constants[code[at+1]]
+			switch op {
+			case opDefun:
+				e.Function.Define(name, retraced(e, name, stack[len(stack)-1]))
+			case opDefglobal:
+				e.Variable.Define(name, stack[len(stack)-1])
+			case opDefdynamic:
+				e.DynamicVariable[:1].Define(name, stack[len(stack)-1])
+			case opDefconstant:
+				e.Constant.Define(name, stack[len(stack)-1])
+			}
+			stack[len(stack)-1] = name
+		case opQuasiquote:
+			n := len(stack) - int(code[at+2])
+			values := append([]ilos.Instance(nil), stack[n:]...)
+			stack = stack[:n]
+			if v, err = expand(e, p.constants[code[at+1]], 0, func(env.Environment, ilos.Instance) (ilos.Instance, ilos.Instance) {
+				v := values[0]
+				values = values[1:]
+				return v, nil
+			}); err == nil {
+				stack = append(stack, v)
+			}
+		case opConvert:
+			if v, err = convert(e, stack[len(stack)-1], p.constants[code[at+1]]); err == nil {
+				stack[len(stack)-1] = v
+			}
+		case opClass:
+			if v, err = Class(e, p.constants[code[at+1]]); err == nil {
+				stack = append(stack, v)
+			}
+		}
+		if err == nil {
+			continue
+		}
+		attachLocation(e, err, p.forms[at])
+		for err != nil {
+			if len(handlers) == 0 {
+				return nil, err
+			}
+			h := handlers[len(handlers)-1]
+			handlers = handlers[:len(handlers)-1]
+			h.disestablish()
+			switch h.op {
+			case opPushBlock, opPushCatch:
+				c := class.BlockTag
+				if h.op == opPushCatch {
+					c = class.CatchTag
+				}
+				if _, ok := escape(err, c, h.uid); ok {
+					obj, _ := err.(instance.Instance).GetSlotValue(instance.NewSymbol("IRIS.OBJECT"), c)
+					stack, fr, e = append(stack[:h.sp], obj), h.frame, h.env
+					pc, err = h.target, nil
+				}
+			case opPushTagbody:
+				if tag, ok := escape(err, class.TagbodyTag, h.uid); ok {
+					if target, ok := h.table[tag]; ok {
+						if err = step(h.env); err != nil {
+							break
+						}
+						h.frame.slots[h.index] = h.uid
+						handlers = append(handlers, h)
+						stack, fr, e = stack[:h.sp], h.frame, h.env
+						pc, err = target, nil
+					}
+				}
+			case opPushUnwind:
+				stack, fr, e = append(stack[:h.sp], Nil, &unwinding{err}), h.frame, uninterrupted(h.env, err)
+				pc, err = h.target, nil
+			case opPushGuard:
+				// The cleanup forms may not exit out of unwind-protect.
+				if ilos.InstanceOf(class.Escape, err) {
+					fr, e = h.frame, h.env
+					if v, err = SignalCondition(e, instance.NewControlError(e), Nil); err == nil {
+						stack = append(stack[:h.sp-2], v)
+						pc = h.target
+					}
+				}
+			}
+		}
+	}
+}
+
+// fallbackTo runs the code of f in an environment with the variables,
+// functions, blocks and tagbodies of the frames bound by name, and stores
+// the variables it assigns back into the frames.
+func fallbackTo(e env.Environment, fr *vmFrame, f *fallback) (ilos.Instance, ilos.Instance) {
+	ne := e
+	ne.Variable = e.Variable.Push(valuesOf(fr, f.variables))
+	ne.Function = e.Function.Push(valuesOf(fr, f.functions))
+	ne.BlockTag = e.BlockTag.Push(bindingsOf(fr, f.blocks))
+	ne.TagbodyTag = e.TagbodyTag.Push(bindingsOf(fr, f.tags))
+	if f.code == nil {
+		f.code = compile(ne, nil, f.form, false)
+	}
+	ret, err := f.code(ne)
+	for i, a := range f.variables {
+		if v := ne.Variable.Frame.Values[i]; v != nil {
+			fr.outer(int32(a.depth)).slots[a.index] = v
+		}
+	}
+	return ret, err
+}
+
+// valuesOf returns the names and values at the addresses. The value of a
+// binding which has not been made is nil.
+func valuesOf(fr *vmFrame, addresses []address) ([]ilos.Instance, []ilos.Instance) {
+	names := make([]ilos.Instance, len(addresses))
+	values := make([]ilos.Instance, len(addresses))
+	for i, a := range addresses {
+		names[i] = a.name
+		values[i] = fr.outer(int32(a.depth)).slots[a.index]
+	}
+	return names, values
+}
+
+// bindingsOf returns the bindings at the addresses which have been made.
+func bindingsOf(fr *vmFrame, addresses []address) map[ilos.Instance]ilos.Instance {
+	bindings := make(map[ilos.Instance]ilos.Instance, len(addresses))
+	for _, a := range addresses {
+		if v := fr.outer(int32(a.depth)).slots[a.index]; v != nil {
+			bindings[a.name] = v
+		}
+	}
+	return bindings
+}
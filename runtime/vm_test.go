@@ -0,0 +1,99 @@
+// This Source Code Form is subject to the terms of the Mozilla Public License,
+// v. 2.0. If a copy of the MPL was not distributed with this file, You can
+// obtain one at http://mozilla.org/MPL/2.0/.
+
+package runtime
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// execVMTests runs the tests as execTests does, with exp run on the virtual
+// machine.
+func execVMTests(t *testing.T, tests []test) {
+	re := regexp.MustCompile(`\s+`)
+	for _, tt := range tests {
+		t.Run(re.ReplaceAllString(tt.exp, " "), func(t *testing.T) {
+			obj, err := readFromString(tt.exp)
+			if err != nil {
+				t.Fatalf("ParseError %v, want %v", err, tt.exp)
+			}
+			got, err := Execute(TopLevel, obj)
+			wantObj, err1 := readFromString(tt.want)
+			if err1 != nil {
+				t.Fatalf("ParseError %v, want %v", err1, tt.want)
+			}
+			want, _ := Eval(TopLevel, wantObj)
+			if !tt.wantErr && !reflect.DeepEqual(got, want) {
+				t.Errorf("Execute() got = %v, want %v", got, want)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Execute() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	execVMTests(t, []test{
+		// closures
+		{`(defun vm-adder (n) (lambda (x) (+ x n)))`, `'vm-adder`, false},
+		{`(funcall (vm-adder 3) 4)`, `7`, false},
+		{`(let ((n 0)) (let ((inc (lambda () (setq n (+ n 1))))) (funcall inc) (funcall inc) n))`, `2`, false},
+		{`(mapcar (lambda (x) (* x x)) '(1 2 3))`, `'(1 4 9)`, false},
+		{`(let ((fs '())) (for ((i 0 (+ i 1))) ((= i 3)) (setq fs (cons (lambda () i) fs))) (mapcar #'funcall fs))`, `'(2 1 0)`, false},
+		{`((lambda (x :rest y) (list x y)) 1 2 3)`, `'(1 (2 3))`, false},
+		{`((lambda (x) x))`, `nil`, true},
+		{`(function-arity (lambda (x :rest y) x))`, `'(1 t)`, false},
+		{`(labels ((even (n) (if (= n 0) t (odd (- n 1)))) (odd (n) (if (= n 0) nil (even (- n 1))))) (even 10))`, `t`, false},
+		{`(flet ((f (x) (+ x 1))) (flet ((f (x) (f (* x 2)))) (f 5)))`, `11`, false},
+		{`(defun vm-count (n acc) (if (= n 0) acc (vm-count (- n 1) (+ acc 1))))`, `'vm-count`, false},
+		{`(vm-count 10000 0)`, `10000`, false},
+		// block and return-from
+		{`(block x (+ 10 (return-from x 6) 22))`, `6`, false},
+		{`(block x (let ((f (lambda () (return-from x 'exit)))) (funcall f) 'normal))`, `'exit`, false},
+		{`(funcall (block x (lambda () (return-from x 1))))`, `nil`, true},
+		// catch and throw
+		{`(catch 'c (let ((f (lambda () (throw 'c 5)))) (+ 1 (funcall f))))`, `5`, false},
+		{`(throw 'no-such-tag 1)`, `nil`, true},
+		{`(catch 1 2)`, `nil`, true},
+		// tagbody and go
+		{`(let ((n 0)) (tagbody a (setq n (+ n 1)) (if (< n 5) (go a))) n)`, `5`, false},
+		{`(let ((x '())) (tagbody (go b) a (setq x (cons 'a x)) (go c) b (setq x (cons 'b x)) (funcall (lambda () (go a))) c) x)`, `'(a b)`, false},
+		// unwind-protect
+		{`(let ((x '())) (catch 'c (unwind-protect (throw 'c 1) (setq x (cons 'cleanup x)))) x)`, `'(cleanup)`, false},
+		{`(let ((x 0)) (list (unwind-protect 1 (setq x 2)) x))`, `'(1 2)`, false},
+		{`(block a (unwind-protect 1 (return-from a 2)))`, `nil`, true},
+		// dynamic variables and handlers
+		{`(defdynamic *vm-depth* 0)`, `'*vm-depth*`, false},
+		{`(defun vm-depth () (dynamic *vm-depth*))`, `'vm-depth`, false},
+		{`(list (dynamic-let ((*vm-depth* 1)) (vm-depth)) (vm-depth))`, `'(1 0)`, false},
+		{`(with-handler (lambda (c) (continue-condition c 5)) (+ 1 (cerror "cont" "err")))`, `6`, false},
+		{`(defun vm-inner (x) (car x))`, `'vm-inner`, false},
+		{`(defun vm-outer (x) (list (vm-inner x)))`, `'vm-outer`, false},
+		{`(mapcar #'car (condition-backtrace (catch 'c (with-handler (lambda (c) (throw 'c c)) (vm-outer 1)))))`, `'(car vm-inner vm-outer)`, false},
+		// macros, quasiquote and definitions
+		{`(defmacro vm-twice (x) (list 'progn x x))`, `'vm-twice`, false},
+		{`(let ((n 0)) (vm-twice (setq n (+ n 1))) n)`, `2`, false},
+		{`(let ((x 1) (y '(2 3))) ` + "`" + `(a ,x ,@y))`, `'(a 1 2 3)`, false},
+		{`(defglobal vm-global 10)`, `'vm-global`, false},
+		{`(progn (setq vm-global (+ vm-global 1)) vm-global)`, `11`, false},
+		{`(case 2 ((1) 'one) ((2 3) 'two) (t 'other))`, `'two`, false},
+		{`(case 100000000000000000000 ((100000000000000000000) 'hit) (t 'miss))`, `'hit`, false},
+		{`(let ((x 1)) (defclass vm-point () ((x :initarg x))) x)`, `1`, false},
+	})
+}
+
+func BenchmarkVMFib(b *testing.B) {
+	benchmarkEval(b, Execute, benchFib, `(bench-fib 15)`)
+}
+
+func BenchmarkVMTailLoop(b *testing.B) {
+	benchmarkEval(b, Execute, benchLoop, `(bench-loop 1000 0)`)
+}
+
+func BenchmarkVMFor(b *testing.B) {
+	benchmarkEval(b, Execute, benchIncf, benchFor)
+}